@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// adminCommands are the subcommands that talk to a running kernel instead of starting one
+var adminCommands = map[string]func(args []string) error{
+	"backup":  backupCommand,
+	"restore": restoreCommand,
+}
+
+// defaultServer is the kernel API the admin commands use, overridden by CKM_SERVER
+func defaultServer() string {
+	if addr := os.Getenv("CKM_SERVER"); addr != "" {
+		return addr
+	}
+	return "http://localhost:8080"
+}
+
+// backupCommand saves a backup archive: ckm backup [-server URL] [-o file]
+func backupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	server := fs.String("server", defaultServer(), "kernel API address")
+	output := fs.String("o", "", "archive path (default ckm-backup-<time>.json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := *output
+	if path == "" {
+		path = "ckm-backup-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+	}
+
+	resp, err := http.Get(strings.TrimSuffix(*server, "/") + "/api/v1/admin/backup")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	// Write to a temporary file so a failed download never leaves a partial archive
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("download backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	fmt.Println("Backup written to", path)
+	return nil
+}
+
+// restoreCommand loads a backup archive into a fresh kernel:
+// ckm restore [-server URL] [-inflight requeue|interrupt] file
+func restoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	server := fs.String("server", defaultServer(), "kernel API address")
+	inflight := fs.String("inflight", "requeue", "what to do with in-flight workloads: requeue or interrupt")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: ckm restore [-server URL] [-inflight requeue|interrupt] <archive>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	endpoint := strings.TrimSuffix(*server, "/") + "/api/v1/admin/restore?inflight=" + url.QueryEscape(*inflight)
+	resp, err := http.Post(endpoint, "application/json", f)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	report, _ := io.ReadAll(resp.Body)
+	fmt.Println(strings.TrimSpace(string(report)))
+	return nil
+}
+
+// checkResponse turns an error response from the API into an error
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
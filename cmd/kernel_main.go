@@ -2,6 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
 	"syscall"
 	"time"
 
@@ -13,19 +19,64 @@ import (
 )
 
 func main() {
+	// Admin subcommands (backup, restore) talk to a running kernel
+	if len(os.Args) > 1 {
+		if command, ok := adminCommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "ckm "+os.Args[1]+":", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Initialize structured logging
 	common.InitLogger()
 	logger := common.Logger
 	defer logger.Sync()
 
+	// Load runtime-tunable settings (reloaded on SIGHUP)
+	configPath := os.Getenv("CKM_CONFIG")
+	if configPath == "" {
+		configPath = "configs/ckm.yaml"
+	}
+	cfg, err := common.LoadKernelConfig(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Info("No config file, using defaults", zap.String("path", configPath))
+	} else if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+	_ = common.SetLogLevel(cfg.LogLevel)
+
 	// Initialize Prometheus metrics
 	common.InitMetrics()
 	logger.Info("Metrics server started on :9090")
 
-	// Create components
-	cgroups := kernel.NewCGroupManager(1024) // 1024 MB total memory
-	store := kernel.NewWorkloadStore()
-	scheduler := kernel.NewRoundRobinScheduler(1 * time.Second)
+	// PIDs wrap around at pid_max and are reused once reaped
+	kernel.SetPIDMax(cfg.PIDMax)
+
+	// Create components (fallback capacity until host detection runs)
+	cgroups := kernel.NewCGroupManagerWithCapacity(kernel.Resources{
+		MemoryMB:      1024,                             // 1024 MB total memory
+		CPUMillicores: int64(goruntime.NumCPU()) * 1000, // All host CPUs
+		PIDs:          4096,
+		DiskMB:        10240,
+	})
+	// Workloads and their history survive restarts in a WAL-backed store
+	stateDir := os.Getenv("CKM_STATE_DIR")
+	if stateDir == "" {
+		stateDir = "data"
+	}
+	store, err := kernel.OpenDurableStore(filepath.Join(stateDir, "store"), logger)
+	if err != nil {
+		logger.Fatal("Failed to open workload store", zap.Error(err))
+	}
+	defer store.Close()
+	processes := kernel.NewProcessManager()
+	scheduler, err := kernel.NewSchedulerByName(cfg.Scheduler, cfg.Quantum)
+	if err != nil {
+		logger.Fatal("Failed to create scheduler", zap.Error(err))
+	}
 
 	// Initialize shared Docker client
 	dockerClient, err := runtime.NewDockerClient()
@@ -36,14 +87,107 @@ func main() {
 	// Initialize Docker runtime using shared client
 	dockerRuntime := runtime.NewDockerRuntime(dockerClient, logger)
 
-	// Create executor with worker pool (max 10 concurrent workloads)
-	executor := kernel.NewExecutor(dockerRuntime, store, logger, 10)
+	// Detect allocatable memory and CPU, keeping some back for the host (like kubelet)
+	capacityDetector := kernel.NewCapacityDetector("/proc", "/sys/fs/cgroup", dockerRuntime, cfg.Capacity, cgroups, logger)
+	capacityDetector.Refresh(context.Background())
+
+	// Create executor with worker pool
+	executor := kernel.NewExecutor(dockerRuntime, store, logger, cfg.WorkerPoolSize)
+	executor.SetCircuitBreakerThresholds(cfg.CircuitBreaker.MaxFailures, cfg.CircuitBreaker.Timeout)
+	executor.SetProcessManager(processes)
+	executor.SetCGroupManager(cgroups)
+
+	// Container output is saved per workload before the container is removed
+	logStore, err := kernel.NewLogStore(filepath.Join(stateDir, "logs"), cfg.ContainerLogs, logger)
+	if err != nil {
+		logger.Fatal("Failed to open log store", zap.Error(err))
+	}
+	executor.SetLogStore(logStore)
+
+	// Hold dispatch while /proc/pressure shows the host is thrashing
+	pressureGate := kernel.NewPressureGate("/proc", cfg.Pressure, logger)
+	executor.SetAdmissionGate(pressureGate)
+
+	// Resubmit OOM-killed workloads that opted in with an oom_retry policy
+	oomRetrier := kernel.NewOOMRetrier(store, cgroups, executor, logger)
+	executor.SetOOMHandler(oomRetrier.Handle)
+
+	// Linux-style 1/5/15 minute load averages of queued plus running workloads
+	loadAvg := kernel.NewLoadAverage(kernel.RunQueueSampler(store, executor), kernel.LoadFrequency)
 
 	// Start container discovery service using shared client (monitors ALL running containers)
 	discovery := runtime.NewContainerDiscovery(dockerClient, logger, 5*time.Second)
 
+	// Evict BestEffort, then over-request Burstable workloads above 90% real memory use
+	evictor := kernel.NewEvictor(store, cgroups, discovery, executor, logger, 0.9, 10*time.Second)
+
+	// Remove finished workloads after their TTL or past the per-type cap
+	gc := kernel.NewGarbageCollector(store, cgroups, dockerRuntime, cfg.GC, logger)
+
 	// Create API server
 	server := api.NewServer(store, executor, scheduler, cgroups, logger)
+	server.SetCapacityDetector(capacityDetector)
+	server.SetProcessManager(processes)
+	signals := kernel.NewSignalDeliverer(store, processes, executor, logger)
+	signals.SetCGroupManager(cgroups)
+	server.SetSignalDeliverer(signals)
+	server.SetStatsSource(discovery)
+	server.SetLoadAverage(loadAvg)
+	server.SetLogStore(logStore)
+	server.SetRateLimit(cfg.RateLimit.Rate, cfg.RateLimit.Burst)
+	server.SetExecConfig(cfg.Exec)
+	executor.SetDequeue(server.Dequeue)
+	server.SetBackupManager(kernel.NewBackupManager(store, cgroups, processes, server.Scheduler, logger))
+
+	// Apply safe config changes in place on SIGHUP
+	reloader := kernel.NewReloader(configPath, cfg, logger)
+	reloader.OnReload(func(old, new common.KernelConfig) error {
+		if old.Scheduler == new.Scheduler && old.Quantum == new.Quantum {
+			return nil
+		}
+		sched, err := kernel.NewSchedulerByName(new.Scheduler, new.Quantum)
+		if err != nil {
+			return err
+		}
+		server.SetScheduler(sched)
+		return nil
+	})
+	reloader.OnReload(func(old, new common.KernelConfig) error {
+		server.SetRateLimit(new.RateLimit.Rate, new.RateLimit.Burst)
+		server.SetExecConfig(new.Exec)
+		executor.SetWorkerPoolSize(new.WorkerPoolSize)
+		executor.SetCircuitBreakerThresholds(new.CircuitBreaker.MaxFailures, new.CircuitBreaker.Timeout)
+		gc.SetConfig(new.GC)
+		pressureGate.SetConfig(new.Pressure)
+		logStore.SetConfig(new.ContainerLogs)
+		if old.PIDMax != new.PIDMax {
+			logger.Warn("pid_max changes take effect on the next restart", zap.Int("pid_max", old.PIDMax))
+		}
+		if old.Capacity != new.Capacity {
+			capacityDetector.SetConfig(new.Capacity)
+			capacityDetector.Refresh(context.Background())
+		}
+		return common.SetLogLevel(new.LogLevel)
+	})
+
+	// Re-attach containers that outlived the last run before resuming the queue
+	reconciler := kernel.NewReconciler(store, cgroups, dockerRuntime, executor, kernel.OrphanPolicy(cfg.OrphanPolicy), logger)
+	if _, err := reconciler.Reconcile(context.Background()); err != nil {
+		logger.Error("Failed to reconcile containers", zap.Error(err))
+	}
+
+	// Resume workloads left queued or interrupted by the last shutdown
+	queuePath := filepath.Join(stateDir, "queue.json")
+	queued, err := kernel.LoadQueue(queuePath)
+	if err != nil {
+		logger.Error("Failed to load persisted queue", zap.String("path", queuePath), zap.Error(err))
+	}
+	if pending := kernel.PendingAfterRestart(store, queued); len(pending) > 0 {
+		resumed := server.ResumeWorkloads(pending)
+		logger.Info("Resumed persisted workloads", zap.Int("resumed", resumed), zap.Int("persisted", len(pending)))
+		_ = os.Remove(queuePath)
+	}
+	drainer := kernel.NewDrainer(store, executor, queuePath, logger)
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -58,11 +202,36 @@ func main() {
 		logger.Info("Received SIGINT, shutting down gracefully...")
 		cancel()
 	})
+	sigHandler.RegisterHandler(syscall.SIGHUP, func() {
+		logger.Info("Received SIGHUP, reloading config", zap.String("path", configPath))
+		_ = reloader.Reload()
+	})
+	// SIGUSR1 or SIGQUIT writes a diagnostic bundle without stopping the kernel
+	diagDir := os.Getenv("CKM_DIAGNOSTICS_DIR")
+	if diagDir == "" {
+		diagDir = filepath.Join(os.TempDir(), "ckm-diagnostics")
+	}
+	dumper := kernel.NewDiagnosticDumper(diagDir, store, cgroups, executor, server.Scheduler, logger)
+	dumpDiagnostics := func() {
+		if _, err := dumper.Dump(); err != nil {
+			logger.Error("Failed to write diagnostic bundle", zap.Error(err))
+		}
+	}
+	sigHandler.RegisterHandler(syscall.SIGUSR1, dumpDiagnostics)
+	sigHandler.RegisterHandler(syscall.SIGQUIT, dumpDiagnostics)
 	sigHandler.Start(ctx)
 
 	// Start container discovery in background
 	go discovery.Start(ctx)
 	logger.Info("Container discovery started (monitoring all Docker containers)")
+	go evictor.Start(ctx)
+	go pressureGate.Start(ctx)
+	go capacityDetector.Start(ctx, time.Minute)
+	go processes.RunReaper(ctx, 30*time.Second, logger)
+	go loadAvg.Start(ctx)
+	go store.Start(ctx, time.Minute)
+	go gc.Start(ctx)
+	go logStore.Start(ctx, time.Hour)
 
 	// Start API server in goroutine
 	serverErr := make(chan error, 1)
@@ -77,16 +246,30 @@ func main() {
 	select {
 	case <-ctx.Done():
 		logger.Info("Shutting down...")
+		drainTimeout := reloader.Current().DrainTimeout
+
+		// Stop admitting, persist the queue, then wait for running workloads
+		// up to the deadline before interrupting the rest
+		server.StartDrain(drainTimeout)
+		report, err := drainer.Drain(drainTimeout)
+		if err != nil {
+			logger.Error("Drain failed", zap.Error(err))
+		}
+		logger.Info("Drain finished",
+			zap.Int("queued", report.Queued),
+			zap.Int("interrupted", report.Interrupted),
+			zap.Bool("completed", report.Completed))
+
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer shutdownCancel()
-		
-		// Wait for running workloads
-		executor.Wait()
-		
+
 		// Shutdown API server
 		if err := server.Shutdown(shutdownCtx); err != nil {
 			logger.Error("Error shutting down server", zap.Error(err))
 		}
+		if err := store.Compact(); err != nil {
+			logger.Error("Failed to snapshot workload store", zap.Error(err))
+		}
 		logger.Info("Shutdown complete")
 	case err := <-serverErr:
 		logger.Fatal("Server error", zap.Error(err))
@@ -5,6 +5,7 @@ go 1.24.2
 require (
 	github.com/docker/docker v27.5.1+incompatible
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/prometheus/client_golang v1.21.1
 	go.uber.org/zap v1.27.0
 	gopkg.in/yaml.v3 v3.0.1
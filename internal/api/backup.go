@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ckm/internal/common"
+	"ckm/internal/kernel"
+	"go.uber.org/zap"
+)
+
+// exportBackup handles GET /api/v1/admin/backup, returning a point-in-time
+// archive of the kernel state. Admission and deletion wait while it's taken.
+func (s *Server) exportBackup(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Backups not enabled")
+		return
+	}
+	s.stateMu.Lock()
+	backup := s.backups.Export()
+	s.stateMu.Unlock()
+
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="ckm-backup-%s.json"`, backup.CreatedAt.UTC().Format("20060102T150405Z")))
+	s.respondJSON(w, http.StatusOK, backup)
+}
+
+// restoreBackup handles POST /api/v1/admin/restore?inflight=requeue|interrupt.
+// It only restores into a kernel that has no workloads yet.
+func (s *Server) restoreBackup(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Backups not enabled")
+		return
+	}
+	mode := kernel.RestoreMode(r.URL.Query().Get("inflight"))
+	if mode == "" {
+		mode = kernel.RestoreRequeue
+	}
+	if mode != kernel.RestoreRequeue && mode != kernel.RestoreInterrupt {
+		s.respondError(w, http.StatusBadRequest, "inflight must be requeue or interrupt")
+		return
+	}
+	var backup kernel.Backup
+	if err := json.NewDecoder(r.Body).Decode(&backup); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid backup archive")
+		return
+	}
+
+	s.stateMu.Lock()
+	report, err := s.backups.Restore(&backup, mode)
+	if err == nil {
+		s.requeueRestored(report.Requeued)
+	}
+	s.stateMu.Unlock()
+	switch {
+	case errors.Is(err, kernel.ErrStoreNotEmpty):
+		s.respondError(w, http.StatusConflict, "Restore needs a kernel without workloads")
+		return
+	case err != nil:
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.recordResourceUsage()
+	s.logger.Info("Restored backup",
+		zap.Time("taken_at", backup.CreatedAt),
+		zap.Int("workloads", report.Workloads),
+		zap.Int("requeued", len(report.Requeued)))
+	s.respondJSON(w, http.StatusOK, RestoreResponse{RestoreReport: report, Requeued: len(report.Requeued)})
+}
+
+// requeueRestored queues and dispatches restored workloads in order. They
+// already hold their PIDs and reservations, so they skip admission.
+func (s *Server) requeueRestored(workloads []*kernel.Workload) {
+	s.schedMu.RLock()
+	defer s.schedMu.RUnlock()
+	for _, wl := range workloads {
+		s.scheduler.Add(*wl)
+		common.SchedulerQueueLength.WithLabelValues("default").Inc()
+		s.executor.ExecuteAsync(context.Background(), wl)
+	}
+}
+
+// RestoreResponse reports what a restore brought back
+type RestoreResponse struct {
+	kernel.RestoreReport
+	Requeued int `json:"requeued"`
+}
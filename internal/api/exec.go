@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ckm/internal/common"
+	"ckm/internal/kernel"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Channels of the exec and attach WebSocket protocol. Every binary message
+// starts with one of these bytes; the rest of the message is the payload.
+const (
+	channelStdin      byte = 0   // Client to server: input
+	channelStdout     byte = 1   // Server to client: output (all of it with a TTY)
+	channelStderr     byte = 2   // Server to client: error output without a TTY
+	channelStatus     byte = 3   // Server to client: an ExecStatus, sent once before closing
+	channelResize     byte = 4   // Client to server: a TerminalSize
+	channelCloseStdin byte = 255 // Client to server: end of input
+)
+
+const (
+	terminalExec   = "exec"
+	terminalAttach = "attach"
+)
+
+// TerminalSize is the payload of a resize message
+type TerminalSize struct {
+	Width  uint `json:"width"`
+	Height uint `json:"height"`
+}
+
+// ExecStatus is the payload of the status message that ends a session
+type ExecStatus struct {
+	ExitCode *int   `json:"exit_code,omitempty"` // Unset if the process was still running
+	Error    string `json:"error,omitempty"`
+}
+
+// Terminals opens interactive sessions in containers. The executor implements it.
+type Terminals interface {
+	Exec(ctx context.Context, containerID string, cmd []string, tty bool) (runtime.TerminalSession, error)
+	Attach(ctx context.Context, containerID string) (runtime.TerminalSession, error)
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// execWorkload handles GET /api/v1/workloads/{id}/exec?command=...&tty=true.
+// command is repeated for each argument.
+func (s *Server) execWorkload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	command := q["command"]
+	if len(command) == 0 || command[0] == "" {
+		s.respondError(w, http.StatusBadRequest, "command is required")
+		return
+	}
+	s.openTerminal(w, r, terminalExec, command, q.Get("tty") == "true")
+}
+
+// attachWorkload handles GET /api/v1/workloads/{id}/attach
+func (s *Server) attachWorkload(w http.ResponseWriter, r *http.Request) {
+	s.openTerminal(w, r, terminalAttach, nil, false)
+}
+
+// openTerminal authorizes the request, starts the session in the workload's
+// container and bridges it to a WebSocket until either side ends
+func (s *Server) openTerminal(w http.ResponseWriter, r *http.Request, kind string, command []string, tty bool) {
+	id := mux.Vars(r)["id"]
+	audit := s.logger.Named("audit").With(
+		zap.String("kind", kind),
+		zap.String("workload", id),
+		zap.String("remote", r.RemoteAddr))
+
+	user, ok := s.authenticateExec(w, r, kind, audit)
+	if !ok {
+		return
+	}
+	audit = audit.With(zap.String("user", user.Name))
+	wl, ok := s.store.Get(id)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Workload not found")
+		return
+	}
+	audit = audit.With(zap.String("namespace", wl.Namespace))
+	if len(user.Namespaces) > 0 && !slices.Contains(user.Namespaces, wl.Namespace) {
+		s.denyTerminal(w, http.StatusForbidden, "Not allowed in namespace "+wl.Namespace, kind, audit)
+		return
+	}
+	if wl.Status != kernel.StatusRunning || wl.ContainerID == "" {
+		s.respondError(w, http.StatusConflict, "Workload is not running")
+		return
+	}
+	if s.terminals == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Container runtime not enabled")
+		return
+	}
+
+	var session runtime.TerminalSession
+	var err error
+	if kind == terminalExec {
+		session, err = s.terminals.Exec(r.Context(), wl.ContainerID, command, tty)
+	} else {
+		session, err = s.terminals.Attach(r.Context(), wl.ContainerID)
+	}
+	if err != nil {
+		audit.Warn("Terminal session failed to start", zap.Strings("command", command), zap.Error(err))
+		common.ExecSessionsTotal.WithLabelValues(kind, "error").Inc()
+		s.respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer session.Close()
+
+	// Upgrade writes its own error response
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	// The connection outlives the server's read and write timeouts
+	_ = ws.NetConn().SetDeadline(time.Time{})
+
+	audit = audit.With(zap.String("container", wl.ContainerID))
+	audit.Info("Terminal session opened", zap.Strings("command", command), zap.Bool("tty", session.TTY()))
+	started := time.Now()
+	result := s.bridgeTerminal(ws, session)
+	common.ExecSessionsTotal.WithLabelValues(kind, "completed").Inc()
+
+	fields := []zap.Field{
+		zap.Duration("duration", time.Since(started)),
+		zap.Int64("stdin_bytes", result.stdin),
+		zap.Int64("output_bytes", result.output),
+	}
+	if result.status.ExitCode != nil {
+		fields = append(fields, zap.Int("exit_code", *result.status.ExitCode))
+	}
+	if result.status.Error != "" {
+		fields = append(fields, zap.String("error", result.status.Error))
+	}
+	audit.Info("Terminal session closed", fields...)
+}
+
+// authenticateExec finds the exec user for the request's bearer token. It
+// answers 403 while exec is disabled and 401 for a missing or unknown token.
+func (s *Server) authenticateExec(w http.ResponseWriter, r *http.Request, kind string, audit *zap.Logger) (common.ExecUser, bool) {
+	s.execMu.RLock()
+	cfg := s.execConfig
+	s.execMu.RUnlock()
+	if !cfg.Enabled {
+		s.denyTerminal(w, http.StatusForbidden, "Exec is disabled", kind, audit)
+		return common.ExecUser{}, false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && token != "" {
+		for _, u := range cfg.Users {
+			if subtle.ConstantTimeCompare([]byte(u.Token), []byte(token)) == 1 {
+				return u, true
+			}
+		}
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	s.denyTerminal(w, http.StatusUnauthorized, "Missing or invalid bearer token", kind, audit)
+	return common.ExecUser{}, false
+}
+
+// denyTerminal rejects a session and records the attempt in the audit log
+func (s *Server) denyTerminal(w http.ResponseWriter, status int, message, kind string, audit *zap.Logger) {
+	audit.Warn("Terminal session denied", zap.Int("status", status), zap.String("reason", message))
+	common.ExecSessionsTotal.WithLabelValues(kind, "denied").Inc()
+	s.respondError(w, status, message)
+}
+
+// terminalResult describes how a bridged session ended
+type terminalResult struct {
+	status ExecStatus
+	stdin  int64 // Bytes from the client
+	output int64 // Bytes to the client
+}
+
+// bridgeTerminal copies client input to the session and session output to
+// the client. It returns when the process exits or detaches, the client goes
+// away, or the server shuts down.
+func (s *Server) bridgeTerminal(ws *websocket.Conn, session runtime.TerminalSession) terminalResult {
+	var result terminalResult
+	var writeMu sync.Mutex
+	var output atomic.Int64
+	stdout := &channelWriter{ws: ws, mu: &writeMu, channel: channelStdout, n: &output}
+	stderr := &channelWriter{ws: ws, mu: &writeMu, channel: channelStderr, n: &output}
+
+	outputDone := make(chan error, 1)
+	go func() { outputDone <- session.CopyOutput(stdout, stderr) }()
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		result.stdin = s.readTerminalInput(ws, session)
+	}()
+
+	select {
+	case err := <-outputDone:
+		if err != nil {
+			result.status.Error = err.Error()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if code, err := session.ExitCode(ctx); err == nil && code >= 0 {
+			result.status.ExitCode = &code
+		}
+		cancel()
+	case <-inputDone:
+		result.status.Error = "client disconnected"
+	case <-s.closing:
+		result.status.Error = "server shutting down"
+	}
+
+	payload, _ := json.Marshal(result.status)
+	writeMu.Lock()
+	_ = ws.WriteMessage(websocket.BinaryMessage, append([]byte{channelStatus}, payload...))
+	_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	writeMu.Unlock()
+
+	// Closing both ends stops the other copy
+	session.Close()
+	ws.Close()
+	<-inputDone
+	result.output = output.Load()
+	return result
+}
+
+// readTerminalInput handles client messages until the connection closes and
+// returns the number of input bytes
+func (s *Server) readTerminalInput(ws *websocket.Conn, session runtime.TerminalSession) int64 {
+	var n int64
+	for {
+		kind, msg, err := ws.ReadMessage()
+		if err != nil {
+			return n
+		}
+		if kind != websocket.BinaryMessage || len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case channelStdin:
+			written, err := session.Stdin().Write(msg[1:])
+			n += int64(written)
+			if err != nil {
+				s.logger.Debug("Terminal input failed", zap.Error(err))
+			}
+		case channelResize:
+			var size TerminalSize
+			if err := json.Unmarshal(msg[1:], &size); err != nil || !session.TTY() {
+				continue
+			}
+			if err := session.Resize(context.Background(), size.Height, size.Width); err != nil {
+				s.logger.Debug("Terminal resize failed", zap.Error(err))
+			}
+		case channelCloseStdin:
+			if err := session.CloseStdin(); err != nil {
+				s.logger.Debug("Closing terminal input failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// channelWriter sends everything written to it as messages on one channel
+type channelWriter struct {
+	ws      *websocket.Conn
+	mu      *sync.Mutex // Shared by every writer on the connection
+	channel byte
+	n       *atomic.Int64
+}
+
+func (c *channelWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ws.WriteMessage(websocket.BinaryMessage, append([]byte{c.channel}, p...)); err != nil {
+		return 0, err
+	}
+	c.n.Add(int64(len(p)))
+	return len(p), nil
+}
@@ -76,8 +76,8 @@ func TestIntegrationWorkloadLifecycle(t *testing.T) {
 	var workload kernel.Workload
 	json.NewDecoder(resp.Body).Decode(&workload)
 
-	if workload.Status != "done" {
-		t.Errorf("Expected status done, got %s", workload.Status)
+	if workload.Status != "succeeded" {
+		t.Errorf("Expected status succeeded, got %s", workload.Status)
 	}
 }
 
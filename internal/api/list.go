@@ -0,0 +1,235 @@
+package api
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ckm/internal/kernel"
+	"go.uber.org/zap"
+)
+
+// ContinueHeader carries the cursor for the next page of a list
+const ContinueHeader = "X-Continue"
+
+// maxListLimit caps a page of workloads
+const maxListLimit = 1000
+
+// sortFields compare workloads by each field GET /workloads can sort on
+var sortFields = map[string]func(a, b *kernel.Workload) int{
+	"createdAt": func(a, b *kernel.Workload) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"id":        func(a, b *kernel.Workload) int { return strings.Compare(a.ID, b.ID) },
+	"priority":  func(a, b *kernel.Workload) int { return cmp.Compare(a.Priority, b.Priority) },
+	"status":    func(a, b *kernel.Workload) int { return strings.Compare(a.Status, b.Status) },
+	"type":      func(a, b *kernel.Workload) int { return strings.Compare(a.Type, b.Type) },
+}
+
+// listOptions are the filters, order and page a list, watch or bulk delete applies
+type listOptions struct {
+	selector     kernel.Selector
+	statuses     []string
+	types        []string
+	images       []string
+	createdAfter time.Time
+	sortBy       string
+	desc         bool
+	limit        int
+	after        *listCursor // Resume after this workload
+}
+
+// listCursor is the position of the last workload on a page. It holds the
+// sort keys rather than an offset, so pages stay consistent while
+// workloads are added and removed.
+type listCursor struct {
+	SortBy    string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	ID        string    `json:"i"`
+	CreatedAt time.Time `json:"c"`
+	Priority  int       `json:"p,omitempty"`
+	Status    string    `json:"st,omitempty"`
+	Type      string    `json:"t,omitempty"`
+}
+
+// parseListOptions reads labelSelector, status, type, image, createdAfter,
+// sortBy, order, limit and continue from the query string
+func parseListOptions(r *http.Request) (listOptions, error) {
+	q := r.URL.Query()
+	opts := listOptions{sortBy: "createdAt"}
+
+	var err error
+	if opts.selector, err = kernel.ParseSelector(q.Get("labelSelector")); err != nil {
+		return opts, err
+	}
+	opts.statuses = splitList(q.Get("status"))
+	opts.types = splitList(q.Get("type"))
+	opts.images = splitList(q.Get("image"))
+	if v := q.Get("createdAfter"); v != "" {
+		if opts.createdAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			return opts, errors.New("createdAfter must be an RFC 3339 time")
+		}
+	}
+
+	if v := q.Get("sortBy"); v != "" {
+		if _, ok := sortFields[v]; !ok {
+			return opts, errors.New("sortBy must be one of createdAt, id, priority, status, type")
+		}
+		opts.sortBy = v
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.desc = true
+	default:
+		return opts, errors.New("order must be asc or desc")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if opts.limit, err = strconv.Atoi(v); err != nil || opts.limit < 0 {
+			return opts, errors.New("limit must be a non-negative integer")
+		}
+		opts.limit = min(opts.limit, maxListLimit)
+	}
+	if v := q.Get("continue"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil || cursor.SortBy != opts.sortBy || cursor.Desc != opts.desc {
+			return opts, errors.New("continue token is invalid or was issued for a different sort order")
+		}
+		opts.after = &cursor
+	}
+	return opts, nil
+}
+
+// filtered reports whether any filter is set; bulk delete requires one
+func (o listOptions) filtered() bool {
+	return !o.selector.Empty() || len(o.statuses) > 0 || len(o.types) > 0 || len(o.images) > 0 || !o.createdAfter.IsZero()
+}
+
+// matches reports whether a workload passes the label selector and field filters
+func (o listOptions) matches(wl *kernel.Workload) bool {
+	if len(o.statuses) > 0 && !slices.Contains(o.statuses, wl.Status) {
+		return false
+	}
+	if len(o.types) > 0 && !slices.Contains(o.types, wl.Type) {
+		return false
+	}
+	if len(o.images) > 0 && !slices.Contains(o.images, wl.Image) {
+		return false
+	}
+	if !o.createdAfter.IsZero() && !wl.CreatedAt.After(o.createdAfter) {
+		return false
+	}
+	return o.selector.Matches(wl.Labels)
+}
+
+// compare orders workloads by the sort field, then by ID so the order is total
+func (o listOptions) compare(a, b *kernel.Workload) int {
+	c := sortFields[o.sortBy](a, b)
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+// page filters and sorts workloads and cuts out the requested page. The
+// returned token is empty on the last page.
+func (o listOptions) page(workloads []*kernel.Workload) ([]*kernel.Workload, string) {
+	var after *kernel.Workload
+	if o.after != nil {
+		after = &kernel.Workload{
+			ID: o.after.ID, CreatedAt: o.after.CreatedAt, Priority: o.after.Priority,
+			Status: o.after.Status, Type: o.after.Type,
+		}
+	}
+
+	result := make([]*kernel.Workload, 0, len(workloads))
+	for _, wl := range workloads {
+		if o.matches(wl) && (after == nil || o.compare(after, wl) < 0) {
+			result = append(result, wl)
+		}
+	}
+	slices.SortFunc(result, o.compare)
+
+	if o.limit == 0 || len(result) <= o.limit {
+		return result, ""
+	}
+	result = result[:o.limit]
+	last := result[len(result)-1]
+	return result, encodeCursor(listCursor{
+		SortBy: o.sortBy, Desc: o.desc, ID: last.ID, CreatedAt: last.CreatedAt,
+		Priority: last.Priority, Status: last.Status, Type: last.Type,
+	})
+}
+
+// encodeCursor turns a cursor into an opaque continue token
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a continue token made by encodeCursor
+func decodeCursor(token string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// splitList splits a comma-separated query value, dropping empty items
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// deleteWorkloads handles DELETE /api/v1/workloads?labelSelector=...,
+// removing every workload that matches. At least one filter is required.
+func (s *Server) deleteWorkloads(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !opts.filtered() {
+		s.respondError(w, http.StatusBadRequest, "Bulk delete needs a labelSelector or field filter")
+		return
+	}
+
+	resp := BulkDeleteResponse{Deleted: []string{}}
+	for _, wl := range s.store.GetAll() {
+		if !opts.matches(wl) {
+			continue
+		}
+		if _, err := s.removeWorkload(wl.ID, 0, false); err != nil {
+			if !errors.Is(err, kernel.ErrWorkloadNotFound) {
+				s.logger.Warn("Bulk delete skipped workload", zap.String("workload", wl.ID), zap.Error(err))
+				resp.Failed = append(resp.Failed, wl.ID)
+			}
+			continue
+		}
+		resp.Deleted = append(resp.Deleted, wl.ID)
+	}
+	slices.Sort(resp.Deleted)
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// BulkDeleteResponse lists the workloads a bulk delete removed
+type BulkDeleteResponse struct {
+	Deleted []string `json:"deleted"`
+	Failed  []string `json:"failed,omitempty"`
+}
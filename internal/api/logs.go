@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ckm/internal/kernel"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+
+	"github.com/gorilla/mux"
+)
+
+// logQuery holds the options of GET /workloads/{id}/logs
+type logQuery struct {
+	follow     bool
+	tail       int // -1 for all
+	since      time.Time
+	timestamps bool
+	stream     string // runtime.StreamStdout, runtime.StreamStderr or "" for both
+	json       bool   // One JSON object per line instead of plain text
+}
+
+// parseLogQuery reads follow, tail, since, timestamps, stream and format.
+// since is an RFC 3339 time or a duration back from now, like docker logs.
+func parseLogQuery(r *http.Request) (logQuery, error) {
+	q := r.URL.Query()
+	query := logQuery{
+		follow:     q.Get("follow") == "true",
+		tail:       -1,
+		timestamps: q.Get("timestamps") == "true",
+		stream:     q.Get("stream"),
+		json:       q.Get("format") == "json",
+	}
+	if v := q.Get("tail"); v != "" && v != "all" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return query, errors.New("tail must be a non-negative integer or all")
+		}
+		query.tail = n
+	}
+	if v := q.Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			query.since = time.Now().Add(-d)
+		} else if query.since, err = time.Parse(time.RFC3339, v); err != nil {
+			return query, errors.New("since must be an RFC 3339 time or a duration like 10m")
+		}
+	}
+	switch query.stream {
+	case "", runtime.StreamStdout, runtime.StreamStderr:
+	default:
+		return query, errors.New("stream must be stdout or stderr")
+	}
+	if f := q.Get("format"); f != "" && f != "text" && f != "json" {
+		return query, errors.New("format must be text or json")
+	}
+	return query, nil
+}
+
+// getWorkloadLogs handles GET /api/v1/workloads/{id}/logs. Output comes
+// from the container while it exists and from the saved log files after it's
+// removed. With follow, the response streams until the container stops.
+func (s *Server) getWorkloadLogs(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	query, err := parseLogQuery(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wl, ok := s.store.Get(id)
+	if !ok && (s.logs == nil || !s.logs.Has(id)) {
+		s.respondError(w, http.StatusNotFound, "Workload not found")
+		return
+	}
+
+	// A followed stream outlives the server's write timeout
+	if query.follow {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+	if ok && query.follow && wl.ContainerID == "" && !kernel.IsTerminal(wl.Status) {
+		// Wait for the container so a client can follow a workload it just created
+		if wl, ok = s.waitForContainer(r.Context(), id); !ok {
+			s.respondError(w, http.StatusNotFound, "Workload not found")
+			return
+		}
+	}
+
+	if query.json {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	if ok && wl.ContainerID != "" && !kernel.IsTerminal(wl.Status) && s.executor != nil {
+		if s.streamContainerLogs(w, r, wl, query) {
+			return
+		}
+		// The container was removed in the meantime; its logs are saved by now
+	}
+
+	var entries []runtime.LogEntry
+	if s.logs != nil {
+		if entries, err = s.logs.Read(id, query.since, query.tail); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	for _, entry := range entries {
+		if err := writeLogEntry(w, entry, query); err != nil {
+			return
+		}
+	}
+}
+
+// streamContainerLogs copies a live container's logs to the response. It
+// returns false if the container can't be read, before anything is written.
+func (s *Server) streamContainerLogs(w http.ResponseWriter, r *http.Request, wl *kernel.Workload, query logQuery) bool {
+	tail := "all"
+	if query.tail >= 0 {
+		tail = strconv.Itoa(query.tail)
+	}
+	logs, err := s.executor.ContainerLogs(r.Context(), wl.ContainerID, runtime.LogOptions{
+		Follow: query.follow,
+		Tail:   tail,
+		Since:  query.since,
+	})
+	if err != nil {
+		s.logger.Debug("Container logs unavailable", zap.String("workload", wl.ID), zap.Error(err))
+		return false
+	}
+	defer logs.Close()
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+	err = runtime.ReadLogStream(logs, func(entry runtime.LogEntry) error {
+		// Docker's since has whole-second precision
+		if !query.since.IsZero() && !entry.Time.After(query.since) {
+			return nil
+		}
+		if err := writeLogEntry(w, entry, query); err != nil {
+			return err
+		}
+		if query.follow {
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil && r.Context().Err() == nil {
+		s.logger.Warn("Container log stream ended", zap.String("workload", wl.ID), zap.Error(err))
+	}
+	return true
+}
+
+// waitForContainer blocks until a workload has a container or has finished
+func (s *Server) waitForContainer(ctx context.Context, id string) (*kernel.Workload, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := s.store.Watch(ctx, s.store.ResourceVersion())
+	if err != nil {
+		return nil, false
+	}
+	// It may have started between the caller's read and the watch
+	if wl, ok := s.store.Get(id); !ok || wl.ContainerID != "" || kernel.IsTerminal(wl.Status) {
+		return wl, ok
+	}
+	for {
+		select {
+		case e, open := <-events:
+			if !open {
+				// The request ended or the watch fell behind
+				return s.store.Get(id)
+			}
+			if e.Object.ID != id {
+				continue
+			}
+			if e.Type == kernel.EventDeleted {
+				return nil, false
+			}
+			if e.Object.ContainerID != "" || kernel.IsTerminal(e.Object.Status) {
+				return e.Object, true
+			}
+		case <-s.closing:
+			return s.store.Get(id)
+		}
+	}
+}
+
+// writeLogEntry writes one line of output, unless it's from a stream the client didn't ask for
+func writeLogEntry(w http.ResponseWriter, entry runtime.LogEntry, query logQuery) error {
+	if query.stream != "" && entry.Stream != query.stream {
+		return nil
+	}
+	if query.json {
+		return json.NewEncoder(w).Encode(entry)
+	}
+	line := entry.Log + "\n"
+	if query.timestamps {
+		line = entry.Time.UTC().Format(time.RFC3339Nano) + " " + line
+	}
+	_, err := w.Write([]byte(line))
+	return err
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ckm/internal/kernel"
+
+	"github.com/gorilla/mux"
+)
+
+// ProcessNode is one process in a tree, with its workload's status
+type ProcessNode struct {
+	PID        int            `json:"pid"`
+	PPID       int            `json:"ppid"`
+	PGID       int            `json:"pgid"`
+	SID        int            `json:"sid"`
+	State      string         `json:"state"`
+	WorkloadID string         `json:"workload_id,omitempty"`
+	Status     string         `json:"status,omitempty"`
+	Children   []*ProcessNode `json:"children"`
+}
+
+// getProcessTree handles GET /api/v1/processes/{pid}/tree
+func (s *Server) getProcessTree(w http.ResponseWriter, r *http.Request) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return
+	}
+	pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid PID")
+		return
+	}
+
+	infos := s.processes.GetProcessTree(pid)
+	if len(infos) == 0 {
+		s.respondError(w, http.StatusNotFound, "Process not found")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, buildProcessTree(infos, s.workloadsByPID()))
+}
+
+// WaitResponse is the exit status collected from a child process
+type WaitResponse struct {
+	PID        int       `json:"pid"`
+	ExitCode   int64     `json:"exit_code"`
+	State      string    `json:"state"`
+	ExitedAt   time.Time `json:"exited_at"`
+	WorkloadID string    `json:"workload_id,omitempty"`
+}
+
+// maxWaitTimeout bounds how long a single wait request may block
+const maxWaitTimeout = 5 * time.Minute
+
+// waitProcess handles GET /api/v1/processes/{pid}/wait?child=N&nohang=true&timeout=30s.
+// Like waitpid(), it blocks until a child of {pid} exits (child=-1 or omitted
+// means any child), reaps it and returns its exit code.
+func (s *Server) waitProcess(w http.ResponseWriter, r *http.Request) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return
+	}
+	ppid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid PID")
+		return
+	}
+	child := kernel.WaitAny
+	if v := r.URL.Query().Get("child"); v != "" {
+		if child, err = strconv.Atoi(v); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid child PID")
+			return
+		}
+	}
+	timeout := 30 * time.Second
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			s.respondError(w, http.StatusBadRequest, "Invalid timeout")
+			return
+		}
+	}
+	timeout = min(timeout, maxWaitTimeout)
+	nohang := r.URL.Query().Get("nohang") == "true"
+
+	// Blocking past the server's write timeout would cut the response off
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	info, err := s.processes.Wait(ctx, ppid, child, nohang)
+	switch {
+	case errors.Is(err, kernel.ErrNoChild):
+		s.respondError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, kernel.ErrNoExitedChild):
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		s.respondError(w, http.StatusRequestTimeout, "No child exited before timeout")
+		return
+	case err != nil:
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := WaitResponse{PID: info.PID, ExitCode: info.ExitCode, State: info.State, ExitedAt: info.ExitedAt}
+	if wl, ok := s.workloadsByPID()[info.PID]; ok {
+		resp.WorkloadID = wl.ID
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// NamespacedProcessResponse shows a process as its tenant sees it, with the global PID
+type NamespacedProcessResponse struct {
+	Namespace  string `json:"namespace"`
+	PID        int    `json:"pid"`
+	GlobalPID  int    `json:"global_pid"`
+	State      string `json:"state"`
+	WorkloadID string `json:"workload_id,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// getNamespacedProcess handles GET /api/v1/namespaces/{ns}/processes/{pid}
+func (s *Server) getNamespacedProcess(w http.ResponseWriter, r *http.Request) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return
+	}
+	vars := mux.Vars(r)
+	local, err := strconv.Atoi(vars["pid"])
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid PID")
+		return
+	}
+	global, ok := kernel.PIDs().Translate(vars["ns"], local)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Process not found")
+		return
+	}
+	info, ok := s.processes.GetProcess(global)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Process not found")
+		return
+	}
+
+	resp := NamespacedProcessResponse{Namespace: vars["ns"], PID: local, GlobalPID: global, State: info.State}
+	if wl, ok := s.workloadsByPID()[global]; ok {
+		resp.WorkloadID = wl.ID
+		resp.Status = wl.Status
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// workloadsByPID indexes stored workloads by their PID. PIDs are reused, so
+// the most recently created workload wins.
+func (s *Server) workloadsByPID() map[int]*kernel.Workload {
+	byPID := make(map[int]*kernel.Workload)
+	for _, wl := range s.store.GetAll() {
+		if cur, ok := byPID[wl.PID]; !ok || wl.CreatedAt.After(cur.CreatedAt) {
+			byPID[wl.PID] = wl
+		}
+	}
+	return byPID
+}
+
+// buildProcessTree nests the flat pre-order list from GetProcessTree; the first entry is the root
+func buildProcessTree(infos []*kernel.ProcessInfo, workloads map[int]*kernel.Workload) *ProcessNode {
+	nodes := make(map[int]*ProcessNode, len(infos))
+	for _, info := range infos {
+		node := &ProcessNode{
+			PID:      info.PID,
+			PPID:     info.PPID,
+			PGID:     info.PGID,
+			SID:      info.SID,
+			State:    info.State,
+			Children: []*ProcessNode{},
+		}
+		if wl, ok := workloads[info.PID]; ok {
+			node.WorkloadID = wl.ID
+			node.Status = wl.Status
+		}
+		nodes[info.PID] = node
+	}
+
+	root := nodes[infos[0].PID]
+	for _, info := range infos[1:] {
+		if parent, ok := nodes[info.PPID]; ok {
+			parent.Children = append(parent.Children, nodes[info.PID])
+		}
+	}
+	return root
+}
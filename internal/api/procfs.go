@@ -0,0 +1,189 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ckm/internal/kernel"
+	"ckm/internal/runtime"
+
+	"github.com/gorilla/mux"
+)
+
+// procStates maps process table states to the letters used by /proc/{pid}/status
+var procStates = map[string]string{
+	"running":    "R (running)",
+	"stopped":    "T (stopped)",
+	"zombie":     "Z (zombie)",
+	"terminated": "X (dead)",
+}
+
+// procTarget resolves {pid} to its process and, except for init, its workload
+func (s *Server) procTarget(w http.ResponseWriter, r *http.Request) (kernel.ProcessInfo, *kernel.Workload, bool) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return kernel.ProcessInfo{}, nil, false
+	}
+	pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid PID")
+		return kernel.ProcessInfo{}, nil, false
+	}
+	info, ok := s.processes.GetProcess(pid)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Process not found")
+		return kernel.ProcessInfo{}, nil, false
+	}
+	return info, s.workloadsByPID()[pid], true
+}
+
+// procStatus handles GET /api/v1/proc/{pid}/status
+func (s *Server) procStatus(w http.ResponseWriter, r *http.Request) {
+	info, wl, ok := s.procTarget(w, r)
+	if !ok {
+		return
+	}
+
+	name, nspid := "init", strconv.Itoa(info.PID)
+	if wl != nil {
+		name = wl.ID
+		if wl.Namespace != "" {
+			nspid += "\t" + strconv.Itoa(wl.NSPID)
+		}
+	}
+	state, ok := procStates[info.State]
+	if !ok {
+		state = info.State
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Name:\t%s\n", name)
+	fmt.Fprintf(&b, "State:\t%s\n", state)
+	fmt.Fprintf(&b, "Tgid:\t%d\n", info.PID)
+	fmt.Fprintf(&b, "Pid:\t%d\n", info.PID)
+	fmt.Fprintf(&b, "PPid:\t%d\n", info.PPID)
+	fmt.Fprintf(&b, "NSpid:\t%s\n", nspid)
+	fmt.Fprintf(&b, "NSpgid:\t%d\n", info.PGID)
+	fmt.Fprintf(&b, "NSsid:\t%d\n", info.SID)
+	if wl != nil {
+		if stats := s.containerStats(wl); stats != nil {
+			fmt.Fprintf(&b, "VmHWM:\t%8d kB\n", stats.MemoryPeak/1024)
+			fmt.Fprintf(&b, "VmRSS:\t%8d kB\n", stats.MemoryUsage/1024)
+			fmt.Fprintf(&b, "Threads:\t%d\n", stats.PIDs)
+		}
+		fmt.Fprintf(&b, "Cpus_allowed_millicores:\t%d\n", wl.EffectiveCPULimitMillicores())
+	}
+	if info.State == "zombie" || info.State == "terminated" {
+		fmt.Fprintf(&b, "ExitCode:\t%d\n", info.ExitCode)
+	}
+	s.respondText(w, b.String())
+}
+
+// procCgroup handles GET /api/v1/proc/{pid}/cgroup (cgroup v2 format)
+func (s *Server) procCgroup(w http.ResponseWriter, r *http.Request) {
+	_, wl, ok := s.procTarget(w, r)
+	if !ok {
+		return
+	}
+	path := "/"
+	if wl != nil {
+		path = fmt.Sprintf("/ckm/%s/%s", strings.ToLower(string(wl.QoSClass)), wl.ID)
+	}
+	s.respondText(w, "0::"+path+"\n")
+}
+
+// procLimits handles GET /api/v1/proc/{pid}/limits
+func (s *Server) procLimits(w http.ResponseWriter, r *http.Request) {
+	_, wl, ok := s.procTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	row := func(name, soft, hard, units string) {
+		fmt.Fprintf(&b, "%-25s %-20s %-20s %-10s\n", name, soft, hard, units)
+	}
+	row("Limit", "Soft Limit", "Hard Limit", "Units")
+	if wl == nil {
+		row("Max processes", "unlimited", "unlimited", "processes")
+		row("Max resident set", "unlimited", "unlimited", "bytes")
+		row("Max file size", "unlimited", "unlimited", "bytes")
+		s.respondText(w, b.String())
+		return
+	}
+
+	// Requests are soft limits (what is reserved), limits are hard limits
+	pids := procLimit(wl.PIDsLimit)
+	row("Max processes", pids, pids, "processes")
+	row("Max resident set", procLimit(int64(wl.MemoryMB)<<20), procLimit(int64(wl.EffectiveMemoryLimitMB())<<20), "bytes")
+	disk := procLimit(wl.DiskMB << 20)
+	row("Max file size", disk, disk, "bytes")
+	row("Max cpu", procLimit(wl.CPUMillicores), procLimit(wl.EffectiveCPULimitMillicores()), "millicores")
+	s.respondText(w, b.String())
+}
+
+// procMeminfo handles GET /api/v1/proc/meminfo. MemTotal is allocatable memory,
+// MemFree subtracts real container usage and MemAvailable subtracts reservations.
+func (s *Server) procMeminfo(w http.ResponseWriter, r *http.Request) {
+	totalKB := s.cgroups.GetCapacity().MemoryMB * 1024
+	reservedKB := s.cgroups.GetUsed().MemoryMB * 1024
+
+	var usedKB int64
+	for _, wl := range s.store.GetAll() {
+		if stats := s.containerStats(wl); stats != nil {
+			usedKB += int64(stats.MemoryUsage / 1024)
+		}
+	}
+
+	var b strings.Builder
+	row := func(name string, kb int64) {
+		if kb < 0 {
+			kb = 0
+		}
+		fmt.Fprintf(&b, "%-16s%8d kB\n", name+":", kb)
+	}
+	row("MemTotal", totalKB)
+	row("MemFree", totalKB-usedKB)
+	row("MemAvailable", totalKB-reservedKB)
+	row("CommitLimit", totalKB)
+	row("Committed_AS", reservedKB)
+	s.respondText(w, b.String())
+}
+
+// procLoadavg handles GET /api/v1/proc/loadavg. Without a load average
+// tracker, all three averages report the current number of runnable workloads.
+func (s *Server) procLoadavg(w http.ResponseWriter, r *http.Request) {
+	runnable := 0
+	for _, wl := range s.store.GetAll() {
+		if wl.Status == kernel.StatusRunning || wl.Status == kernel.StatusQueued {
+			runnable++
+		}
+	}
+	total := len(s.store.GetAll())
+	if s.processes != nil {
+		total = s.processes.Count()
+	}
+	avg := kernel.LoadAverages{Load1: float64(runnable), Load5: float64(runnable), Load15: float64(runnable)}
+	if s.load != nil {
+		avg = s.load.Get()
+	}
+	s.respondText(w, fmt.Sprintf("%.2f %.2f %.2f %d/%d %d\n", avg.Load1, avg.Load5, avg.Load15, runnable, total, kernel.PIDs().Last()))
+}
+
+// containerStats returns discovery stats for a workload's container, if any
+func (s *Server) containerStats(wl *kernel.Workload) *runtime.ContainerStats {
+	if s.stats == nil || wl.ContainerID == "" {
+		return nil
+	}
+	return s.stats.GetStats(wl.ContainerID)
+}
+
+// procLimit formats a limit value, treating 0 as unlimited
+func procLimit(v int64) string {
+	if v <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(v, 10)
+}
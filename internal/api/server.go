@@ -3,7 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"ckm/internal/common"
@@ -16,17 +21,32 @@ import (
 // Server provides REST API for workload management
 type Server struct {
 	router      *mux.Router
-	store       *kernel.WorkloadStore
+	store       kernel.Store
 	executor    *kernel.Executor
 	scheduler   kernel.Scheduler
+	schedMu     sync.RWMutex // Guards the scheduler pointer, swapped on reload; schedulers lock their own queues
 	cgroups     *kernel.CGroupManager
 	rateLimiter *common.RateLimiter
+	capacity    *kernel.CapacityDetector
+	processes   *kernel.ProcessManager
+	signals     *kernel.SignalDeliverer
+	stats       kernel.StatsSource
+	load        *kernel.LoadAverage
+	backups     *kernel.BackupManager
+	logs        *kernel.LogStore
+	terminals   Terminals
+	execMu      sync.RWMutex // Guards execConfig, which can change on reload
+	execConfig  common.ExecConfig
+	stateMu     sync.RWMutex  // Held for writing while a backup is taken or restored
+	draining    atomic.Bool   // Set when a shutdown drain starts
+	retryAfter  time.Duration // Retry-After sent while draining
+	closing     chan struct{} // Closed on shutdown to end open watches
 	logger      *zap.Logger
 	httpServer  *http.Server
 }
 
 // NewServer creates a new API server
-func NewServer(store *kernel.WorkloadStore, executor *kernel.Executor, scheduler kernel.Scheduler, cgroups *kernel.CGroupManager, logger *zap.Logger) *Server {
+func NewServer(store kernel.Store, executor *kernel.Executor, scheduler kernel.Scheduler, cgroups *kernel.CGroupManager, logger *zap.Logger) *Server {
 	s := &Server{
 		router:      mux.NewRouter(),
 		store:       store,
@@ -34,8 +54,12 @@ func NewServer(store *kernel.WorkloadStore, executor *kernel.Executor, scheduler
 		scheduler:   scheduler,
 		cgroups:     cgroups,
 		rateLimiter: common.NewRateLimiter(100, 50), // 100 req/sec, burst of 50
+		closing:     make(chan struct{}),
 		logger:      logger,
 	}
+	if executor != nil {
+		s.terminals = executor
+	}
 	s.setupRoutes()
 	return s
 }
@@ -43,12 +67,49 @@ func NewServer(store *kernel.WorkloadStore, executor *kernel.Executor, scheduler
 // setupRoutes configures API endpoints
 func (s *Server) setupRoutes() {
 	api := s.router.PathPrefix("/api/v1").Subrouter()
+	api.Use(s.drainMiddleware)
 	api.Use(s.rateLimitMiddleware)
 	api.HandleFunc("/workloads", s.createWorkload).Methods("POST")
 	api.HandleFunc("/workloads", s.listWorkloads).Methods("GET")
+	api.HandleFunc("/workloads", s.deleteWorkloads).Methods("DELETE")
 	api.HandleFunc("/workloads/{id}", s.getWorkload).Methods("GET")
+	api.HandleFunc("/workloads/{id}", s.patchWorkload).Methods("PATCH")
+	api.HandleFunc("/workloads/{id}/history", s.getWorkloadHistory).Methods("GET")
+	api.HandleFunc("/workloads/{id}/logs", s.getWorkloadLogs).Methods("GET")
+	api.HandleFunc("/workloads/{id}/exec", s.execWorkload).Methods("GET")
+	api.HandleFunc("/workloads/{id}/attach", s.attachWorkload).Methods("GET")
 	api.HandleFunc("/workloads/{id}", s.deleteWorkload).Methods("DELETE")
+	api.HandleFunc("/workloads/{id}/signal", s.signalWorkload).Methods("POST")
+	api.HandleFunc("/sessions", s.createSession).Methods("POST")
+	api.HandleFunc("/sessions/{sid}", s.getSession).Methods("GET")
+	api.HandleFunc("/sessions/{sid}", s.hangupSession).Methods("DELETE")
+	api.HandleFunc("/sessions/{sid}/foreground", s.setForeground).Methods("PUT")
+	api.HandleFunc("/sessions/{sid}/suspend", s.suspendSession).Methods("POST")
+	api.HandleFunc("/sessions/{sid}/resume", s.resumeSession).Methods("POST")
+	api.HandleFunc("/processes/{pid}/tree", s.getProcessTree).Methods("GET")
+	api.HandleFunc("/processes/{pid}/wait", s.waitProcess).Methods("GET")
+	api.HandleFunc("/namespaces/{ns}/processes/{pid}", s.getNamespacedProcess).Methods("GET")
+	api.HandleFunc("/proc/meminfo", s.procMeminfo).Methods("GET")
+	api.HandleFunc("/proc/loadavg", s.procLoadavg).Methods("GET")
+	api.HandleFunc("/proc/{pid:[0-9]+}/status", s.procStatus).Methods("GET")
+	api.HandleFunc("/proc/{pid:[0-9]+}/cgroup", s.procCgroup).Methods("GET")
+	api.HandleFunc("/proc/{pid:[0-9]+}/limits", s.procLimits).Methods("GET")
+	api.HandleFunc("/capacity", s.getCapacity).Methods("GET")
 	api.HandleFunc("/health", s.healthCheck).Methods("GET")
+	api.HandleFunc("/admin/backup", s.exportBackup).Methods("GET")
+	api.HandleFunc("/admin/restore", s.restoreBackup).Methods("POST")
+}
+
+// drainMiddleware rejects changes with 503 and Retry-After once a shutdown drain starts
+func (s *Server) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() && r.Method != http.MethodGet {
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.retryAfter.Seconds())))
+			s.respondError(w, http.StatusServiceUnavailable, "Server is shutting down")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // rateLimitMiddleware applies rate limiting to all API requests
@@ -69,43 +130,226 @@ func (s *Server) createWorkload(w http.ResponseWriter, r *http.Request) {
 		s.respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if req.ID == "" {
+		s.respondError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if _, exists := s.store.Get(req.ID); exists {
+		s.respondError(w, http.StatusConflict, "Workload already exists")
+		return
+	}
 
 	// Create workload with PID
 	wl := &kernel.Workload{
-		ID:       req.ID,
-		PID:      kernel.NextPID(),
-		Type:     req.Type,
-		MemoryMB: req.MemoryMB,
-		Image:    req.Image,
-		Command:  req.Command,
-		Priority: req.Priority,
-		Status:   "waiting",
+		ID:                 req.ID,
+		Namespace:          req.Namespace,
+		PPID:               req.ParentPID,
+		Type:               req.Type,
+		MemoryMB:           req.MemoryMB,
+		MemoryLimitMB:      req.MemoryLimitMB,
+		CPUMillicores:      req.CPUMillicores,
+		CPULimitMillicores: req.CPULimitMillicores,
+		PIDsLimit:          req.PIDsLimit,
+		DiskMB:             req.EphemeralStorageMB,
+		Image:              req.Image,
+		Command:            req.Command,
+		Priority:           req.Priority,
+		OOMRetry:           req.OOMRetry,
+		Status:             kernel.StatusPending,
+
+		Labels:                  req.Labels,
+		TTLSecondsAfterFinished: req.TTLSecondsAfterFinished,
+	}
+	if err := kernel.ValidateLabels(wl.Labels); err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if wl.TTLSecondsAfterFinished != nil && *wl.TTLSecondsAfterFinished < 0 {
+		s.respondError(w, http.StatusBadRequest, "ttl_seconds_after_finished must not be negative")
+		return
+	}
+	if (wl.MemoryLimitMB > 0 && wl.MemoryLimitMB < wl.MemoryMB) ||
+		(wl.CPULimitMillicores > 0 && wl.CPULimitMillicores < wl.CPUMillicores) {
+		s.respondError(w, http.StatusBadRequest, "Limits must not be lower than requests")
+		return
+	}
+	wl.QoSClass = kernel.ClassifyQoS(wl)
+
+	// A child workload needs a live parent to fork from
+	if wl.PPID > 0 && s.processes != nil {
+		parent, ok := s.processes.GetProcess(wl.PPID)
+		if !ok || parent.State != "running" {
+			s.respondError(w, http.StatusBadRequest, "Parent process not found or not running")
+			return
+		}
+		// Children live in their parent's PID namespace
+		if pw, ok := s.workloadsByPID()[wl.PPID]; ok {
+			wl.Namespace = pw.Namespace
+		}
+	}
+
+	// Jobs can only be launched into an open session
+	if req.SessionID > 0 && wl.PPID == 0 && s.processes != nil {
+		sess, ok := s.processes.GetSession(req.SessionID)
+		if !ok {
+			s.respondError(w, http.StatusBadRequest, "Session not found")
+			return
+		}
+		if sess.State == kernel.SessionClosed {
+			s.respondError(w, http.StatusConflict, "Session is closed")
+			return
+		}
 	}
 
-	// Allocate memory via cgroups
-	if !s.cgroups.Allocate(wl.ID, wl.MemoryMB) {
-		s.respondError(w, http.StatusInsufficientStorage, "Not enough memory")
+	if err := s.admit(wl, req.SessionID, req.Background, false); err != nil {
+		status := http.StatusInsufficientStorage
+		switch {
+		case errors.Is(err, kernel.ErrPIDExhausted):
+			status = http.StatusServiceUnavailable
+		case errors.Is(err, kernel.ErrWorkloadExists):
+			status = http.StatusConflict // Created by a concurrent request
+		case errors.Is(err, kernel.ErrSessionClosed), errors.Is(err, kernel.ErrNoSuchSession):
+			status = http.StatusConflict // Hung up since it was checked
+		}
+		s.respondError(w, status, err.Error())
 		return
 	}
 
+	if cur, ok := s.store.Get(wl.ID); ok {
+		wl = cur
+	}
+	setETag(w, wl)
+	s.respondJSON(w, http.StatusCreated, wl)
+}
+
+// admit gives a validated workload its PID and resource reservation,
+// registers it in the process table and starts it. Only a resumed workload
+// may replace the stored record with its ID.
+func (s *Server) admit(wl *kernel.Workload, sessionID int, background, resume bool) error {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+
+	// Allocate a global PID, plus one inside the tenant's PID namespace
+	pid, nspid, err := kernel.AllocPID(wl.Namespace)
+	if err != nil {
+		return err
+	}
+	wl.PID, wl.NSPID = pid, nspid
+
+	// Reserve memory, CPU, PIDs and disk via cgroups
+	if err := s.cgroups.AllocateResources(wl.ID, wl.Resources()); err != nil {
+		kernel.ReleasePID(pid)
+		return err
+	}
+
+	// Register in the process table; top-level workloads lead their own group (job)
+	if s.processes != nil {
+		s.processes.CreateProcess(wl.PID, wl.PPID)
+		if wl.PPID == 0 {
+			s.processes.CreateProcessGroup(wl.PID, wl.PID)
+		}
+	}
+
 	// Add to store and scheduler
-	s.store.Add(wl)
+	if resume {
+		s.store.Add(wl)
+	} else if err := s.store.Create(wl); err != nil {
+		s.unadmit(wl)
+		return err
+	}
+
+	// Join the session only once the workload is stored: a hang-up closes the
+	// session before cancelling its members, so it either turns this join away
+	// or finds the workload and cancels it
+	if s.processes != nil && wl.PPID == 0 && sessionID > 0 {
+		err := s.processes.JoinSession(sessionID, wl.PID)
+		if err == nil && !background {
+			err = s.processes.SetForeground(sessionID, wl.PID)
+		}
+		if err != nil {
+			s.store.Delete(wl.ID)
+			s.unadmit(wl)
+			return err
+		}
+	}
+	// The read lock only keeps the scheduler from being swapped; concurrent
+	// Adds are serialized inside the scheduler
+	s.schedMu.RLock()
 	s.scheduler.Add(*wl)
+	s.schedMu.RUnlock()
+	s.store.SetStatus(wl.ID, kernel.StatusQueued, "Admitted", "")
 
 	// Update metrics
-	common.MemoryUsed.Set(float64(s.cgroups.GetUsedMemory()))
+	s.recordResourceUsage()
 	common.SchedulerQueueLength.WithLabelValues("default").Inc()
 
 	// Execute asynchronously
 	ctx := context.Background()
 	s.executor.ExecuteAsync(ctx, wl)
+	return nil
+}
 
-	s.respondJSON(w, http.StatusCreated, wl)
+// unadmit gives back the PID, reservation and process table entry of a
+// workload admit couldn't finish
+func (s *Server) unadmit(wl *kernel.Workload) {
+	s.cgroups.FreeResources(wl.ID, wl.Resources())
+	if s.processes != nil {
+		s.processes.RemoveProcess(wl.PID) // Frees the PID too
+	} else {
+		kernel.ReleasePID(wl.PID)
+	}
+}
+
+// ResumeWorkloads resubmits workloads persisted by a shutdown drain. They get
+// fresh PIDs; a child whose parent wasn't resumed becomes a top-level job.
+func (s *Server) ResumeWorkloads(workloads []*kernel.Workload) int {
+	resumed := 0
+	newPIDs := make(map[int]int) // Old PID -> PID after resuming
+	for _, wl := range workloads {
+		oldPID := wl.PID
+		if ppid, ok := newPIDs[wl.PPID]; ok && s.processes != nil {
+			wl.PPID = ppid
+		} else {
+			wl.PPID = 0
+		}
+		wasInterrupted := wl.Status == kernel.StatusInterrupted
+		wl.Status, wl.Reason, wl.Message = kernel.StatusPending, "", ""
+		if wasInterrupted {
+			wl.Reason = "Resumed"
+			wl.Message = "resumed after a shutdown interrupted it"
+		}
+		wl.ContainerID, wl.ExitCode = "", 0
+		wl.StartedAt, wl.CompletedAt = time.Time{}, time.Time{}
+		wl.Signals = nil
+
+		if err := s.admit(wl, 0, true, true); err != nil {
+			s.logger.Error("Failed to resume workload", zap.String("id", wl.ID), zap.Error(err))
+			continue
+		}
+		newPIDs[oldPID] = wl.PID
+		resumed++
+	}
+	return resumed
 }
 
 // listWorkloads handles GET /api/v1/workloads
 func (s *Server) listWorkloads(w http.ResponseWriter, r *http.Request) {
-	workloads := s.store.GetAll()
+	if r.URL.Query().Get("watch") == "true" {
+		s.watchWorkloads(w, r)
+		return
+	}
+	opts, err := parseListOptions(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Read the version first so a watch from it can't miss a change made during the list
+	w.Header().Set(ResourceVersionHeader, strconv.FormatUint(s.store.ResourceVersion(), 10))
+	workloads, next := opts.page(s.store.GetAll())
+	if next != "" {
+		w.Header().Set(ContinueHeader, next)
+	}
 	s.respondJSON(w, http.StatusOK, workloads)
 }
 
@@ -117,35 +361,270 @@ func (s *Server) getWorkload(w http.ResponseWriter, r *http.Request) {
 		s.respondError(w, http.StatusNotFound, "Workload not found")
 		return
 	}
+	setETag(w, wl)
 	s.respondJSON(w, http.StatusOK, wl)
 }
 
-// deleteWorkload handles DELETE /api/v1/workloads/{id}
-func (s *Server) deleteWorkload(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	wl, ok := s.store.Get(vars["id"])
+// getWorkloadHistory handles GET /api/v1/workloads/{id}/history, returning
+// every status transition oldest first
+func (s *Server) getWorkloadHistory(w http.ResponseWriter, r *http.Request) {
+	wl, ok := s.store.Get(mux.Vars(r)["id"])
 	if !ok {
 		s.respondError(w, http.StatusNotFound, "Workload not found")
 		return
 	}
+	history := wl.History
+	if history == nil {
+		history = []kernel.Transition{}
+	}
+	setETag(w, wl)
+	s.respondJSON(w, http.StatusOK, history)
+}
+
+// maxPatchAttempts bounds how often an unconditional update retries after losing a race
+const maxPatchAttempts = 5
+
+// patchWorkload handles PATCH /api/v1/workloads/{id}. Changes to a queued
+// workload apply when it's dispatched.
+func (s *Server) patchWorkload(w http.ResponseWriter, r *http.Request) {
+	var req PatchWorkloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := kernel.ValidateLabels(req.Labels); err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.TTLSecondsAfterFinished != nil && *req.TTLSecondsAfterFinished < 0 {
+		s.respondError(w, http.StatusBadRequest, "ttl_seconds_after_finished must not be negative")
+		return
+	}
+	expected, conditional, err := ifMatch(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := mux.Vars(r)["id"]
+	updated, err := s.updateWorkload(id, expected, conditional, req.apply)
+	if err != nil {
+		s.respondUpdateError(w, id, err)
+		return
+	}
+	setETag(w, updated)
+	s.respondJSON(w, http.StatusOK, updated)
+}
+
+// deleteWorkload handles DELETE /api/v1/workloads/{id}
+func (s *Server) deleteWorkload(w http.ResponseWriter, r *http.Request) {
+	expected, conditional, err := ifMatch(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if _, err := s.removeWorkload(id, expected, conditional); err != nil {
+		s.respondUpdateError(w, id, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// removeWorkload cancels an unfinished workload, removes it from the store,
+// stops its container and frees its resources
+func (s *Server) removeWorkload(id string, expected uint64, conditional bool) (*kernel.Workload, error) {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+
+	var wasRunning bool
+	wl, err := s.updateWorkload(id, expected, conditional, func(cur *kernel.Workload) {
+		wasRunning = cur.Status == kernel.StatusRunning
+		if !kernel.IsTerminal(cur.Status) {
+			cur.Status, cur.Reason, cur.Message = kernel.StatusCancelled, "Deleted", "deleted through the API"
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The version was checked above; the executor may still record a container ID
+	s.store.CompareAndDelete(wl.ID, 0)
 
 	// Stop container if running
-	if wl.ContainerID != "" && wl.Status == "running" {
+	if wl.ContainerID != "" && wasRunning {
 		ctx := context.Background()
 		_ = s.executor.StopContainer(ctx, wl.ContainerID)
 	}
 
-	// Free memory and delete
-	s.cgroups.Free(wl.ID, wl.MemoryMB)
-	s.store.Delete(wl.ID)
-	common.MemoryUsed.Set(float64(s.cgroups.GetUsedMemory()))
+	// Free reserved resources and delete
+	s.cgroups.Release(wl.ID)
+	if s.processes != nil {
+		s.processes.TerminateProcess(wl.PID)
+	}
+	s.recordResourceUsage()
+	return wl, nil
+}
+
+// errTooManyConflicts is returned when an unconditional update keeps losing races
+var errTooManyConflicts = errors.New("workload is changing too quickly; retry")
 
-	s.respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+// updateWorkload applies fn to workload id with compare-and-swap. When
+// conditional, the update applies only to the expected version (0 for any)
+// and a mismatch is kernel.ErrConflict; otherwise a lost race is retried
+// against the latest version.
+func (s *Server) updateWorkload(id string, expected uint64, conditional bool, fn func(*kernel.Workload)) (*kernel.Workload, error) {
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		wl, ok := s.store.Get(id)
+		if !ok {
+			return nil, kernel.ErrWorkloadNotFound
+		}
+		if conditional && expected != 0 && wl.ResourceVersion != expected {
+			return nil, kernel.ErrConflict
+		}
+
+		fn(wl)
+		updated, err := s.store.CompareAndSwap(wl)
+		if errors.Is(err, kernel.ErrConflict) && (!conditional || expected == 0) {
+			continue // Someone else won; update their version instead
+		}
+		return updated, err
+	}
+	return nil, errTooManyConflicts
+}
+
+// respondUpdateError maps an updateWorkload error onto a response
+func (s *Server) respondUpdateError(w http.ResponseWriter, id string, err error) {
+	var transitionErr *kernel.TransitionError
+	switch {
+	case errors.Is(err, kernel.ErrConflict):
+		if cur, ok := s.store.Get(id); ok {
+			s.respondConflict(w, cur.ResourceVersion)
+			return
+		}
+		s.respondError(w, http.StatusNotFound, "Workload not found")
+	case errors.Is(err, kernel.ErrWorkloadNotFound):
+		s.respondError(w, http.StatusNotFound, "Workload not found")
+	case errors.As(err, &transitionErr), errors.Is(err, errTooManyConflicts):
+		s.respondError(w, http.StatusConflict, err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// getCapacity handles GET /api/v1/capacity
+func (s *Server) getCapacity(w http.ResponseWriter, r *http.Request) {
+	resp := CapacityResponse{
+		Allocatable: s.cgroups.GetCapacity(),
+		Used:        s.cgroups.GetUsed(),
+	}
+	if s.capacity != nil {
+		if report, ok := s.capacity.Last(); ok {
+			resp.Detected = report
+		}
+	}
+	s.respondJSON(w, http.StatusOK, resp)
 }
 
 // healthCheck handles GET /api/v1/health
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
-	s.respondJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	resp := HealthResponse{Status: "healthy"}
+	if s.load != nil {
+		avg := s.load.Get()
+		resp.LoadAverage = &avg
+	}
+	// Fail health checks while draining so load balancers stop routing here
+	if s.draining.Load() {
+		resp.Status = "draining"
+		s.respondJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// SetCapacityDetector exposes host capacity detection on /api/v1/capacity
+func (s *Server) SetCapacityDetector(d *kernel.CapacityDetector) {
+	s.capacity = d
+}
+
+// SetProcessManager registers workloads in a process table and enables /processes
+func (s *Server) SetProcessManager(pm *kernel.ProcessManager) {
+	s.processes = pm
+}
+
+// SetBackupManager enables the /admin/backup and /admin/restore endpoints
+func (s *Server) SetBackupManager(b *kernel.BackupManager) {
+	s.backups = b
+}
+
+// SetLogStore serves saved container output on /workloads/{id}/logs once containers are removed
+func (s *Server) SetLogStore(logs *kernel.LogStore) {
+	s.logs = logs
+}
+
+// SetTerminals replaces where exec and attach sessions are opened (the executor by default)
+func (s *Server) SetTerminals(t Terminals) {
+	s.terminals = t
+}
+
+// SetExecConfig sets who may open exec and attach sessions
+func (s *Server) SetExecConfig(cfg common.ExecConfig) {
+	s.execMu.Lock()
+	defer s.execMu.Unlock()
+	s.execConfig = cfg
+}
+
+// SetSignalDeliverer enables POST /workloads/{id}/signal
+func (s *Server) SetSignalDeliverer(d *kernel.SignalDeliverer) {
+	s.signals = d
+}
+
+// SetScheduler swaps the scheduler, re-queueing workloads still waiting to run
+func (s *Server) SetScheduler(sched kernel.Scheduler) {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+	for _, wl := range s.store.GetAll() {
+		if wl.Status == kernel.StatusQueued {
+			sched.Add(*wl)
+		}
+	}
+	s.scheduler = sched
+}
+
+// Dequeue takes a workload off the scheduler queue once the executor picks it up
+func (s *Server) Dequeue(id string) {
+	s.schedMu.RLock()
+	defer s.schedMu.RUnlock()
+	if kernel.Dequeue(s.scheduler, id) {
+		common.SchedulerQueueLength.WithLabelValues("default").Dec()
+	}
+}
+
+// StartDrain stops admitting work; changes get 503 with Retry-After until shutdown
+func (s *Server) StartDrain(retryAfter time.Duration) {
+	s.retryAfter = retryAfter
+	s.draining.Store(true)
+	s.logger.Info("API draining, rejecting new work", zap.Duration("retry_after", retryAfter))
+}
+
+// Scheduler returns the scheduler new workloads are queued on
+func (s *Server) Scheduler() kernel.Scheduler {
+	s.schedMu.RLock()
+	defer s.schedMu.RUnlock()
+	return s.scheduler
+}
+
+// SetRateLimit changes the API rate limit
+func (s *Server) SetRateLimit(rate, burst float64) {
+	s.rateLimiter.SetLimits(rate, burst)
+}
+
+// SetStatsSource feeds real container usage into the /proc endpoints
+func (s *Server) SetStatsSource(stats kernel.StatsSource) {
+	s.stats = stats
+}
+
+// SetLoadAverage reports run queue load averages on /health and /proc/loadavg
+func (s *Server) SetLoadAverage(l *kernel.LoadAverage) {
+	s.load = l
 }
 
 // Start starts the HTTP server
@@ -156,6 +635,8 @@ func (s *Server) Start(addr string) error {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
+	// Shutdown waits for open connections, so watches must end on their own
+	s.httpServer.RegisterOnShutdown(func() { close(s.closing) })
 	s.logger.Info("Starting API server", zap.String("addr", addr))
 	return s.httpServer.ListenAndServe()
 }
@@ -166,22 +647,120 @@ func (s *Server) Shutdown(ctx context.Context) error {
 }
 
 // Helper functions
+func (s *Server) recordResourceUsage() {
+	used, capacity := s.cgroups.GetUsed(), s.cgroups.GetCapacity()
+	common.MemoryUsed.Set(float64(used.MemoryMB))
+	common.RecordResources(common.ResourceUsed, used.MemoryMB, used.CPUMillicores, used.PIDs, used.DiskMB)
+	common.RecordResources(common.ResourceCapacity, capacity.MemoryMB, capacity.CPUMillicores, capacity.PIDs, capacity.DiskMB)
+}
+
 func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
 
+func (s *Server) respondText(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body))
+}
+
 func (s *Server) respondError(w http.ResponseWriter, status int, message string) {
 	s.respondJSON(w, status, map[string]string{"error": message})
 }
 
+// respondConflict reports a failed If-Match with the workload's current ETag
+func (s *Server) respondConflict(w http.ResponseWriter, current uint64) {
+	w.Header().Set("ETag", etag(current))
+	s.respondError(w, http.StatusConflict, kernel.ErrConflict.Error())
+}
+
+// etag formats a resource version as a strong entity tag
+func etag(rv uint64) string {
+	return `"` + strconv.FormatUint(rv, 10) + `"`
+}
+
+// setETag sets the ETag header to a workload's resource version
+func setETag(w http.ResponseWriter, wl *kernel.Workload) {
+	w.Header().Set("ETag", etag(wl.ResourceVersion))
+}
+
+// ifMatch parses an If-Match header holding one ETag or "*". It returns the
+// version to match (0 for "*") and whether the header was present.
+func ifMatch(r *http.Request) (uint64, bool, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" {
+		return 0, false, nil
+	}
+	if v == "*" {
+		return 0, true, nil
+	}
+	rv, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil || rv == 0 {
+		return 0, false, errors.New("If-Match must be an ETag from this API or *")
+	}
+	return rv, true, nil
+}
+
+// HealthResponse is the health check result with the current load averages
+type HealthResponse struct {
+	Status      string               `json:"status"`
+	LoadAverage *kernel.LoadAverages `json:"load_average,omitempty"`
+}
+
 // CreateWorkloadRequest represents workload creation request
 type CreateWorkloadRequest struct {
-	ID       string   `json:"id"`
-	Type     string   `json:"type"`
-	MemoryMB int      `json:"memory_mb"`
-	Image    string   `json:"image"`
-	Command  []string `json:"command"`
-	Priority int      `json:"priority"`
+	ID                 string   `json:"id"`
+	Type               string   `json:"type"`
+	MemoryMB           int      `json:"memory_mb"`
+	MemoryLimitMB      int      `json:"memory_limit_mb"`
+	CPUMillicores      int64    `json:"cpu_millicores"`
+	CPULimitMillicores int64    `json:"cpu_limit_millicores"`
+	PIDsLimit          int64    `json:"pids_limit"`
+	EphemeralStorageMB int64    `json:"ephemeral_storage_mb"`
+	Image              string   `json:"image"`
+	Command            []string `json:"command"`
+	Priority           int      `json:"priority"`
+	Namespace          string   `json:"namespace"`  // PID namespace (tenant)
+	ParentPID          int      `json:"parent_pid"` // Spawn as a child of this workload (global PID)
+	SessionID          int      `json:"session_id"` // Launch as a job in this session
+	Background         bool     `json:"background"` // Don't make the job the session's foreground
+
+	OOMRetry *kernel.OOMRetryPolicy `json:"oom_retry,omitempty"`
+	Labels   map[string]string      `json:"labels,omitempty"`
+
+	// Seconds to keep the workload after it finishes; omitted uses gc.ttl_after_finished
+	TTLSecondsAfterFinished *int `json:"ttl_seconds_after_finished,omitempty"`
+}
+
+// PatchWorkloadRequest lists the fields PATCH can change; omitted fields are kept
+type PatchWorkloadRequest struct {
+	Priority                *int                   `json:"priority,omitempty"`
+	OOMRetry                *kernel.OOMRetryPolicy `json:"oom_retry,omitempty"`
+	TTLSecondsAfterFinished *int                   `json:"ttl_seconds_after_finished,omitempty"`
+	Labels                  map[string]string      `json:"labels,omitempty"` // Replaces all labels
+}
+
+// apply copies the fields set in the patch onto wl
+func (p PatchWorkloadRequest) apply(wl *kernel.Workload) {
+	if p.Priority != nil {
+		wl.Priority = *p.Priority
+	}
+	if p.OOMRetry != nil {
+		wl.OOMRetry = p.OOMRetry
+	}
+	if p.TTLSecondsAfterFinished != nil {
+		wl.TTLSecondsAfterFinished = p.TTLSecondsAfterFinished
+	}
+	if p.Labels != nil {
+		wl.Labels = p.Labels
+	}
+}
+
+// CapacityResponse reports what workloads can reserve and what is reserved
+type CapacityResponse struct {
+	Allocatable kernel.Resources       `json:"allocatable"`
+	Used        kernel.Resources       `json:"used"`
+	Detected    *kernel.CapacityReport `json:"detected,omitempty"`
 }
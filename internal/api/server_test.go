@@ -1,15 +1,29 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"ckm/internal/common"
 	"ckm/internal/kernel"
+	"ckm/internal/runtime"
 	"go.uber.org/zap"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 )
 
 // setupTestServer creates a test server without Docker runtime
@@ -155,3 +169,954 @@ func TestCreateWorkloadRequest(t *testing.T) {
 		t.Errorf("Expected MemoryMB 256, got %d", parsed.MemoryMB)
 	}
 }
+
+// TestGetProcessTree tests the nested process tree endpoint
+func TestGetProcessTree(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+
+	s.store.Add(&kernel.Workload{ID: "parent", PID: 2001, Status: "running"})
+	s.store.Add(&kernel.Workload{ID: "child", PID: 2002, PPID: 2001, Status: "queued"})
+	s.processes.CreateProcess(2001, 0)
+	s.processes.CreateProcessGroup(2001, 2001)
+	s.processes.CreateProcess(2002, 2001)
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/processes/2001/tree", nil), map[string]string{"pid": "2001"})
+	w := httptest.NewRecorder()
+
+	s.getProcessTree(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var root ProcessNode
+	json.Unmarshal(w.Body.Bytes(), &root)
+
+	if root.WorkloadID != "parent" || len(root.Children) != 1 {
+		t.Fatalf("Unexpected tree root: %+v", root)
+	}
+	if child := root.Children[0]; child.WorkloadID != "child" || child.Status != "queued" || child.PGID != 2001 {
+		t.Errorf("Unexpected child: %+v", child)
+	}
+}
+
+// TestGetProcessTreeNotFound tests an unknown PID
+func TestGetProcessTreeNotFound(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/processes/9999/tree", nil), map[string]string{"pid": "9999"})
+	w := httptest.NewRecorder()
+
+	s.getProcessTree(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// fakeStats serves fixed container stats to the /proc endpoints
+type fakeStats map[string]*runtime.ContainerStats
+
+func (f fakeStats) GetAllStats() []*runtime.ContainerStats {
+	result := make([]*runtime.ContainerStats, 0, len(f))
+	for _, s := range f {
+		result = append(result, s)
+	}
+	return result
+}
+
+func (f fakeStats) GetStats(containerID string) *runtime.ContainerStats {
+	return f[containerID]
+}
+
+// TestProcStatus tests the /proc/{pid}/status text format
+func TestProcStatus(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+	s.stats = fakeStats{"c1": {ContainerID: "c1", MemoryUsage: 64 << 20, MemoryPeak: 96 << 20, PIDs: 3}}
+
+	s.store.Add(&kernel.Workload{ID: "web", PID: 2001, Namespace: "tenant-a", NSPID: 1, MemoryMB: 128, ContainerID: "c1", Status: "running"})
+	s.processes.CreateProcess(2001, 0)
+	s.processes.CreateProcessGroup(2001, 2001)
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/proc/2001/status", nil), map[string]string{"pid": "2001"})
+	w := httptest.NewRecorder()
+
+	s.procStatus(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Name:\tweb\n", "State:\tR (running)\n", "NSpid:\t2001\t1\n", "NSpgid:\t2001\n", "VmHWM:\t   98304 kB\n", "VmRSS:\t   65536 kB\n", "Threads:\t3\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected status to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestProcLimits tests that requests and limits map to soft and hard limits
+func TestProcLimits(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+
+	s.store.Add(&kernel.Workload{ID: "web", PID: 2001, MemoryMB: 1, MemoryLimitMB: 2, PIDsLimit: 64})
+	s.processes.CreateProcess(2001, 0)
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/proc/2001/limits", nil), map[string]string{"pid": "2001"})
+	w := httptest.NewRecorder()
+
+	s.procLimits(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, fmt.Sprintf("%-25s %-20s %-20s %-10s\n", "Max resident set", "1048576", "2097152", "bytes")) {
+		t.Errorf("Unexpected resident set limit:\n%s", body)
+	}
+	if !strings.Contains(body, fmt.Sprintf("%-25s %-20s %-20s %-10s\n", "Max file size", "unlimited", "unlimited", "bytes")) {
+		t.Errorf("Expected unlimited file size:\n%s", body)
+	}
+}
+
+// TestProcMeminfo tests meminfo generated from capacity, reservations and usage
+func TestProcMeminfo(t *testing.T) {
+	s := setupTestServer()
+	s.stats = fakeStats{"c1": {ContainerID: "c1", MemoryUsage: 100 << 20}}
+
+	s.cgroups.Allocate("web", 256)
+	s.store.Add(&kernel.Workload{ID: "web", PID: 2001, MemoryMB: 256, ContainerID: "c1", Status: "running"})
+
+	w := httptest.NewRecorder()
+	s.procMeminfo(w, httptest.NewRequest("GET", "/api/v1/proc/meminfo", nil))
+
+	want := "MemTotal:        1048576 kB\n" +
+		"MemFree:          946176 kB\n" +
+		"MemAvailable:     786432 kB\n" +
+		"CommitLimit:     1048576 kB\n" +
+		"Committed_AS:     262144 kB\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("Unexpected meminfo:\n%s", got)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain, got %s", ct)
+	}
+}
+
+// TestHealthCheckLoadAverage tests that load averages are reported on /health
+func TestHealthCheckLoadAverage(t *testing.T) {
+	s := setupTestServer()
+	s.load = kernel.NewLoadAverage(func() int { return 2 }, kernel.LoadFrequency)
+	s.load.Tick()
+
+	w := httptest.NewRecorder()
+	s.healthCheck(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+
+	var response HealthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.LoadAverage == nil || response.LoadAverage.Load1 <= 0 {
+		t.Errorf("Expected a positive load1, got %+v", response.LoadAverage)
+	}
+}
+
+// TestDrainRejectsNewWork tests 503 with Retry-After while draining
+func TestDrainRejectsNewWork(t *testing.T) {
+	s := setupTestServer()
+	s.StartDrain(30 * time.Second)
+
+	handler := s.drainMiddleware(http.HandlerFunc(s.listWorkloads))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/workloads", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected 503 with Retry-After 30, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Reads keep working
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/workloads", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected reads to succeed while draining, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.healthCheck(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected health to fail while draining, got %d", w.Code)
+	}
+}
+
+// TestWatchWorkloads tests the SSE stream and resuming from a resource version
+func TestWatchWorkloads(t *testing.T) {
+	s := setupTestServer()
+	s.store.Add(&kernel.Workload{ID: "a", Status: "queued"})
+	ts := httptest.NewServer(http.HandlerFunc(s.listWorkloads))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?watch=true&resourceVersion=1")
+	if err != nil {
+		t.Fatalf("Watch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s.store.SetStatus("a", "scheduled", "", "")
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if lines[0] != "id: 2" || lines[1] != "event: MODIFIED" {
+		t.Errorf("Expected id 2 MODIFIED, got %q", lines[:2])
+	}
+	var event kernel.WatchEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &event); err != nil || event.Object.Status != "scheduled" {
+		t.Errorf("Expected scheduled workload in data, got %q (%v)", lines[2], err)
+	}
+}
+
+// TestWatchWorkloadsGone tests 410 for a version older than the history
+func TestWatchWorkloadsGone(t *testing.T) {
+	s := setupTestServer()
+	for i := 0; i < kernel.DefaultWatchHistory+2; i++ {
+		s.store.Add(&kernel.Workload{ID: "a"})
+	}
+
+	w := httptest.NewRecorder()
+	s.listWorkloads(w, httptest.NewRequest("GET", "/api/v1/workloads?watch=true&resourceVersion=1", nil))
+	if w.Code != http.StatusGone {
+		t.Errorf("Expected 410 Gone, got %d", w.Code)
+	}
+}
+
+// patchRequest builds a PATCH for workload id with an optional If-Match
+func patchRequest(id, body, match string) *http.Request {
+	req := httptest.NewRequest("PATCH", "/api/v1/workloads/"+id, strings.NewReader(body))
+	if match != "" {
+		req.Header.Set("If-Match", match)
+	}
+	return mux.SetURLVars(req, map[string]string{"id": id})
+}
+
+// TestPatchWorkloadIfMatch tests ETags on PATCH and 409 for a stale If-Match
+func TestPatchWorkloadIfMatch(t *testing.T) {
+	s := setupTestServer()
+	s.store.Add(&kernel.Workload{ID: "a", Priority: 1})
+
+	w := httptest.NewRecorder()
+	s.getWorkload(w, mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/workloads/a", nil), map[string]string{"id": "a"}))
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("Expected GET to return an ETag")
+	}
+
+	w = httptest.NewRecorder()
+	s.patchWorkload(w, patchRequest("a", `{"priority":5}`, tag))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Fatalf("Expected 200 with a new ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if wl, _ := s.store.Get("a"); wl.Priority != 5 {
+		t.Errorf("Expected priority 5, got %d", wl.Priority)
+	}
+
+	// The old ETag is stale now
+	w = httptest.NewRecorder()
+	s.patchWorkload(w, patchRequest("a", `{"priority":7}`, tag))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale If-Match, got %d", w.Code)
+	}
+
+	// Without If-Match the patch applies to the latest version
+	w = httptest.NewRecorder()
+	s.patchWorkload(w, patchRequest("a", `{"priority":7}`, ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected unconditional PATCH to succeed, got %d", w.Code)
+	}
+}
+
+// TestDeleteWorkloadIfMatch tests that a stale If-Match leaves the workload in place
+func TestDeleteWorkloadIfMatch(t *testing.T) {
+	s := setupTestServer()
+	wl := &kernel.Workload{ID: "a", Status: "running"}
+	s.store.Add(wl)
+	stale := etag(wl.ResourceVersion)
+	s.store.SetStatus("a", "failed", "", "")
+
+	del := func(match string) int {
+		req := httptest.NewRequest("DELETE", "/api/v1/workloads/a", nil)
+		req.Header.Set("If-Match", match)
+		w := httptest.NewRecorder()
+		s.deleteWorkload(w, mux.SetURLVars(req, map[string]string{"id": "a"}))
+		return w.Code
+	}
+	if code := del(stale); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale If-Match, got %d", code)
+	}
+	if _, ok := s.store.Get("a"); !ok {
+		t.Fatal("Expected a conflicting delete to keep the workload")
+	}
+	cur, _ := s.store.Get("a")
+	if code := del(etag(cur.ResourceVersion)); code != http.StatusOK {
+		t.Errorf("Expected delete at the current ETag to succeed, got %d", code)
+	}
+}
+
+// TestConcurrentAPIAndExecutor runs API clients against executor-style
+// fakeRuntime runs every container to a clean exit after a short wait
+type fakeRuntime struct {
+	created atomic.Int64
+	removed atomic.Int64
+}
+
+func (f *fakeRuntime) CreateContainer(ctx context.Context, image string, cmd []string, limits runtime.ResourceLimits, labels map[string]string) (string, error) {
+	return fmt.Sprintf("c%d", f.created.Add(1)), nil
+}
+func (f *fakeRuntime) StartContainer(ctx context.Context, id string) error { return nil }
+func (f *fakeRuntime) WaitContainer(ctx context.Context, id string) (int64, error) {
+	time.Sleep(time.Millisecond)
+	return 0, nil
+}
+func (f *fakeRuntime) StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	return nil
+}
+func (f *fakeRuntime) KillContainer(ctx context.Context, id, signal string) error { return nil }
+func (f *fakeRuntime) PauseContainer(ctx context.Context, id string) error        { return nil }
+func (f *fakeRuntime) UnpauseContainer(ctx context.Context, id string) error      { return nil }
+func (f *fakeRuntime) RemoveContainer(ctx context.Context, id string) error {
+	f.removed.Add(1)
+	return nil
+}
+func (f *fakeRuntime) InspectContainer(ctx context.Context, id string) (types.ContainerJSON, error) {
+	return types.ContainerJSON{}, nil
+}
+func (f *fakeRuntime) GetContainerLogs(ctx context.Context, id string, opts runtime.LogOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+func (f *fakeRuntime) Exec(ctx context.Context, id string, cmd []string, tty bool) (runtime.TerminalSession, error) {
+	return nil, errors.New("not supported")
+}
+func (f *fakeRuntime) Attach(ctx context.Context, id string) (runtime.TerminalSession, error) {
+	return nil, errors.New("not supported")
+}
+
+// TestConcurrentAPIAndExecutor tests workloads created, run by the executor
+// and read, patched and deleted through the API all at once (run with -race)
+func TestConcurrentAPIAndExecutor(t *testing.T) {
+	s := setupTestServer()
+	rt := &fakeRuntime{}
+	s.executor = kernel.NewExecutor(rt, s.store, zap.NewNop(), 4)
+	s.executor.SetDequeue(s.Dequeue)
+	s.executor.SetCGroupManager(s.cgroups)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("w%d", i)
+		wg.Add(2)
+		go func() { // Clients creating workloads the executor runs
+			defer wg.Done()
+			body := fmt.Sprintf(`{"id":%q,"type":"task","image":"alpine","memory_mb":1}`, id)
+			w := httptest.NewRecorder()
+			s.createWorkload(w, httptest.NewRequest("POST", "/api/v1/workloads", strings.NewReader(body)))
+			if w.Code != http.StatusCreated {
+				t.Errorf("Create %s: got %d %s", id, w.Code, w.Body.String())
+			}
+		}()
+		go func() { // Clients reading and patching while they run
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				s.listWorkloads(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/workloads", nil))
+				s.getWorkload(httptest.NewRecorder(), mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"id": id}))
+				s.patchWorkload(httptest.NewRecorder(), patchRequest(id, fmt.Sprintf(`{"priority":%d}`, n), ""))
+			}
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		done := 0
+		for _, wl := range s.store.GetAll() {
+			if wl.Status == kernel.StatusSucceeded {
+				done++
+			}
+		}
+		held := len(s.cgroups.Reservations())
+		if done == n && held == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d workloads to succeed and release their reservations, got %d with %d still held", n, done, held)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rt.created.Load() != n || rt.removed.Load() != n {
+		t.Errorf("Expected %d containers created and removed, got %d/%d", n, rt.created.Load(), rt.removed.Load())
+	}
+	if queued := kernel.QueueSnapshot(s.scheduler); len(queued) != 0 {
+		t.Errorf("Expected the queue to be empty once everything ran, got %d", len(queued))
+	}
+}
+
+// TestConcurrentAdmitsKeepEveryQueueEntry tests that concurrent creates don't
+// lose each other's scheduler entries
+func TestConcurrentAdmitsKeepEveryQueueEntry(t *testing.T) {
+	s := setupTestServer()
+	s.executor = kernel.NewExecutor(&fakeRuntime{}, s.store, zap.NewNop(), 1)
+	s.executor.StopDispatch() // Leave everything queued
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.admit(&kernel.Workload{ID: fmt.Sprintf("w%d", i), Status: kernel.StatusPending}, 0, false, false)
+		}()
+	}
+	wg.Wait()
+	if queued := kernel.QueueSnapshot(s.scheduler); len(queued) != 50 {
+		t.Errorf("Expected 50 queued workloads, got %d", len(queued))
+	}
+}
+
+// TestCreateRejectsDuplicateID tests that a create never replaces an existing workload
+func TestCreateRejectsDuplicateID(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+	s.store.Add(&kernel.Workload{ID: "web", PID: 2001, MemoryMB: 64, ContainerID: "c1", Status: kernel.StatusRunning})
+	s.cgroups.AllocateResources("web", kernel.Resources{MemoryMB: 64})
+
+	create := func(body string) int {
+		w := httptest.NewRecorder()
+		s.createWorkload(w, httptest.NewRequest("POST", "/api/v1/workloads", strings.NewReader(body)))
+		return w.Code
+	}
+	if code := create(`{"id":"web","type":"task","image":"alpine","memory_mb":32}`); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate ID, got %d", code)
+	}
+	if code := create(`{"type":"task","image":"alpine"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an ID, got %d", code)
+	}
+
+	// A concurrent create that got past the check is undone by admit
+	wl := &kernel.Workload{ID: "web", MemoryMB: 32, Status: kernel.StatusPending}
+	if err := s.admit(wl, 0, false, false); !errors.Is(err, kernel.ErrWorkloadExists) {
+		t.Errorf("Expected ErrWorkloadExists, got %v", err)
+	}
+	if _, ok := s.processes.GetProcess(wl.PID); ok {
+		t.Errorf("Expected process %d removed", wl.PID)
+	}
+	if cur, _ := s.store.Get("web"); cur.Status != kernel.StatusRunning || cur.ContainerID != "c1" {
+		t.Errorf("Expected the running workload untouched, got %s %q", cur.Status, cur.ContainerID)
+	}
+	if r, _ := s.cgroups.GetReservation("web"); r.MemoryMB != 64 {
+		t.Errorf("Expected the original 64MB reservation, got %dMB", r.MemoryMB)
+	}
+}
+
+// TestAdmitIntoClosedSession tests that a job whose session was hung up after
+// the create checked it is turned away instead of running outside the session
+func TestAdmitIntoClosedSession(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+	s.processes.CreateSession(5000, "alice")
+	s.processes.CreateProcess(5001, 0)
+	s.processes.CreateProcessGroup(5001, 5001)
+	if err := s.processes.JoinSession(5000, 5001); err != nil {
+		t.Fatal(err)
+	}
+	s.processes.SetSessionState(5000, kernel.SessionClosed)
+
+	wl := &kernel.Workload{ID: "late", MemoryMB: 32, Status: kernel.StatusPending}
+	if err := s.admit(wl, 5000, false, false); !errors.Is(err, kernel.ErrSessionClosed) {
+		t.Fatalf("Expected ErrSessionClosed, got %v", err)
+	}
+	if _, ok := s.store.Get("late"); ok {
+		t.Error("Expected the workload removed from the store")
+	}
+	if _, ok := s.cgroups.GetReservation("late"); ok {
+		t.Error("Expected the reservation freed")
+	}
+	if _, ok := s.processes.GetProcess(wl.PID); ok {
+		t.Errorf("Expected process %d removed", wl.PID)
+	}
+}
+
+// TestPatchRejectsNegativeTTL tests that PATCH validates the TTL like create does
+func TestPatchRejectsNegativeTTL(t *testing.T) {
+	s := setupTestServer()
+	s.store.Add(&kernel.Workload{ID: "a", Status: kernel.StatusQueued})
+
+	w := httptest.NewRecorder()
+	s.patchWorkload(w, patchRequest("a", `{"ttl_seconds_after_finished":-1}`, ""))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+}
+
+// TestWorkloadHistory tests GET /workloads/{id}/history
+func TestWorkloadHistory(t *testing.T) {
+	s := setupTestServer()
+	s.store.Add(&kernel.Workload{ID: "a", Status: kernel.StatusPending})
+	s.store.SetStatus("a", kernel.StatusQueued, "Admitted", "")
+
+	w := httptest.NewRecorder()
+	s.getWorkloadHistory(w, mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/workloads/a/history", nil), map[string]string{"id": "a"}))
+
+	var history []kernel.Transition
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if w.Code != http.StatusOK || len(history) != 2 {
+		t.Fatalf("Expected 2 transitions, got %d %s", w.Code, w.Body.String())
+	}
+	if history[1].From != kernel.StatusPending || history[1].To != kernel.StatusQueued || history[1].Reason != "Admitted" {
+		t.Errorf("Expected pending -> queued (Admitted), got %+v", history[1])
+	}
+}
+
+// TestDeleteCancelsUnfinishedWorkload tests that a delete moves the workload to cancelled first
+func TestDeleteCancelsUnfinishedWorkload(t *testing.T) {
+	s := setupTestServer()
+	s.store.Add(&kernel.Workload{ID: "a", Status: kernel.StatusQueued})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := s.store.Watch(ctx, s.store.ResourceVersion())
+
+	w := httptest.NewRecorder()
+	s.deleteWorkload(w, mux.SetURLVars(httptest.NewRequest("DELETE", "/api/v1/workloads/a", nil), map[string]string{"id": "a"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if e := <-events; e.Type != kernel.EventModified || e.Object.Status != kernel.StatusCancelled {
+		t.Errorf("Expected MODIFIED cancelled, got %s %s", e.Type, e.Object.Status)
+	}
+	if e := <-events; e.Type != kernel.EventDeleted {
+		t.Errorf("Expected DELETED, got %s", e.Type)
+	}
+}
+
+// addLabeled adds a queued workload with labels and a priority
+func addLabeled(s *Server, id string, priority int, labels map[string]string) {
+	s.store.Add(&kernel.Workload{ID: id, Type: "batch", Status: kernel.StatusQueued, Priority: priority, Labels: labels})
+}
+
+// listIDs lists workloads with a query and returns their IDs and the continue token
+func listIDs(t *testing.T, s *Server, query string) ([]string, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.listWorkloads(w, httptest.NewRequest("GET", "/api/v1/workloads?"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("List %q: expected 200, got %d %s", query, w.Code, w.Body.String())
+	}
+	var workloads []*kernel.Workload
+	json.Unmarshal(w.Body.Bytes(), &workloads)
+	ids := make([]string, len(workloads))
+	for i, wl := range workloads {
+		ids[i] = wl.ID
+	}
+	return ids, w.Header().Get(ContinueHeader)
+}
+
+// TestListWorkloadsFilters tests label selectors and field filters
+func TestListWorkloadsFilters(t *testing.T) {
+	s := setupTestServer()
+	addLabeled(s, "a", 1, map[string]string{"env": "prod", "tier": "web"})
+	addLabeled(s, "b", 2, map[string]string{"env": "prod", "tier": "batch"})
+	addLabeled(s, "c", 3, map[string]string{"env": "dev"})
+	s.store.SetStatus("c", kernel.StatusScheduled, "", "")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"labelSelector=env%3Dprod,tier!%3Dbatch", "a"},
+		{"labelSelector=env+in+(prod,dev)&status=scheduled", "c"},
+		{"status=queued&sortBy=id", "a,b"},
+		{"labelSelector=!tier", "c"},
+		{"type=service", ""},
+	}
+	for _, tt := range tests {
+		ids, _ := listIDs(t, s, tt.query)
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("List %q = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"labelSelector=env%3Dpr+od", "sortBy=memory", "order=up", "createdAfter=yesterday", "continue=garbage"} {
+		w := httptest.NewRecorder()
+		s.listWorkloads(w, httptest.NewRequest("GET", "/api/v1/workloads?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("List %q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// TestListWorkloadsPagination tests sorting and following continue tokens
+func TestListWorkloadsPagination(t *testing.T) {
+	s := setupTestServer()
+	for i := 0; i < 5; i++ {
+		addLabeled(s, fmt.Sprintf("w%d", i), i%3, nil)
+	}
+
+	var pages []string
+	query := "sortBy=priority&order=desc&limit=2"
+	for {
+		ids, next := listIDs(t, s, query)
+		pages = append(pages, strings.Join(ids, ","))
+		if next == "" {
+			break
+		}
+		if len(pages) == 2 {
+			// A workload added mid-listing sorts before the cursor, so it doesn't shift later pages
+			addLabeled(s, "w9", 2, nil)
+		}
+		query = "sortBy=priority&order=desc&limit=2&continue=" + next
+	}
+	if got, want := strings.Join(pages, "|"), "w2,w4|w1,w3|w0"; got != want {
+		t.Errorf("Pages = %q, want %q", got, want)
+	}
+
+	_, next := listIDs(t, s, "limit=1")
+	w := httptest.NewRecorder()
+	s.listWorkloads(w, httptest.NewRequest("GET", "/api/v1/workloads?sortBy=id&continue="+next, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a token from another sort order, got %d", w.Code)
+	}
+}
+
+// TestBulkDeleteWorkloads tests DELETE /workloads with a selector
+func TestBulkDeleteWorkloads(t *testing.T) {
+	s := setupTestServer()
+	addLabeled(s, "a", 0, map[string]string{"env": "dev"})
+	addLabeled(s, "b", 0, map[string]string{"env": "dev"})
+	addLabeled(s, "c", 0, map[string]string{"env": "prod"})
+
+	w := httptest.NewRecorder()
+	s.deleteWorkloads(w, httptest.NewRequest("DELETE", "/api/v1/workloads", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a filter, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.deleteWorkloads(w, httptest.NewRequest("DELETE", "/api/v1/workloads?labelSelector=env%3Ddev", nil))
+	var resp BulkDeleteResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || strings.Join(resp.Deleted, ",") != "a,b" {
+		t.Fatalf("Expected a and b deleted, got %d %s", w.Code, w.Body.String())
+	}
+	if ids, _ := listIDs(t, s, ""); strings.Join(ids, ",") != "c" {
+		t.Errorf("Expected only c to remain, got %v", ids)
+	}
+}
+
+// TestBackupAndRestore exports one server's state and restores it into another
+func TestBackupAndRestore(t *testing.T) {
+	source := setupTestServer()
+	source.backups = kernel.NewBackupManager(source.store, source.cgroups, nil, source.Scheduler, zap.NewNop())
+	addLabeled(source, "a", 0, map[string]string{"env": "prod"})
+	source.store.Add(&kernel.Workload{ID: "b", Status: kernel.StatusFailed})
+
+	w := httptest.NewRecorder()
+	source.exportBackup(w, httptest.NewRequest("GET", "/api/v1/admin/backup", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Disposition"), "ckm-backup-") {
+		t.Fatalf("Expected a backup attachment, got %d %v", w.Code, w.Header())
+	}
+	archive := w.Body.String()
+
+	target := setupTestServer()
+	target.backups = kernel.NewBackupManager(target.store, target.cgroups, nil, target.Scheduler, zap.NewNop())
+	restore := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		target.restoreBackup(w, httptest.NewRequest("POST", "/api/v1/admin/restore"+query, strings.NewReader(archive)))
+		return w
+	}
+	if w := restore("?inflight=later"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown inflight mode, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	target.restoreBackup(w, httptest.NewRequest("POST", "/api/v1/admin/restore", strings.NewReader(`{"version":1,"workloads":[null]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a null workload, got %d", w.Code)
+	}
+	w = restore("?inflight=interrupt")
+	var resp RestoreResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Workloads != 2 || resp.Interrupted != 1 {
+		t.Fatalf("Expected 2 workloads with 1 interrupted, got %d %s", w.Code, w.Body.String())
+	}
+	if wl, _ := target.store.Get("a"); wl.Status != kernel.StatusInterrupted || wl.Labels["env"] != "prod" {
+		t.Errorf("Expected a interrupted with its labels, got %s %v", wl.Status, wl.Labels)
+	}
+	if w := restore("?inflight=interrupt"); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 restoring into a kernel with workloads, got %d", w.Code)
+	}
+}
+
+// TestWorkloadLogs tests reading saved logs with tail, stream, timestamps and format
+func TestWorkloadLogs(t *testing.T) {
+	s := setupTestServer()
+	logs, err := kernel.NewLogStore(t.TempDir(), common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.logs = logs
+	s.store.Add(&kernel.Workload{ID: "a", Status: kernel.StatusRunning})
+	s.store.SetStatus("a", kernel.StatusSucceeded, "", "")
+
+	// A multiplexed Docker stream: stdout, stderr, stdout
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var stream bytes.Buffer
+	for i, line := range []string{"hello", "oops", "bye"} {
+		payload := start.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano) + " " + line + "\n"
+		header := []byte{1, 0, 0, 0, 0, 0, 0, byte(len(payload))}
+		if line == "oops" {
+			header[0] = 2
+		}
+		stream.Write(header)
+		stream.WriteString(payload)
+	}
+	logs.Capture("a", &stream)
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/v1/workloads/"+id+"/logs?"+query, nil)
+		s.getWorkloadLogs(w, mux.SetURLVars(req, map[string]string{"id": id}))
+		return w
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "hello\noops\nbye\n"},
+		{"stream=stderr", "oops\n"},
+		{"tail=1&timestamps=true", "2024-05-01T12:00:02Z bye\n"},
+		{"since=2024-05-01T12:00:00Z&stream=stdout", "bye\n"},
+	}
+	for _, tt := range tests {
+		if w := get("a", tt.query); w.Code != http.StatusOK || w.Body.String() != tt.want {
+			t.Errorf("Logs %q = %d %q, want %q", tt.query, w.Code, w.Body.String(), tt.want)
+		}
+	}
+
+	w := get("a", "format=json&tail=1")
+	var entry runtime.LogEntry
+	json.Unmarshal(w.Body.Bytes(), &entry)
+	if entry.Stream != runtime.StreamStdout || entry.Log != "bye" || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Expected a JSON stdout entry, got %s", w.Body.String())
+	}
+
+	// Saved logs stay readable after the workload is deleted
+	s.store.Delete("a")
+	if w := get("a", "tail=1"); w.Code != http.StatusOK || w.Body.String() != "bye\n" {
+		t.Errorf("Expected logs of a deleted workload, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown workload, got %d", w.Code)
+	}
+	if w := get("a", "tail=-1"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative tail, got %d", w.Code)
+	}
+}
+
+// fakeTerminal is a session that echoes input back as output
+type fakeTerminal struct {
+	stdinR  *io.PipeReader
+	stdinW  *io.PipeWriter
+	tty     bool
+	resized chan TerminalSize
+}
+
+func (f *fakeTerminal) Stdin() io.Writer  { return f.stdinW }
+func (f *fakeTerminal) CloseStdin() error { return f.stdinW.Close() }
+func (f *fakeTerminal) TTY() bool         { return f.tty }
+
+func (f *fakeTerminal) CopyOutput(stdout, stderr io.Writer) error {
+	stderr.Write([]byte("ready\n"))
+	_, err := io.Copy(stdout, f.stdinR)
+	return err
+}
+
+func (f *fakeTerminal) Resize(ctx context.Context, height, width uint) error {
+	f.resized <- TerminalSize{Width: width, Height: height}
+	return nil
+}
+
+func (f *fakeTerminal) ExitCode(ctx context.Context) (int, error) { return 7, nil }
+
+func (f *fakeTerminal) Close() error {
+	f.stdinR.Close()
+	return f.stdinW.Close()
+}
+
+// fakeTerminals opens fakeTerminals and remembers the last command
+type fakeTerminals struct {
+	mu      sync.Mutex
+	command []string
+	session *fakeTerminal
+}
+
+func (f *fakeTerminals) Exec(ctx context.Context, containerID string, cmd []string, tty bool) (runtime.TerminalSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, w := io.Pipe()
+	f.command = cmd
+	f.session = &fakeTerminal{stdinR: r, stdinW: w, tty: tty, resized: make(chan TerminalSize, 1)}
+	return f.session, nil
+}
+
+func (f *fakeTerminals) Attach(ctx context.Context, containerID string) (runtime.TerminalSession, error) {
+	return f.Exec(ctx, containerID, nil, false)
+}
+
+// setupExecServer serves exec and attach for a running workload "a" in namespace ml
+func setupExecServer(t *testing.T) (*Server, *fakeTerminals, string) {
+	s := setupTestServer()
+	terminals := &fakeTerminals{}
+	s.SetTerminals(terminals)
+	s.SetExecConfig(common.ExecConfig{Enabled: true, Users: []common.ExecUser{
+		{Name: "alice", Token: "a-token", Namespaces: []string{"ml"}},
+		{Name: "bob", Token: "b-token", Namespaces: []string{"web"}},
+	}})
+	s.store.Add(&kernel.Workload{ID: "a", Namespace: "ml", Status: kernel.StatusRunning, ContainerID: "c1"})
+	s.store.Add(&kernel.Workload{ID: "q", Namespace: "ml", Status: kernel.StatusQueued})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/workloads/{id}/exec", s.execWorkload)
+	router.HandleFunc("/workloads/{id}/attach", s.attachWorkload)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return s, terminals, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// TestExecAuthorization tests who may open a session and on which workloads
+func TestExecAuthorization(t *testing.T) {
+	s, _, url := setupExecServer(t)
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"no token", "/workloads/a/exec?command=sh", "", http.StatusUnauthorized},
+		{"bad token", "/workloads/a/exec?command=sh", "nope", http.StatusUnauthorized},
+		{"other namespace", "/workloads/a/exec?command=sh", "b-token", http.StatusForbidden},
+		{"not running", "/workloads/q/exec?command=sh", "a-token", http.StatusConflict},
+		{"unknown", "/workloads/x/attach", "a-token", http.StatusNotFound},
+		{"no command", "/workloads/a/exec", "a-token", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.token != "" {
+			header.Set("Authorization", "Bearer "+tt.token)
+		}
+		_, resp, err := websocket.DefaultDialer.Dial(url+tt.path, header)
+		if err == nil || resp == nil || resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %v (%v)", tt.name, tt.want, resp, err)
+		}
+	}
+
+	s.SetExecConfig(common.ExecConfig{})
+	header := http.Header{"Authorization": {"Bearer a-token"}}
+	if _, resp, _ := websocket.DefaultDialer.Dial(url+"/workloads/a/exec?command=sh", header); resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 while exec is disabled, got %v", resp)
+	}
+}
+
+// TestExecSession tests that input, output, resize and the exit status cross the WebSocket
+func TestExecSession(t *testing.T) {
+	_, terminals, url := setupExecServer(t)
+	header := http.Header{"Authorization": {"Bearer a-token"}}
+	ws, _, err := websocket.DefaultDialer.Dial(url+"/workloads/a/exec?command=sh&command=-i&tty=true", header)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() (byte, string) {
+		_, msg, err := ws.ReadMessage()
+		if err != nil || len(msg) == 0 {
+			t.Fatalf("Read failed: %v", err)
+		}
+		return msg[0], string(msg[1:])
+	}
+	if ch, out := read(); ch != channelStderr || out != "ready\n" {
+		t.Fatalf("Expected stderr greeting, got %d %q", ch, out)
+	}
+	terminals.mu.Lock()
+	session, command := terminals.session, terminals.command
+	terminals.mu.Unlock()
+	if strings.Join(command, " ") != "sh -i" || !session.tty {
+		t.Errorf("Expected a TTY session running sh -i, got %v tty=%v", command, session.tty)
+	}
+
+	ws.WriteMessage(websocket.BinaryMessage, append([]byte{channelResize}, `{"width":120,"height":40}`...))
+	select {
+	case size := <-session.resized:
+		if size != (TerminalSize{Width: 120, Height: 40}) {
+			t.Errorf("Expected 120x40, got %+v", size)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resize never reached the session")
+	}
+
+	ws.WriteMessage(websocket.BinaryMessage, append([]byte{channelStdin}, "ls\n"...))
+	if ch, out := read(); ch != channelStdout || out != "ls\n" {
+		t.Errorf("Expected echoed input on stdout, got %d %q", ch, out)
+	}
+
+	ws.WriteMessage(websocket.BinaryMessage, []byte{channelCloseStdin})
+	ch, payload := read()
+	var status ExecStatus
+	if ch != channelStatus || json.Unmarshal([]byte(payload), &status) != nil {
+		t.Fatalf("Expected a status message, got %d %q", ch, payload)
+	}
+	if status.ExitCode == nil || *status.ExitCode != 7 || status.Error != "" {
+		t.Errorf("Expected exit code 7, got %+v", status)
+	}
+}
+
+// TestSignalRoutes tests signalling a workload and a whole process group
+func TestSignalRoutes(t *testing.T) {
+	s := setupTestServer()
+	s.processes = kernel.NewProcessManager()
+	s.signals = kernel.NewSignalDeliverer(s.store, s.processes, &fakeRuntime{}, zap.NewNop())
+	s.processes.CreateProcess(3001, 0)
+	s.processes.CreateProcessGroup(3001, 3001)
+	s.processes.CreateProcess(3002, 3001) // Inherits the group
+	for _, pid := range []int{3001, 3002} {
+		s.store.Add(&kernel.Workload{ID: fmt.Sprintf("w%d", pid), PID: pid, ContainerID: fmt.Sprintf("c%d", pid), Status: kernel.StatusRunning})
+	}
+
+	signal := func(id, body string) (int, SignalResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		s.signalWorkload(w, mux.SetURLVars(req, map[string]string{"id": id}))
+		var resp SignalResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	if code, resp := signal("w3002", `{"signal":"term"}`); code != http.StatusOK || len(resp.Delivered) != 1 {
+		t.Errorf("Expected w3002 signalled, got %d %+v", code, resp)
+	}
+	if code, resp := signal("w3002", `{"signal":"term","pgid":3001}`); code != http.StatusOK || resp.PGID != 3001 || len(resp.Delivered) != 2 {
+		t.Errorf("Expected both group members signalled, got %d %+v", code, resp)
+	}
+	if code, _ := signal("w3002", `{"signal":"term","pgid":4242}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a group w3002 isn't in, got %d", code)
+	}
+	if code, _ := signal("w3002", `{"signal":"term","pgid":-3001}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative PGID, got %d", code)
+	}
+	// A negative ID is just a workload name, not a process group
+	if code, _ := signal("-3001", `{"signal":"term"}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for workload -3001, got %d", code)
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ckm/internal/kernel"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateSessionRequest is the body of POST /api/v1/sessions
+type CreateSessionRequest struct {
+	User string `json:"user"`
+}
+
+// SetForegroundRequest is the body of PUT /api/v1/sessions/{sid}/foreground
+type SetForegroundRequest struct {
+	PGID int `json:"pgid"`
+}
+
+// SessionActionResponse reports the session after a job-control action
+type SessionActionResponse struct {
+	Session   kernel.Session `json:"session"`
+	Signalled []string       `json:"signalled"`
+}
+
+// createSession handles POST /api/v1/sessions
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return
+	}
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.User == "" {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	sid, _, err := kernel.AllocPID("")
+	if err != nil {
+		s.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sess := s.processes.CreateSession(sid, req.User)
+	s.respondJSON(w, http.StatusCreated, sess)
+}
+
+// getSession handles GET /api/v1/sessions/{sid}
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	sid, ok := s.sessionID(w, r)
+	if !ok {
+		return
+	}
+	sess, found := s.processes.GetSession(sid)
+	if !found {
+		s.respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, sess)
+}
+
+// setForeground handles PUT /api/v1/sessions/{sid}/foreground
+func (s *Server) setForeground(w http.ResponseWriter, r *http.Request) {
+	sid, ok := s.sessionID(w, r)
+	if !ok {
+		return
+	}
+	var req SetForegroundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := s.processes.SetForeground(sid, req.PGID); err != nil {
+		s.respondSessionError(w, err)
+		return
+	}
+	sess, _ := s.processes.GetSession(sid)
+	s.respondJSON(w, http.StatusOK, sess)
+}
+
+// hangupSession handles DELETE /api/v1/sessions/{sid}: SIGHUP to every job
+func (s *Server) hangupSession(w http.ResponseWriter, r *http.Request) {
+	s.sessionAction(w, r, s.signals.HangupSession)
+}
+
+// suspendSession handles POST /api/v1/sessions/{sid}/suspend: SIGSTOP background jobs
+func (s *Server) suspendSession(w http.ResponseWriter, r *http.Request) {
+	s.sessionAction(w, r, s.signals.SuspendSession)
+}
+
+// resumeSession handles POST /api/v1/sessions/{sid}/resume: SIGCONT background jobs
+func (s *Server) resumeSession(w http.ResponseWriter, r *http.Request) {
+	s.sessionAction(w, r, s.signals.ResumeSession)
+}
+
+// sessionAction runs a job-control action and responds with the updated session
+func (s *Server) sessionAction(w http.ResponseWriter, r *http.Request, action func(context.Context, int) ([]string, error)) {
+	if s.signals == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Signal delivery not enabled")
+		return
+	}
+	sid, ok := s.sessionID(w, r)
+	if !ok {
+		return
+	}
+	before, _ := s.processes.GetSession(sid)
+	signalled, err := action(context.Background(), sid)
+	if err != nil {
+		s.respondSessionError(w, err)
+		return
+	}
+	sess, ok := s.processes.GetSession(sid)
+	if !ok {
+		// Hanging up a session with no members left removes it
+		sess = before
+		sess.State, sess.PIDs = kernel.SessionClosed, nil
+	}
+	s.respondJSON(w, http.StatusOK, SessionActionResponse{Session: sess, Signalled: signalled})
+}
+
+// sessionID parses the {sid} path variable
+func (s *Server) sessionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if s.processes == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Process table not enabled")
+		return 0, false
+	}
+	sid, err := strconv.Atoi(mux.Vars(r)["sid"])
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return 0, false
+	}
+	return sid, true
+}
+
+// respondSessionError maps session errors onto HTTP status codes
+func (s *Server) respondSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, kernel.ErrNoSuchSession):
+		s.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, kernel.ErrSessionClosed):
+		s.respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, kernel.ErrNotInSession):
+		s.respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.respondSignalError(w, err)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ckm/internal/kernel"
+
+	"github.com/gorilla/mux"
+)
+
+// SignalRequest is the body of POST /api/v1/workloads/{id}/signal
+type SignalRequest struct {
+	Signal string `json:"signal"`         // e.g. "SIGTERM", "term", "SIGSTOP"
+	PGID   int    `json:"pgid,omitempty"` // Signal the workload's whole process group instead
+}
+
+// SignalResponse lists the workloads a signal was delivered to
+type SignalResponse struct {
+	Signal    string   `json:"signal"`
+	PGID      int      `json:"pgid,omitempty"`
+	Delivered []string `json:"delivered"`
+}
+
+// signalWorkload handles POST /api/v1/workloads/{id}/signal. With a pgid the
+// signal goes to every running workload in that group, like kill -SIG -pgid;
+// the workload in the path must be a member, so a stale PGID can't hit
+// somebody else's group.
+func (s *Server) signalWorkload(w http.ResponseWriter, r *http.Request) {
+	if s.signals == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Signal delivery not enabled")
+		return
+	}
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	sig, err := kernel.ParseSignal(req.Signal)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.PGID < 0 {
+		s.respondError(w, http.StatusBadRequest, "Invalid process group ID")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if req.PGID == 0 {
+		if err := s.signals.SignalWorkload(context.Background(), id, sig); err != nil {
+			s.respondSignalError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, SignalResponse{Signal: sig, Delivered: []string{id}})
+		return
+	}
+
+	wl, ok := s.store.Get(id)
+	if !ok {
+		s.respondSignalError(w, kernel.ErrWorkloadNotFound)
+		return
+	}
+	if info, ok := s.processes.GetProcess(wl.PID); !ok || info.PGID != req.PGID {
+		s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Workload %s is not in process group %d", id, req.PGID))
+		return
+	}
+	delivered, err := s.signals.SignalGroup(context.Background(), req.PGID, sig)
+	if err != nil {
+		s.respondSignalError(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, SignalResponse{Signal: sig, PGID: req.PGID, Delivered: delivered})
+}
+
+// respondSignalError maps signal delivery errors onto HTTP status codes
+func (s *Server) respondSignalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, kernel.ErrUnsupportedSignal):
+		s.respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, kernel.ErrWorkloadNotFound), errors.Is(err, kernel.ErrNoSuchGroup):
+		s.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, kernel.ErrNotRunning):
+		s.respondError(w, http.StatusConflict, err.Error())
+	default:
+		s.respondError(w, http.StatusBadGateway, err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ckm/internal/kernel"
+	"go.uber.org/zap"
+)
+
+// ResourceVersionHeader carries the store version a list was taken at
+const ResourceVersionHeader = "X-Resource-Version"
+
+// watchKeepalive is how often an idle watch sends a comment to keep proxies from closing it
+const watchKeepalive = 15 * time.Second
+
+// watchWorkloads handles GET /api/v1/workloads?watch=true&resourceVersion=N.
+// It streams changes newer than N as Server-Sent Events, each with the
+// resource version as its id so a reconnecting client's Last-Event-ID
+// resumes where it left off. Without a version it starts with every
+// current workload as ADDED. A version older than the store's history
+// gets 410 Gone, and the client must list again. The list's label selector
+// and field filters apply to the events.
+func (s *Server) watchWorkloads(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var fromRV uint64
+	v := r.URL.Query().Get("resourceVersion")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		v = id
+	}
+	if v != "" {
+		if fromRV, err = strconv.ParseUint(v, 10, 64); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid resourceVersion")
+			return
+		}
+	}
+
+	events, err := s.store.Watch(r.Context(), fromRV)
+	if errors.Is(err, kernel.ErrResourceVersionGone) {
+		s.respondError(w, http.StatusGone, err.Error())
+		return
+	} else if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{}) // A watch outlives the server's write timeout
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	keepalive := time.NewTicker(watchKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				// The watcher fell behind; the client reconnects with its last id
+				s.logger.Debug("Watch closed by store", zap.Uint64("from", fromRV))
+				return
+			}
+			fromRV = e.ResourceVersion
+			if !opts.matches(e.Object) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				s.logger.Error("Failed to encode watch event", zap.Error(err))
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ResourceVersion, e.Type, data); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-s.closing:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
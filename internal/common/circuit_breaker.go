@@ -14,6 +14,20 @@ const (
 	StateHalfOpen                   // Testing if service recovered
 )
 
+// String returns the state name
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
 // CircuitBreaker prevents cascading failures by stopping requests when service is failing
 type CircuitBreaker struct {
 	maxFailures int           // Failures before opening circuit
@@ -76,6 +90,14 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 	return nil
 }
 
+// SetThresholds changes the failure threshold and open timeout
+func (cb *CircuitBreaker) SetThresholds(maxFailures int, timeout time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.maxFailures = maxFailures
+	cb.timeout = timeout
+}
+
 // GetState returns current circuit state
 func (cb *CircuitBreaker) GetState() CircuitState {
 	cb.mu.RLock()
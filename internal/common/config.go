@@ -1,9 +1,13 @@
 package common
 
 import (
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 )
 
@@ -34,3 +38,255 @@ func ParseCPUTime(rawTime string) time.Duration {
     d, _ := time.ParseDuration(rawTime)
     return d
 }
+
+// Scheduler names accepted in KernelConfig
+var SchedulerNames = []string{"fifo", "round_robin", "priority", "fair", "multilevel"}
+
+// PID range for pid_max: global PIDs below FirstPID are reserved, and
+// MaxPIDMax is Linux's PID_MAX_LIMIT
+const (
+	FirstPID  = 1001
+	MaxPIDMax = 4194304
+)
+
+// Orphan policies accepted in KernelConfig
+var OrphanPolicies = []string{"adopt", "remove", "ignore"}
+
+// RateLimitConfig configures the API token bucket
+type RateLimitConfig struct {
+	Rate  float64 `yaml:"rate"`  // Requests per second
+	Burst float64 `yaml:"burst"` // Bucket capacity
+}
+
+// CircuitBreakerConfig configures the Docker circuit breaker
+type CircuitBreakerConfig struct {
+	MaxFailures int           `yaml:"max_failures"`
+	Timeout     time.Duration `yaml:"timeout"` // Time before trying half-open
+}
+
+// GCConfig configures garbage collection of finished workloads
+type GCConfig struct {
+	TTLAfterFinished   time.Duration `yaml:"ttl_after_finished"`    // Default for workloads without ttlSecondsAfterFinished
+	MaxFinishedPerType int           `yaml:"max_finished_per_type"` // Newest finished workloads kept per type; 0 = no cap
+	Interval           time.Duration `yaml:"interval"`              // Time between collections
+}
+
+// ContainerLogsConfig configures the log files kept for each workload after its container is removed
+type ContainerLogsConfig struct {
+	MaxSizeMB int           `yaml:"max_size_mb"` // Size at which a workload's log file is rotated
+	MaxFiles  int           `yaml:"max_files"`   // Files kept per workload, including the current one
+	Retention time.Duration `yaml:"retention"`   // Log files not written for this long are deleted; 0 = keep
+}
+
+// CapacityConfig sets aside host resources that workloads may not reserve
+// (like kubelet's --system-reserved and --eviction-hard)
+type CapacityConfig struct {
+	SystemReservedMemoryMB      int64 `yaml:"system_reserved_memory_mb"`
+	SystemReservedCPUMillicores int64 `yaml:"system_reserved_cpu_millicores"`
+	EvictionThresholdMemoryMB   int64 `yaml:"eviction_threshold_memory_mb"`
+}
+
+// PressureConfig sets the avg10 stall percentages above which dispatch is
+// held, and how often they're read. A zero threshold disables that check.
+type PressureConfig struct {
+	Interval   time.Duration `yaml:"interval"`
+	MemorySome float64       `yaml:"memory_some"`
+	MemoryFull float64       `yaml:"memory_full"`
+	CPUSome    float64       `yaml:"cpu_some"`
+	IOSome     float64       `yaml:"io_some"`
+	IOFull     float64       `yaml:"io_full"`
+}
+
+// ExecUser is a client allowed to open exec and attach sessions
+type ExecUser struct {
+	Name       string   `yaml:"name"`       // Recorded in the audit log
+	Token      string   `yaml:"token"`      // Sent as Authorization: Bearer <token>
+	Namespaces []string `yaml:"namespaces"` // Namespaces whose workloads it may enter; empty = all
+}
+
+// ExecConfig controls interactive exec and attach sessions in workload containers
+type ExecConfig struct {
+	Enabled bool       `yaml:"enabled"`
+	Users   []ExecUser `yaml:"users"`
+}
+
+// KernelConfig holds the settings that can be changed at runtime with SIGHUP
+type KernelConfig struct {
+	Scheduler      string               `yaml:"scheduler"`
+	Quantum        time.Duration        `yaml:"quantum"` // Time slice for round_robin and fair
+	RateLimit      RateLimitConfig      `yaml:"rate_limit"`
+	WorkerPoolSize int                  `yaml:"worker_pool_size"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	LogLevel       string               `yaml:"log_level"`
+	DrainTimeout   time.Duration        `yaml:"drain_timeout"` // How long shutdown waits for running workloads
+	OrphanPolicy   string               `yaml:"orphan_policy"` // Unknown containers found at startup
+	PIDMax         int                  `yaml:"pid_max"`       // PIDs wrap around here; applies on restart
+	GC             GCConfig             `yaml:"gc"`
+	ContainerLogs  ContainerLogsConfig  `yaml:"container_logs"`
+	Capacity       CapacityConfig       `yaml:"capacity"`
+	Pressure       PressureConfig       `yaml:"pressure"`
+	Exec           ExecConfig           `yaml:"exec"`
+}
+
+// DefaultKernelConfig returns the settings used when no config file exists
+func DefaultKernelConfig() KernelConfig {
+	return KernelConfig{
+		Scheduler:      "round_robin",
+		Quantum:        time.Second,
+		RateLimit:      RateLimitConfig{Rate: 100, Burst: 50},
+		WorkerPoolSize: 10,
+		CircuitBreaker: CircuitBreakerConfig{MaxFailures: 5, Timeout: 30 * time.Second},
+		LogLevel:       "info",
+		DrainTimeout:   30 * time.Second,
+		OrphanPolicy:   "remove",
+		PIDMax:         32768,
+		GC:             GCConfig{TTLAfterFinished: time.Hour, MaxFinishedPerType: 100, Interval: time.Minute},
+		ContainerLogs:  ContainerLogsConfig{MaxSizeMB: 10, MaxFiles: 3, Retention: 7 * 24 * time.Hour},
+		Capacity:       CapacityConfig{SystemReservedMemoryMB: 512, SystemReservedCPUMillicores: 500, EvictionThresholdMemoryMB: 100},
+		Pressure:       PressureConfig{Interval: 5 * time.Second, MemorySome: 40, MemoryFull: 10, CPUSome: 80, IOFull: 20},
+	}
+}
+
+// LoadKernelConfig reads a config file over the defaults and validates it
+func LoadKernelConfig(path string) (KernelConfig, error) {
+	cfg := DefaultKernelConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that every setting is usable
+func (c KernelConfig) Validate() error {
+	if !slices.Contains(SchedulerNames, c.Scheduler) {
+		return fmt.Errorf("scheduler %q must be one of %s", c.Scheduler, strings.Join(SchedulerNames, ", "))
+	}
+	if c.Quantum <= 0 {
+		return fmt.Errorf("quantum must be positive, got %s", c.Quantum)
+	}
+	if c.RateLimit.Rate <= 0 || c.RateLimit.Burst < 1 {
+		return fmt.Errorf("rate_limit needs rate > 0 and burst >= 1, got %v/%v", c.RateLimit.Rate, c.RateLimit.Burst)
+	}
+	if c.WorkerPoolSize < 1 {
+		return fmt.Errorf("worker_pool_size must be at least 1, got %d", c.WorkerPoolSize)
+	}
+	if c.CircuitBreaker.MaxFailures < 1 || c.CircuitBreaker.Timeout <= 0 {
+		return fmt.Errorf("circuit_breaker needs max_failures >= 1 and a positive timeout")
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	if c.DrainTimeout <= 0 {
+		return fmt.Errorf("drain_timeout must be positive, got %s", c.DrainTimeout)
+	}
+	if !slices.Contains(OrphanPolicies, c.OrphanPolicy) {
+		return fmt.Errorf("orphan_policy %q must be one of %s", c.OrphanPolicy, strings.Join(OrphanPolicies, ", "))
+	}
+	if c.PIDMax <= FirstPID || c.PIDMax > MaxPIDMax {
+		return fmt.Errorf("pid_max must be above the reserved PIDs (%d) and at most %d, got %d", FirstPID, MaxPIDMax, c.PIDMax)
+	}
+	if c.GC.TTLAfterFinished < 0 || c.GC.MaxFinishedPerType < 0 || c.GC.Interval <= 0 {
+		return fmt.Errorf("gc needs ttl_after_finished >= 0, max_finished_per_type >= 0 and a positive interval")
+	}
+	if c.ContainerLogs.MaxSizeMB < 1 || c.ContainerLogs.MaxFiles < 1 || c.ContainerLogs.Retention < 0 {
+		return fmt.Errorf("container_logs needs max_size_mb >= 1, max_files >= 1 and retention >= 0")
+	}
+	if c.Capacity.SystemReservedMemoryMB < 0 || c.Capacity.SystemReservedCPUMillicores < 0 || c.Capacity.EvictionThresholdMemoryMB < 0 {
+		return fmt.Errorf("capacity reservations and eviction threshold must be >= 0")
+	}
+	if err := c.Pressure.validate(); err != nil {
+		return err
+	}
+	return c.Exec.validate()
+}
+
+func (c PressureConfig) validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("pressure.interval must be positive, got %s", c.Interval)
+	}
+	for _, t := range []float64{c.MemorySome, c.MemoryFull, c.CPUSome, c.IOSome, c.IOFull} {
+		if t < 0 || t > 100 {
+			return fmt.Errorf("pressure thresholds must be between 0 and 100, got %v", t)
+		}
+	}
+	return nil
+}
+
+func (c ExecConfig) validate() error {
+	if c.Enabled && len(c.Users) == 0 {
+		return fmt.Errorf("exec is enabled but has no users")
+	}
+	names := make(map[string]bool, len(c.Users))
+	tokens := make(map[string]bool, len(c.Users))
+	for i, u := range c.Users {
+		if u.Name == "" || u.Token == "" {
+			return fmt.Errorf("exec.users[%d] needs a name and a token", i)
+		}
+		if names[u.Name] || tokens[u.Token] {
+			return fmt.Errorf("exec.users[%d] repeats a name or token", i)
+		}
+		names[u.Name], tokens[u.Token] = true, true
+	}
+	return nil
+}
+
+func (u ExecUser) equal(o ExecUser) bool {
+	return u.Name == o.Name && u.Token == o.Token && slices.Equal(u.Namespaces, o.Namespaces)
+}
+
+func execUserNames(users []ExecUser) string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// DiffKernelConfig lists the settings that differ, as "field: old -> new"
+func DiffKernelConfig(old, new KernelConfig) []string {
+	var diff []string
+	add := func(field string, a, b any) {
+		if a != b {
+			diff = append(diff, fmt.Sprintf("%s: %v -> %v", field, a, b))
+		}
+	}
+	add("scheduler", old.Scheduler, new.Scheduler)
+	add("quantum", old.Quantum, new.Quantum)
+	add("rate_limit.rate", old.RateLimit.Rate, new.RateLimit.Rate)
+	add("rate_limit.burst", old.RateLimit.Burst, new.RateLimit.Burst)
+	add("worker_pool_size", old.WorkerPoolSize, new.WorkerPoolSize)
+	add("circuit_breaker.max_failures", old.CircuitBreaker.MaxFailures, new.CircuitBreaker.MaxFailures)
+	add("circuit_breaker.timeout", old.CircuitBreaker.Timeout, new.CircuitBreaker.Timeout)
+	add("log_level", old.LogLevel, new.LogLevel)
+	add("drain_timeout", old.DrainTimeout, new.DrainTimeout)
+	add("orphan_policy", old.OrphanPolicy, new.OrphanPolicy)
+	add("pid_max", old.PIDMax, new.PIDMax)
+	add("gc.ttl_after_finished", old.GC.TTLAfterFinished, new.GC.TTLAfterFinished)
+	add("gc.max_finished_per_type", old.GC.MaxFinishedPerType, new.GC.MaxFinishedPerType)
+	add("gc.interval", old.GC.Interval, new.GC.Interval)
+	add("container_logs.max_size_mb", old.ContainerLogs.MaxSizeMB, new.ContainerLogs.MaxSizeMB)
+	add("container_logs.max_files", old.ContainerLogs.MaxFiles, new.ContainerLogs.MaxFiles)
+	add("container_logs.retention", old.ContainerLogs.Retention, new.ContainerLogs.Retention)
+	add("capacity.system_reserved_memory_mb", old.Capacity.SystemReservedMemoryMB, new.Capacity.SystemReservedMemoryMB)
+	add("capacity.system_reserved_cpu_millicores", old.Capacity.SystemReservedCPUMillicores, new.Capacity.SystemReservedCPUMillicores)
+	add("capacity.eviction_threshold_memory_mb", old.Capacity.EvictionThresholdMemoryMB, new.Capacity.EvictionThresholdMemoryMB)
+	add("pressure.interval", old.Pressure.Interval, new.Pressure.Interval)
+	add("pressure.memory_some", old.Pressure.MemorySome, new.Pressure.MemorySome)
+	add("pressure.memory_full", old.Pressure.MemoryFull, new.Pressure.MemoryFull)
+	add("pressure.cpu_some", old.Pressure.CPUSome, new.Pressure.CPUSome)
+	add("pressure.io_some", old.Pressure.IOSome, new.Pressure.IOSome)
+	add("pressure.io_full", old.Pressure.IOFull, new.Pressure.IOFull)
+	add("exec.enabled", old.Exec.Enabled, new.Exec.Enabled)
+	// Tokens stay out of the log; only the user list is reported
+	if !slices.EqualFunc(old.Exec.Users, new.Exec.Users, ExecUser.equal) {
+		diff = append(diff, fmt.Sprintf("exec.users: %s -> %s", execUserNames(old.Exec.Users), execUserNames(new.Exec.Users)))
+	}
+	return diff
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadKernelConfigShipped tests that the bundled config file is valid
+func TestLoadKernelConfigShipped(t *testing.T) {
+	cfg, err := LoadKernelConfig("../../configs/ckm.yaml")
+	if err != nil {
+		t.Fatalf("Expected bundled config to load, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg, DefaultKernelConfig()) {
+		t.Errorf("Expected bundled config to match defaults, got %+v", cfg)
+	}
+}
+
+// TestLoadKernelConfigOverrides tests that unset fields keep their defaults
+func TestLoadKernelConfigOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ckm.yaml")
+	os.WriteFile(path, []byte("scheduler: fair\ncircuit_breaker:\n  timeout: 1m\n"), 0644)
+
+	cfg, err := LoadKernelConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Scheduler != "fair" || cfg.CircuitBreaker.Timeout != time.Minute {
+		t.Errorf("Expected overrides to apply, got %+v", cfg)
+	}
+	if cfg.CircuitBreaker.MaxFailures != 5 || cfg.WorkerPoolSize != 10 {
+		t.Errorf("Expected defaults for unset fields, got %+v", cfg)
+	}
+}
+
+// TestKernelConfigValidate tests rejection of unusable settings
+func TestKernelConfigValidate(t *testing.T) {
+	tests := map[string]func(*KernelConfig){
+		"scheduler":   func(c *KernelConfig) { c.Scheduler = "lottery" },
+		"worker pool": func(c *KernelConfig) { c.WorkerPoolSize = 0 },
+		"rate limit":  func(c *KernelConfig) { c.RateLimit.Rate = -1 },
+		"breaker":     func(c *KernelConfig) { c.CircuitBreaker.MaxFailures = 0 },
+		"log level":   func(c *KernelConfig) { c.LogLevel = "loud" },
+		"capacity":    func(c *KernelConfig) { c.Capacity.SystemReservedMemoryMB = -1 },
+		"pid_max":     func(c *KernelConfig) { c.PIDMax = FirstPID },
+		"pressure":    func(c *KernelConfig) { c.Pressure.CPUSome = 120 },
+		"psi period":  func(c *KernelConfig) { c.Pressure.Interval = 0 },
+		"exec users":  func(c *KernelConfig) { c.Exec.Enabled = true },
+		"exec token":  func(c *KernelConfig) { c.Exec.Users = []ExecUser{{Name: "alice"}} },
+		"exec dup": func(c *KernelConfig) {
+			c.Exec.Users = []ExecUser{{Name: "alice", Token: "a"}, {Name: "bob", Token: "a"}}
+		},
+	}
+	for name, mutate := range tests {
+		cfg := DefaultKernelConfig()
+		mutate(&cfg)
+		if cfg.Validate() == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+// TestDiffKernelConfig tests the reload diff format
+func TestDiffKernelConfig(t *testing.T) {
+	old := DefaultKernelConfig()
+	next := old
+	next.WorkerPoolSize = 20
+	next.LogLevel = "debug"
+
+	diff := DiffKernelConfig(old, next)
+	want := "worker_pool_size: 10 -> 20,log_level: info -> debug"
+	if got := strings.Join(diff, ","); got != want {
+		t.Errorf("Expected diff %q, got %q", want, got)
+	}
+}
+
+// TestDiffKernelConfigHidesTokens tests that exec tokens never reach the reload log
+func TestDiffKernelConfigHidesTokens(t *testing.T) {
+	old := DefaultKernelConfig()
+	next := old
+	next.Exec = ExecConfig{Enabled: true, Users: []ExecUser{{Name: "alice", Token: "s3cret"}}}
+
+	diff := strings.Join(DiffKernelConfig(old, next), ",")
+	want := "exec.enabled: false -> true,exec.users: [] -> [alice]"
+	if diff != want {
+		t.Errorf("Expected diff %q, got %q", want, diff)
+	}
+}
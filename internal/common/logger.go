@@ -9,6 +9,9 @@ import (
 
 var Logger *zap.Logger
 
+// LogLevel is the logger's level; it can be changed at runtime
+var LogLevel = zap.NewAtomicLevel()
+
 // InitLogger initializes structured logging (JSON for production, colored for dev)
 func InitLogger() {
 	config := zap.NewProductionConfig()
@@ -22,9 +25,21 @@ func InitLogger() {
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
+	LogLevel = config.Level
+
 	var err error
 	Logger, err = config.Build()
 	if err != nil {
 		panic("Failed to initialize logger: " + err.Error())
 	}
 }
+
+// SetLogLevel changes the log level without rebuilding the logger
+func SetLogLevel(level string) error {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	LogLevel.SetLevel(l)
+	return nil
+}
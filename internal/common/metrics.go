@@ -40,6 +40,60 @@ var (
 		[]string{"type", "reason"},
 	)
 
+	WorkloadOOMRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_workload_oom_retries_total",
+			Help: "Total OOM-killed workloads resubmitted with more memory",
+		},
+		[]string{"type"},
+	)
+
+	SignalsDeliveredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_signals_delivered_total",
+			Help: "Total signals delivered to workloads by signal",
+		},
+		[]string{"signal"},
+	)
+
+	ExecSessionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_exec_sessions_total",
+			Help: "Total exec and attach sessions by kind and result",
+		},
+		[]string{"kind", "result"},
+	)
+
+	// Garbage collection metrics
+	GCWorkloadsCollectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_gc_workloads_collected_total",
+			Help: "Finished workloads removed by the garbage collector, by reason (ttl, cap)",
+		},
+		[]string{"reason"},
+	)
+
+	GCResourcesReleasedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_gc_resources_released_total",
+			Help: "Leftover resources freed by the garbage collector, by kind (reservation, container)",
+		},
+		[]string{"kind"},
+	)
+
+	GCDurationSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "ckm_gc_duration_seconds",
+			Help:    "Time taken by each garbage collection pass",
+			Buckets: prometheus.DefBuckets,
+		})
+
+	FinishedWorkloads = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ckm_finished_workloads",
+			Help: "Finished workloads kept in the store after the last garbage collection",
+		})
+
 	// Memory metrics
 	MemoryUsed = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -47,7 +101,96 @@ var (
 			Help: "Memory usage in MB",
 		})
 
+	MemoryPressureUsedMB = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ckm_memory_pressure_used_megabytes",
+			Help: "Real memory used by all containers, as seen by the evictor",
+		})
+
+	WorkloadEvictionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_workload_evictions_total",
+			Help: "Total workloads evicted by QoS class and reason",
+		},
+		[]string{"qos_class", "reason"},
+	)
+
+	// Reserved resources vs capacity, by resource (memory_mb, cpu_millicores, pids, disk_mb)
+	ResourceUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ckm_resource_used",
+			Help: "Resources reserved by workloads",
+		},
+		[]string{"resource"},
+	)
+
+	ResourceCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ckm_resource_capacity",
+			Help: "Total resource capacity available to workloads",
+		},
+		[]string{"resource"},
+	)
+
+	NodeCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ckm_node_capacity",
+			Help: "Detected host capacity before system reservations",
+		},
+		[]string{"resource"},
+	)
+
+	// Pressure-stall information from /proc/pressure
+	PressureStallPercent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ckm_pressure_stall_percent",
+			Help: "Share of time tasks stalled on a resource (Linux PSI)",
+		},
+		[]string{"resource", "kind", "window"},
+	)
+
+	AdmissionHeld = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ckm_admission_held",
+			Help: "1 while new dispatches are held because of resource pressure",
+		})
+
+	ConfigReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ckm_config_reloads_total",
+			Help: "Total SIGHUP config reloads by result",
+		},
+		[]string{"result"},
+	)
+
+	// Store metrics
+	StoreWALRecords = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ckm_store_wal_records",
+			Help: "Records in the workload WAL since the last snapshot",
+		})
+
+	StoreWriteErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ckm_store_write_errors_total",
+			Help: "Total failed writes to the workload WAL",
+		})
+
+	StoreWatchers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ckm_store_watchers",
+			Help: "Open watches on workload changes",
+		})
+
 	// Scheduler metrics
+	LoadAverage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ckm_load_average",
+			Help: "Exponentially damped average of runnable plus running workloads",
+		},
+		[]string{"window"},
+	)
+
 	SchedulerQueueLength = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "ckm_scheduler_queue_length",
@@ -138,13 +281,40 @@ var (
 	)
 )
 
+// RecordResources sets a per-resource gauge for each tracked resource
+func RecordResources(g *prometheus.GaugeVec, memoryMB, cpuMillicores, pids, diskMB int64) {
+	g.WithLabelValues("memory_mb").Set(float64(memoryMB))
+	g.WithLabelValues("cpu_millicores").Set(float64(cpuMillicores))
+	g.WithLabelValues("pids").Set(float64(pids))
+	g.WithLabelValues("disk_mb").Set(float64(diskMB))
+}
+
 // InitMetrics registers all Prometheus metrics and starts metrics server
 func InitMetrics() {
 	prometheus.MustRegister(WorkloadsRunning)
 	prometheus.MustRegister(WorkloadCompleted)
 	prometheus.MustRegister(WorkloadDurationSeconds)
 	prometheus.MustRegister(WorkloadFailuresTotal)
+	prometheus.MustRegister(WorkloadOOMRetriesTotal)
+	prometheus.MustRegister(SignalsDeliveredTotal)
+	prometheus.MustRegister(ExecSessionsTotal)
+	prometheus.MustRegister(GCWorkloadsCollectedTotal)
+	prometheus.MustRegister(GCResourcesReleasedTotal)
+	prometheus.MustRegister(GCDurationSeconds)
+	prometheus.MustRegister(FinishedWorkloads)
 	prometheus.MustRegister(MemoryUsed)
+	prometheus.MustRegister(MemoryPressureUsedMB)
+	prometheus.MustRegister(WorkloadEvictionsTotal)
+	prometheus.MustRegister(ResourceUsed)
+	prometheus.MustRegister(ResourceCapacity)
+	prometheus.MustRegister(NodeCapacity)
+	prometheus.MustRegister(PressureStallPercent)
+	prometheus.MustRegister(AdmissionHeld)
+	prometheus.MustRegister(LoadAverage)
+	prometheus.MustRegister(StoreWALRecords)
+	prometheus.MustRegister(StoreWriteErrorsTotal)
+	prometheus.MustRegister(StoreWatchers)
+	prometheus.MustRegister(ConfigReloadsTotal)
 	prometheus.MustRegister(SchedulerQueueLength)
 	prometheus.MustRegister(ContainerStartupTimeSeconds)
 
@@ -44,6 +44,15 @@ func (rl *RateLimiter) Allow() bool {
 	return false
 }
 
+// SetLimits changes the refill rate and burst capacity, keeping current tokens
+func (rl *RateLimiter) SetLimits(rate float64, capacity float64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rate = rate
+	rl.capacity = capacity
+	rl.tokens = min(rl.tokens, capacity)
+}
+
 // Wait blocks until a token is available
 func (rl *RateLimiter) Wait() {
 	for !rl.Allow() {
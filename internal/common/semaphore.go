@@ -0,0 +1,53 @@
+package common
+
+import "sync"
+
+// Semaphore limits concurrency like a buffered channel, but its limit can be
+// changed while slots are held. Shrinking never interrupts holders; new
+// acquirers wait until usage drops below the new limit.
+type Semaphore struct {
+	limit int
+	inUse int
+	mu    sync.Mutex
+	cond  *sync.Cond
+}
+
+// NewSemaphore creates a semaphore with the given number of slots
+func NewSemaphore(limit int) *Semaphore {
+	s := &Semaphore{limit: limit}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire blocks until a slot is free and takes it
+func (s *Semaphore) Acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.inUse >= s.limit {
+		s.cond.Wait()
+	}
+	s.inUse++
+}
+
+// Release returns a slot
+func (s *Semaphore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inUse--
+	s.cond.Broadcast()
+}
+
+// SetLimit changes the number of slots
+func (s *Semaphore) SetLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.cond.Broadcast()
+}
+
+// Usage returns the slots in use and the current limit
+func (s *Semaphore) Usage() (inUse, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inUse, s.limit
+}
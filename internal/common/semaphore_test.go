@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSemaphoreResize tests that growing the limit wakes a blocked acquirer
+func TestSemaphoreResize(t *testing.T) {
+	s := NewSemaphore(1)
+	s.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		s.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected second acquire to block at limit 1")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.SetLimit(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected second acquire to succeed after raising the limit")
+	}
+
+	// Shrinking below usage doesn't evict holders
+	s.SetLimit(1)
+	if inUse, limit := s.Usage(); inUse != 2 || limit != 1 {
+		t.Errorf("Expected 2 in use with limit 1, got %d/%d", inUse, limit)
+	}
+}
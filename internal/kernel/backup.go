@@ -0,0 +1,311 @@
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BackupVersion is bumped when the backup archive format changes
+const BackupVersion = 1
+
+var (
+	// ErrBackupVersion is returned for an archive written in another format version
+	ErrBackupVersion = errors.New("unsupported backup version")
+	// ErrInvalidBackup is returned for an archive that couldn't have been exported
+	ErrInvalidBackup = errors.New("invalid backup")
+)
+
+// RestoreMode decides what happens to workloads that were in flight when a backup was taken
+type RestoreMode string
+
+const (
+	RestoreRequeue   RestoreMode = "requeue"   // Queue them again; they start new containers
+	RestoreInterrupt RestoreMode = "interrupt" // Mark them interrupted; they resume on the next restart
+)
+
+// Backup is a point-in-time archive of the kernel state
+type Backup struct {
+	Version         int                  `json:"version"`
+	CreatedAt       time.Time            `json:"created_at"`
+	ResourceVersion uint64               `json:"resource_version"` // Store version the workloads were read at
+	Workloads       []*Workload          `json:"workloads"`        // Including their status history
+	Reservations    map[string]Resources `json:"reservations"`
+	SchedulerQueue  []string             `json:"scheduler_queue"` // Queued workload IDs in scheduler order
+	Processes       ProcessTable         `json:"processes"`
+}
+
+// validate rejects archives with missing or repeated entries before anything is restored
+func (b *Backup) validate() error {
+	ids := make(map[string]bool, len(b.Workloads))
+	for i, w := range b.Workloads {
+		switch {
+		case w == nil:
+			return fmt.Errorf("%w: workloads[%d] is null", ErrInvalidBackup, i)
+		case w.ID == "":
+			return fmt.Errorf("%w: workloads[%d] has no ID", ErrInvalidBackup, i)
+		case ids[w.ID]:
+			return fmt.Errorf("%w: workload %q appears twice", ErrInvalidBackup, w.ID)
+		}
+		ids[w.ID] = true
+	}
+	for i, pg := range b.Processes.Groups {
+		if pg == nil {
+			return fmt.Errorf("%w: processes.groups[%d] is null", ErrInvalidBackup, i)
+		}
+	}
+	return nil
+}
+
+// ProcessTable is a copy of the process table: processes, groups and sessions
+type ProcessTable struct {
+	Processes []ProcessInfo   `json:"processes"`
+	Groups    []*ProcessGroup `json:"groups"`
+	Sessions  []Session       `json:"sessions"`
+}
+
+// RestoreReport summarizes a restore
+type RestoreReport struct {
+	Workloads    int         `json:"workloads"`
+	Requeued     []*Workload `json:"-"` // In scheduler order; the caller dispatches them
+	Interrupted  int         `json:"interrupted"`
+	Reservations int         `json:"reservations"`
+	Processes    int         `json:"processes"`
+}
+
+// BackupManager exports the kernel state to a Backup and restores it into a fresh kernel
+type BackupManager struct {
+	store     Store
+	cgroups   *CGroupManager
+	processes *ProcessManager  // Optional
+	scheduler func() Scheduler // The scheduler can be swapped on reload
+	logger    *zap.Logger
+}
+
+// NewBackupManager creates a backup manager over the kernel's state
+func NewBackupManager(store Store, cgroups *CGroupManager, processes *ProcessManager, scheduler func() Scheduler, logger *zap.Logger) *BackupManager {
+	return &BackupManager{
+		store:     store,
+		cgroups:   cgroups,
+		processes: processes,
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// Export captures the current state. Callers that admit or delete workloads
+// should be held off meanwhile so reservations and processes match the
+// workloads; status changes after the captured resource version aren't included.
+func (b *BackupManager) Export() *Backup {
+	workloads, rv := b.store.Snapshot()
+	slices.SortFunc(workloads, func(x, y *Workload) int { return x.CreatedAt.Compare(y.CreatedAt) })
+	backup := &Backup{
+		Version:         BackupVersion,
+		CreatedAt:       time.Now(),
+		ResourceVersion: rv,
+		Workloads:       workloads,
+		Reservations:    make(map[string]Resources),
+		SchedulerQueue:  []string{},
+	}
+
+	known := make(map[string]bool, len(workloads))
+	for _, w := range workloads {
+		known[w.ID] = true
+	}
+	// A reservation can outlive its workload for a moment while it's deleted
+	for id, r := range b.cgroups.Reservations() {
+		if known[id] {
+			backup.Reservations[id] = r
+		}
+	}
+	if b.scheduler != nil {
+		for _, w := range QueueSnapshot(b.scheduler()) {
+			if known[w.ID] {
+				backup.SchedulerQueue = append(backup.SchedulerQueue, w.ID)
+			}
+		}
+	}
+	if b.processes != nil {
+		backup.Processes = b.processes.Snapshot()
+	}
+
+	b.logger.Info("Kernel state exported",
+		zap.Uint64("resource_version", rv),
+		zap.Int("workloads", len(workloads)),
+		zap.Int("reservations", len(backup.Reservations)),
+		zap.Int("processes", len(backup.Processes.Processes)))
+	return backup
+}
+
+// Restore loads a backup into a kernel whose store is still empty. In-flight
+// workloads are queued again or marked interrupted depending on mode; the
+// queued ones are returned in scheduler order for the caller to dispatch.
+func (b *BackupManager) Restore(backup *Backup, mode RestoreMode) (RestoreReport, error) {
+	var report RestoreReport
+	if backup.Version != BackupVersion {
+		return report, fmt.Errorf("%w %d (want %d)", ErrBackupVersion, backup.Version, BackupVersion)
+	}
+	if mode != RestoreRequeue && mode != RestoreInterrupt {
+		return report, fmt.Errorf("unknown restore mode %q", mode)
+	}
+	if err := backup.validate(); err != nil {
+		return report, err
+	}
+
+	now := time.Now()
+	var inflight []*Workload
+	for _, w := range backup.Workloads {
+		migrateStatus(w)
+		if IsTerminal(w.Status) {
+			continue
+		}
+		status, reason, message := StatusQueued, "Restored", "queued again after a restore"
+		if mode == RestoreInterrupt {
+			status, reason, message = StatusInterrupted, "Restored", "interrupted by a restore; resumes on the next restart"
+		}
+		// A restore replaces the kernel, so this bypasses the lifecycle like a restart does
+		w.History = append(w.History, Transition{From: w.Status, To: status, Reason: reason, Message: message, Time: now})
+		w.Status, w.Reason, w.Message = status, reason, message
+		w.ContainerID, w.StartedAt = "", time.Time{}
+		inflight = append(inflight, w)
+	}
+	if err := b.store.Restore(backup.Workloads); err != nil {
+		return report, err
+	}
+	report.Workloads = len(backup.Workloads)
+
+	byID := make(map[string]*Workload, len(backup.Workloads))
+	byPID := make(map[int]*Workload, len(backup.Workloads))
+	for _, w := range backup.Workloads {
+		byID[w.ID] = w
+		byPID[w.PID] = w
+	}
+	if b.processes != nil {
+		// Every process holds its PID until it's reaped
+		report.Processes = b.processes.Restore(backup.Processes)
+		for _, p := range backup.Processes.Processes {
+			if p.PID == InitPID {
+				continue
+			}
+			namespace, local := "", p.PID
+			if w, ok := byPID[p.PID]; ok {
+				namespace, local = w.Namespace, w.NSPID
+			}
+			if !PIDs().Reserve(p.PID, namespace, local) {
+				b.logger.Warn("Restored PID already taken", zap.Int("pid", p.PID))
+			}
+		}
+		// Session IDs come from the same pool
+		for _, sess := range backup.Processes.Sessions {
+			if !PIDs().Reserve(sess.ID, "", sess.ID) {
+				b.logger.Warn("Restored session ID already taken", zap.Int("sid", sess.ID))
+			}
+		}
+	}
+
+	interrupted := make(map[string]bool)
+	for _, w := range inflight {
+		if w.Status == StatusInterrupted {
+			interrupted[w.ID] = true
+			report.Interrupted++
+			if b.processes != nil {
+				b.processes.TerminateProcess(w.PID)
+			}
+		}
+	}
+	for id, r := range backup.Reservations {
+		// Interrupted workloads hold nothing until they're resumed, as after a drain
+		if _, ok := byID[id]; !ok || interrupted[id] {
+			continue
+		}
+		if err := b.cgroups.AllocateResources(id, r); err != nil {
+			b.logger.Warn("Restored reservation exceeds current capacity", zap.String("workload", id), zap.Error(err))
+			continue
+		}
+		report.Reservations++
+	}
+
+	if mode == RestoreRequeue {
+		report.Requeued = requeueOrder(inflight, backup.SchedulerQueue)
+	}
+	b.logger.Info("Kernel state restored",
+		zap.String("mode", string(mode)),
+		zap.Int("workloads", report.Workloads),
+		zap.Int("requeued", len(report.Requeued)),
+		zap.Int("interrupted", report.Interrupted),
+		zap.Int("reservations", report.Reservations),
+		zap.Int("processes", report.Processes))
+	return report, nil
+}
+
+// requeueOrder puts workloads that were in the scheduler queue first, in
+// queue order, followed by the rest oldest first
+func requeueOrder(workloads []*Workload, queue []string) []*Workload {
+	position := make(map[string]int, len(queue))
+	for i, id := range queue {
+		position[id] = i
+	}
+	ordered := slices.Clone(workloads)
+	slices.SortStableFunc(ordered, func(x, y *Workload) int {
+		px, inX := position[x.ID]
+		py, inY := position[y.ID]
+		switch {
+		case inX && inY:
+			return px - py
+		case inX:
+			return -1
+		case inY:
+			return 1
+		}
+		return x.CreatedAt.Compare(y.CreatedAt)
+	})
+	return ordered
+}
+
+// Snapshot copies the process table
+func (pm *ProcessManager) Snapshot() ProcessTable {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var table ProcessTable
+	for _, info := range pm.processes {
+		table.Processes = append(table.Processes, *info.copy())
+	}
+	for _, pg := range pm.processGroups {
+		pg.mu.RLock()
+		table.Groups = append(table.Groups, &ProcessGroup{ID: pg.ID, LeaderPID: pg.LeaderPID, PIDs: slices.Clone(pg.PIDs)})
+		pg.mu.RUnlock()
+	}
+	for _, sess := range pm.sessions {
+		table.Sessions = append(table.Sessions, sess.copy())
+	}
+	slices.SortFunc(table.Processes, func(a, b ProcessInfo) int { return a.PID - b.PID })
+	slices.SortFunc(table.Groups, func(a, b *ProcessGroup) int { return a.ID - b.ID })
+	slices.SortFunc(table.Sessions, func(a, b Session) int { return a.ID - b.ID })
+	return table
+}
+
+// Restore replaces the process table with a snapshot and returns the number
+// of processes restored. Init is kept if the snapshot doesn't have it.
+func (pm *ProcessManager) Restore(table ProcessTable) int {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.processes = map[int]*ProcessInfo{InitPID: {PID: InitPID, State: "running"}}
+	for _, info := range table.Processes {
+		pm.processes[info.PID] = info.copy()
+	}
+	pm.processGroups = make(map[int]*ProcessGroup, len(table.Groups))
+	for _, pg := range table.Groups {
+		pm.processGroups[pg.ID] = &ProcessGroup{ID: pg.ID, LeaderPID: pg.LeaderPID, PIDs: slices.Clone(pg.PIDs)}
+	}
+	pm.sessions = make(map[int]*Session, len(table.Sessions))
+	for _, sess := range table.Sessions {
+		c := sess.copy()
+		pm.sessions[sess.ID] = &c
+	}
+	return len(pm.processes) - 1
+}
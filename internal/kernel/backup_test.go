@@ -0,0 +1,167 @@
+package kernel
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// backupKernel builds a kernel with a finished workload, two queued ones in
+// scheduler order and a running one, all in the process table
+func backupKernel(t *testing.T) (*BackupManager, Store) {
+	t.Helper()
+	store := NewWorkloadStore()
+	cgroups := NewCGroupManager(1024)
+	processes := NewProcessManager()
+	scheduler := NewFIFOScheduler()
+
+	add := func(id string, pid int, status string) {
+		w := &Workload{ID: id, PID: pid, MemoryMB: 64, Status: StatusPending, Labels: map[string]string{"app": id}}
+		store.Add(w)
+		store.SetStatus(id, StatusQueued, "Admitted", "")
+		for _, next := range []string{StatusScheduled, StatusCreating, StatusRunning, StatusSucceeded} {
+			if w, _ := store.Get(id); w.Status == status {
+				break
+			}
+			store.SetStatus(id, next, "", "")
+		}
+		cgroups.AllocateResources(id, w.Resources())
+		processes.CreateProcess(pid, 0)
+		processes.CreateProcessGroup(pid, pid)
+	}
+	add("done", 7001, StatusSucceeded)
+	add("second", 7002, StatusQueued)
+	add("first", 7003, StatusQueued)
+	add("busy", 7004, StatusRunning)
+	processes.CreateSession(7000, "alice")
+	processes.JoinSession(7000, 7004)
+	for _, id := range []string{"first", "second"} {
+		w, _ := store.Get(id)
+		scheduler.Add(*w)
+	}
+	return NewBackupManager(store, cgroups, processes, func() Scheduler { return scheduler }, zap.NewNop()), store
+}
+
+// roundTrip encodes and decodes a backup like the archive file does
+func roundTrip(t *testing.T, b *Backup) *Backup {
+	t.Helper()
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Failed to encode backup: %v", err)
+	}
+	var decoded Backup
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode backup: %v", err)
+	}
+	return &decoded
+}
+
+// TestBackupRestoreRequeue tests restoring in-flight workloads to the queue
+func TestBackupRestoreRequeue(t *testing.T) {
+	source, sourceStore := backupKernel(t)
+	backup := roundTrip(t, source.Export())
+	if backup.Version != BackupVersion || len(backup.Workloads) != 4 || len(backup.Reservations) != 4 {
+		t.Fatalf("Unexpected backup: version %d, %d workloads, %d reservations", backup.Version, len(backup.Workloads), len(backup.Reservations))
+	}
+	if backup.ResourceVersion != sourceStore.ResourceVersion() {
+		t.Errorf("Expected resource version %d, got %d", sourceStore.ResourceVersion(), backup.ResourceVersion)
+	}
+
+	store, cgroups, processes := NewWorkloadStore(), NewCGroupManager(1024), NewProcessManager()
+	target := NewBackupManager(store, cgroups, processes, nil, zap.NewNop())
+	report, err := target.Restore(backup, RestoreRequeue)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	var order []string
+	for _, w := range report.Requeued {
+		order = append(order, w.ID)
+	}
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "busy" {
+		t.Errorf("Expected scheduler order first, second, then busy, got %v", order)
+	}
+	busy, _ := store.Get("busy")
+	if busy.Status != StatusQueued || busy.Reason != "Restored" || busy.Labels["app"] != "busy" {
+		t.Errorf("Expected busy queued with reason Restored, got %s %s", busy.Status, busy.Reason)
+	}
+	if last := busy.History[len(busy.History)-1]; last.From != StatusRunning || last.To != StatusQueued {
+		t.Errorf("Expected running -> queued in history, got %+v", last)
+	}
+	if done, _ := store.Get("done"); done.Status != StatusSucceeded || len(done.History) != 6 {
+		t.Errorf("Expected done restored with its history, got %s and %d transitions", done.Status, len(done.History))
+	}
+	if _, ok := cgroups.GetReservation("busy"); !ok || report.Reservations != 4 {
+		t.Errorf("Expected all 4 reservations restored, got %d", report.Reservations)
+	}
+	if group, ok := processes.GetProcessGroup(7004); !ok || len(group) != 1 || report.Processes != 4 {
+		t.Errorf("Expected the process table restored, got group %v and %d processes", group, report.Processes)
+	}
+	if !PIDs().global.InUse(7004) || !PIDs().global.InUse(7000) {
+		t.Error("Expected restored PIDs and session IDs to be reserved")
+	}
+
+	if _, err := target.Restore(roundTrip(t, source.Export()), RestoreRequeue); !errors.Is(err, ErrStoreNotEmpty) {
+		t.Errorf("Expected ErrStoreNotEmpty restoring twice, got %v", err)
+	}
+}
+
+// TestBackupRestoreInterrupt tests marking in-flight workloads interrupted
+func TestBackupRestoreInterrupt(t *testing.T) {
+	source, _ := backupKernel(t)
+	store, cgroups, processes := NewWorkloadStore(), NewCGroupManager(1024), NewProcessManager()
+	target := NewBackupManager(store, cgroups, processes, nil, zap.NewNop())
+	report, err := target.Restore(roundTrip(t, source.Export()), RestoreInterrupt)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if report.Interrupted != 3 || len(report.Requeued) != 0 {
+		t.Errorf("Expected 3 interrupted and none requeued, got %+v", report)
+	}
+	if w, _ := store.Get("first"); w.Status != StatusInterrupted {
+		t.Errorf("Expected first interrupted, got %s", w.Status)
+	}
+	if _, ok := cgroups.GetReservation("busy"); ok {
+		t.Error("Expected interrupted workloads to hold no reservation")
+	}
+	if _, ok := cgroups.GetReservation("done"); !ok {
+		t.Error("Expected the finished workload's reservation restored")
+	}
+	if p, _ := processes.GetProcess(7004); p.State != "terminated" {
+		t.Errorf("Expected the interrupted workload's process terminated, got %s", p.State)
+	}
+	// Interrupted workloads are resumed after the next restart
+	if pending := PendingAfterRestart(store, nil); len(pending) != 3 {
+		t.Errorf("Expected 3 workloads pending after restart, got %d", len(pending))
+	}
+}
+
+// TestRestoreRejectsOtherVersion tests the archive version check
+func TestRestoreRejectsOtherVersion(t *testing.T) {
+	target := NewBackupManager(NewWorkloadStore(), NewCGroupManager(1024), nil, nil, zap.NewNop())
+	if _, err := target.Restore(&Backup{Version: BackupVersion + 1}, RestoreRequeue); !errors.Is(err, ErrBackupVersion) {
+		t.Errorf("Expected ErrBackupVersion, got %v", err)
+	}
+}
+
+// TestRestoreRejectsInvalidArchive tests that malformed archives leave the store untouched
+func TestRestoreRejectsInvalidArchive(t *testing.T) {
+	tests := map[string][]*Workload{
+		"null":      {nil},
+		"no ID":     {{Status: StatusQueued}},
+		"duplicate": {{ID: "a", Status: StatusQueued}, {ID: "a", Status: StatusSucceeded}},
+	}
+	for name, workloads := range tests {
+		store := NewWorkloadStore()
+		target := NewBackupManager(store, NewCGroupManager(1024), nil, nil, zap.NewNop())
+		_, err := target.Restore(&Backup{Version: BackupVersion, Workloads: workloads}, RestoreRequeue)
+		if !errors.Is(err, ErrInvalidBackup) {
+			t.Errorf("%s: expected ErrInvalidBackup, got %v", name, err)
+		}
+		if n := len(store.GetAll()); n != 0 {
+			t.Errorf("%s: expected an empty store, got %d workloads", name, n)
+		}
+	}
+}
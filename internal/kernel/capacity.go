@@ -0,0 +1,276 @@
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// numCPU is swapped out in tests
+var numCPU = goruntime.NumCPU
+
+// HostInfoSource reports what the container runtime sees (implemented by DockerRuntime)
+type HostInfoSource interface {
+	HostInfo(ctx context.Context) (memoryBytes int64, cpus int, err error)
+}
+
+// CapacityReport describes detected capacity and what is left for workloads
+type CapacityReport struct {
+	Capacity    Resources             `json:"capacity"`    // Smallest limit seen across sources
+	Allocatable Resources             `json:"allocatable"` // Capacity minus reservations
+	Reserved    common.CapacityConfig `json:"reserved"`
+	Sources     map[string]string     `json:"sources"` // Resource -> source that set the limit
+	DetectedAt  time.Time             `json:"detected_at"`
+}
+
+// CapacityDetector works out allocatable memory and CPU from /proc/meminfo,
+// the cgroup CKM runs in and the container runtime
+type CapacityDetector struct {
+	procRoot   string
+	cgroupRoot string
+	host       HostInfoSource
+	cgroups    *CGroupManager
+	logger     *zap.Logger
+	config     common.CapacityConfig // Guarded by mu
+	last       *CapacityReport
+	mu         sync.RWMutex
+}
+
+// NewCapacityDetector creates a capacity detector (procRoot "/proc", cgroupRoot "/sys/fs/cgroup")
+func NewCapacityDetector(procRoot, cgroupRoot string, host HostInfoSource, config common.CapacityConfig, cgroups *CGroupManager, logger *zap.Logger) *CapacityDetector {
+	return &CapacityDetector{
+		procRoot:   procRoot,
+		cgroupRoot: cgroupRoot,
+		host:       host,
+		config:     config,
+		cgroups:    cgroups,
+		logger:     logger,
+	}
+}
+
+// SetConfig changes the system reservation and eviction threshold, starting with the next Refresh
+func (d *CapacityDetector) SetConfig(config common.CapacityConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+}
+
+// Start re-detects capacity periodically until the context is cancelled
+func (d *CapacityDetector) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh detects capacity and applies allocatable memory and CPU to the CGroupManager
+func (d *CapacityDetector) Refresh(ctx context.Context) (*CapacityReport, bool) {
+	report, ok := d.Detect(ctx)
+	if !ok {
+		d.logger.Warn("Could not detect host memory, keeping current capacity")
+		return nil, false
+	}
+
+	d.mu.Lock()
+	changed := d.last == nil || d.last.Allocatable != report.Allocatable
+	d.last = report
+	d.mu.Unlock()
+
+	capacity := d.cgroups.GetCapacity()
+	capacity.MemoryMB = report.Allocatable.MemoryMB
+	capacity.CPUMillicores = report.Allocatable.CPUMillicores
+	d.cgroups.SetCapacity(capacity)
+
+	common.RecordResources(common.NodeCapacity, report.Capacity.MemoryMB, report.Capacity.CPUMillicores, 0, 0)
+	common.RecordResources(common.ResourceCapacity, capacity.MemoryMB, capacity.CPUMillicores, capacity.PIDs, capacity.DiskMB)
+	if changed {
+		d.logger.Info("Host capacity detected",
+			zap.Int64("capacity_mb", report.Capacity.MemoryMB),
+			zap.Int64("allocatable_mb", report.Allocatable.MemoryMB),
+			zap.Int64("capacity_millicores", report.Capacity.CPUMillicores),
+			zap.Int64("allocatable_millicores", report.Allocatable.CPUMillicores),
+			zap.Any("sources", report.Sources))
+	}
+	return report, true
+}
+
+// Detect reads every source without applying the result. The smallest
+// limit wins for each resource; ok is false if no memory source was found.
+func (d *CapacityDetector) Detect(ctx context.Context) (*CapacityReport, bool) {
+	d.mu.RLock()
+	config := d.config
+	d.mu.RUnlock()
+	report := &CapacityReport{
+		Reserved:   config,
+		Sources:    make(map[string]string),
+		DetectedAt: time.Now(),
+	}
+	lower := func(resource string, current *int64, value int64, source string) {
+		if value > 0 && (*current == 0 || value < *current) {
+			*current = value
+			report.Sources[resource] = source
+		}
+	}
+
+	if mb, err := readMemTotalMB(d.procRoot); err == nil {
+		lower("memory", &report.Capacity.MemoryMB, mb, "meminfo")
+	}
+	lower("cpu", &report.Capacity.CPUMillicores, int64(numCPU())*1000, "host")
+
+	memMB, cpuMillis := d.readCgroupLimits()
+	lower("memory", &report.Capacity.MemoryMB, memMB, "cgroup")
+	lower("cpu", &report.Capacity.CPUMillicores, cpuMillis, "cgroup")
+
+	if d.host != nil {
+		if bytes, cpus, err := d.host.HostInfo(ctx); err == nil {
+			lower("memory", &report.Capacity.MemoryMB, bytes/(1024*1024), "docker")
+			lower("cpu", &report.Capacity.CPUMillicores, int64(cpus)*1000, "docker")
+		} else {
+			d.logger.Debug("Docker info unavailable", zap.Error(err))
+		}
+	}
+
+	if report.Capacity.MemoryMB == 0 {
+		return nil, false
+	}
+	// A capacity of 0 means unlimited to the CGroupManager, so reservations
+	// that use up the host leave a floor of 1 instead, which admits nothing
+	memoryMB := report.Capacity.MemoryMB - config.SystemReservedMemoryMB - config.EvictionThresholdMemoryMB
+	cpuMillicores := report.Capacity.CPUMillicores - config.SystemReservedCPUMillicores
+	if memoryMB < 1 || cpuMillicores < 1 {
+		d.logger.Error("System reservation leaves nothing allocatable",
+			zap.Int64("capacity_mb", report.Capacity.MemoryMB),
+			zap.Int64("capacity_millicores", report.Capacity.CPUMillicores),
+			zap.Any("reserved", config))
+	}
+	report.Allocatable = Resources{MemoryMB: max(memoryMB, 1), CPUMillicores: max(cpuMillicores, 1)}
+	return report, true
+}
+
+// Last returns the most recent capacity report, if any
+func (d *CapacityDetector) Last() (*CapacityReport, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.last, d.last != nil
+}
+
+// readMemTotalMB reads MemTotal from /proc/meminfo
+func readMemTotalMB(procRoot string) (int64, error) {
+	f, err := os.Open(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb / 1024, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, os.ErrNotExist
+}
+
+// readCgroupLimits returns the memory (MB) and CPU (millicores) limits of
+// CKM's own cgroup, 0 where unlimited. Handles cgroup v2 and v1.
+func (d *CapacityDetector) readCgroupLimits() (memoryMB, cpuMillicores int64) {
+	paths := readSelfCgroupPaths(d.procRoot)
+
+	// cgroup v2: memory.max ("max" or bytes), cpu.max ("max 100000" or "quota period")
+	if v2, ok := paths[""]; ok {
+		if s, err := readCgroupFile(d.cgroupRoot, v2, "memory.max"); err == nil && s != "max" {
+			if b, err := strconv.ParseInt(s, 10, 64); err == nil {
+				memoryMB = b / (1024 * 1024)
+			}
+		}
+		if s, err := readCgroupFile(d.cgroupRoot, v2, "cpu.max"); err == nil {
+			if fields := strings.Fields(s); len(fields) == 2 && fields[0] != "max" {
+				cpuMillicores = quotaMillicores(fields[0], fields[1])
+			}
+		}
+	}
+
+	// cgroup v1: a huge limit_in_bytes or a -1 quota means unlimited
+	if memoryMB == 0 {
+		if s, err := readCgroupFile(filepath.Join(d.cgroupRoot, "memory"), paths["memory"], "memory.limit_in_bytes"); err == nil {
+			if b, err := strconv.ParseInt(s, 10, 64); err == nil && b < 1<<62 {
+				memoryMB = b / (1024 * 1024)
+			}
+		}
+	}
+	if cpuMillicores == 0 {
+		cpuDir := filepath.Join(d.cgroupRoot, "cpu")
+		quota, qerr := readCgroupFile(cpuDir, paths["cpu"], "cpu.cfs_quota_us")
+		period, perr := readCgroupFile(cpuDir, paths["cpu"], "cpu.cfs_period_us")
+		if qerr == nil && perr == nil && quota != "-1" {
+			cpuMillicores = quotaMillicores(quota, period)
+		}
+	}
+	return memoryMB, cpuMillicores
+}
+
+// readSelfCgroupPaths maps controller -> cgroup path from /proc/self/cgroup ("" for v2)
+func readSelfCgroupPaths(procRoot string) map[string]string {
+	paths := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(procRoot, "self", "cgroup"))
+	if err != nil {
+		return paths
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// readCgroupFile reads a control file from CKM's own cgroup, falling back to the
+// hierarchy root (the usual view inside a container)
+func readCgroupFile(root, path, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, path, name))
+	if err != nil {
+		data, err = os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// quotaMillicores converts a CFS quota and period into millicores
+func quotaMillicores(quota, period string) int64 {
+	q, err1 := strconv.ParseInt(quota, 10, 64)
+	p, err2 := strconv.ParseInt(period, 10, 64)
+	if err1 != nil || err2 != nil || q <= 0 || p <= 0 {
+		return 0
+	}
+	return q * 1000 / p
+}
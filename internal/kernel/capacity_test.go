@@ -0,0 +1,126 @@
+package kernel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// fakeHostInfo reports fixed Docker host info
+type fakeHostInfo struct {
+	memoryBytes int64
+	cpus        int
+}
+
+func (f fakeHostInfo) HostInfo(ctx context.Context) (int64, int, error) {
+	return f.memoryBytes, f.cpus, nil
+}
+
+// writeFiles creates files under root from a path -> content map
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestCapacityDetectorCgroupV2 tests that the cgroup limit wins over meminfo
+func TestCapacityDetectorCgroupV2(t *testing.T) {
+	defer func(orig func() int) { numCPU = orig }(numCPU)
+	numCPU = func() int { return 8 }
+
+	proc, cg := t.TempDir(), t.TempDir()
+	writeFiles(t, proc, map[string]string{
+		"meminfo":     "MemTotal:       16384000 kB\nMemFree:         1000 kB\n",
+		"self/cgroup": "0::/ckm\n",
+	})
+	writeFiles(t, cg, map[string]string{
+		"ckm/memory.max": "4294967296\n", // 4096 MB
+		"ckm/cpu.max":    "150000 100000\n",
+	})
+
+	cgm := NewCGroupManagerWithCapacity(Resources{MemoryMB: 1024, PIDs: 4096})
+	d := NewCapacityDetector(proc, cg, nil, common.CapacityConfig{SystemReservedMemoryMB: 512, EvictionThresholdMemoryMB: 100, SystemReservedCPUMillicores: 500}, cgm, zap.NewNop())
+
+	report, ok := d.Refresh(context.Background())
+	if !ok {
+		t.Fatal("Expected capacity to be detected")
+	}
+	if report.Capacity.MemoryMB != 4096 || report.Sources["memory"] != "cgroup" {
+		t.Errorf("Expected 4096MB from cgroup, got %d from %s", report.Capacity.MemoryMB, report.Sources["memory"])
+	}
+	if report.Capacity.CPUMillicores != 1500 {
+		t.Errorf("Expected 1500 millicores, got %d", report.Capacity.CPUMillicores)
+	}
+
+	capacity := cgm.GetCapacity()
+	if capacity.MemoryMB != 4096-512-100 || capacity.CPUMillicores != 1000 {
+		t.Errorf("Expected allocatable 3484MB / 1000m, got %+v", capacity)
+	}
+	if capacity.PIDs != 4096 {
+		t.Errorf("Expected PIDs capacity to be kept, got %d", capacity.PIDs)
+	}
+}
+
+// TestCapacityDetectorCgroupV1Unlimited tests meminfo and Docker when cgroup v1 is unlimited
+func TestCapacityDetectorCgroupV1Unlimited(t *testing.T) {
+	defer func(orig func() int) { numCPU = orig }(numCPU)
+	numCPU = func() int { return 8 }
+
+	proc, cg := t.TempDir(), t.TempDir()
+	writeFiles(t, proc, map[string]string{
+		"meminfo":     "MemTotal:       8192000 kB\n",
+		"self/cgroup": "4:memory:/\n1:cpu,cpuacct:/\n",
+	})
+	writeFiles(t, cg, map[string]string{
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		"cpu/cpu.cfs_quota_us":         "-1\n",
+		"cpu/cpu.cfs_period_us":        "100000\n",
+	})
+
+	d := NewCapacityDetector(proc, cg, fakeHostInfo{memoryBytes: 6000 * 1024 * 1024, cpus: 4}, common.CapacityConfig{}, NewCGroupManager(1024), zap.NewNop())
+
+	report, ok := d.Detect(context.Background())
+	if !ok {
+		t.Fatal("Expected capacity to be detected")
+	}
+	if report.Capacity.MemoryMB != 6000 || report.Sources["memory"] != "docker" {
+		t.Errorf("Expected 6000MB from docker, got %d from %s", report.Capacity.MemoryMB, report.Sources["memory"])
+	}
+	if report.Capacity.CPUMillicores != 4000 {
+		t.Errorf("Expected 4000 millicores, got %d", report.Capacity.CPUMillicores)
+	}
+}
+
+// TestCapacityDetectorReservedExceedsHost tests that a host smaller than its
+// reservations admits nothing, rather than reading as unlimited
+func TestCapacityDetectorReservedExceedsHost(t *testing.T) {
+	defer func(orig func() int) { numCPU = orig }(numCPU)
+	numCPU = func() int { return 1 }
+
+	proc := t.TempDir()
+	writeFiles(t, proc, map[string]string{"meminfo": "MemTotal:       512000 kB\n"}) // 500 MB
+
+	cgm := NewCGroupManagerWithCapacity(Resources{MemoryMB: 1024})
+	d := NewCapacityDetector(proc, t.TempDir(), nil, common.CapacityConfig{SystemReservedMemoryMB: 512, EvictionThresholdMemoryMB: 100, SystemReservedCPUMillicores: 1000}, cgm, zap.NewNop())
+	if _, ok := d.Refresh(context.Background()); !ok {
+		t.Fatal("Expected capacity to be detected")
+	}
+
+	if capacity := cgm.GetCapacity(); capacity.MemoryMB != 1 || capacity.CPUMillicores != 1 {
+		t.Errorf("Expected allocatable floored at 1MB / 1m, got %+v", capacity)
+	}
+	if err := cgm.AllocateResources("w", Resources{MemoryMB: 64, CPUMillicores: 100}); err == nil {
+		t.Error("Expected a full host to reject the reservation")
+	}
+}
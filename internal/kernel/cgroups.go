@@ -14,20 +14,46 @@ type CGroup struct {
 	mu         sync.RWMutex
 }
 
+// Resources describes an amount of each resource tracked by the CGroupManager.
+// A zero capacity means the resource is not limited.
+type Resources struct {
+	MemoryMB      int64 `json:"memory_mb"`
+	CPUMillicores int64 `json:"cpu_millicores"` // 1000 = 1 CPU
+	PIDs          int64 `json:"pids"`
+	DiskMB        int64 `json:"disk_mb"` // Ephemeral (writable layer) storage
+}
+
+// ResourceError is returned when a request does not fit into remaining capacity
+type ResourceError struct {
+	Resource  string
+	Requested int64
+	Available int64
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("not enough %s (%d requested, %d available)", e.Resource, e.Requested, e.Available)
+}
+
 // CGroupManager manages cgroups and resource limits
 type CGroupManager struct {
-	cgroups  map[string]*CGroup
-	totalMB  int64 // Total system memory
-	usedMB   int64 // Total used memory across all cgroups
-	mu       sync.RWMutex
+	cgroups      map[string]*CGroup
+	capacity     Resources            // Total system capacity
+	used         Resources            // Total reserved across all workloads
+	reservations map[string]Resources // Workload ID -> reserved resources
+	mu           sync.RWMutex
 }
 
 // NewCGroupManager creates a new cgroup manager with total memory capacity
 func NewCGroupManager(totalMB int64) *CGroupManager {
+	return NewCGroupManagerWithCapacity(Resources{MemoryMB: totalMB})
+}
+
+// NewCGroupManagerWithCapacity creates a cgroup manager tracking every resource
+func NewCGroupManagerWithCapacity(capacity Resources) *CGroupManager {
 	return &CGroupManager{
-		cgroups: make(map[string]*CGroup),
-		totalMB: totalMB,
-		usedMB:  0,
+		cgroups:      make(map[string]*CGroup),
+		capacity:     capacity,
+		reservations: make(map[string]Resources),
 	}
 }
 
@@ -96,41 +122,149 @@ func (cgm *CGroupManager) GetCGroup(name string) (*CGroup, bool) {
 
 // Allocate allocates memory from the global pool (simple interface for workloads)
 func (cgm *CGroupManager) Allocate(id string, mb int) bool {
+	return cgm.AllocateResources(id, Resources{MemoryMB: int64(mb)}) == nil
+}
+
+// AllocateResources reserves every requested resource for a workload, or none of them
+func (cgm *CGroupManager) AllocateResources(id string, req Resources) error {
 	cgm.mu.Lock()
 	defer cgm.mu.Unlock()
 
-	if cgm.usedMB+int64(mb) > cgm.totalMB {
-		fmt.Printf("[MEM] Not enough memory for %s (%dMB needed, %dMB available)\n", id, mb, cgm.totalMB-cgm.usedMB)
-		return false
+	checks := []struct {
+		name      string
+		requested int64
+		used      int64
+		capacity  int64
+	}{
+		{"memory", req.MemoryMB, cgm.used.MemoryMB, cgm.capacity.MemoryMB},
+		{"cpu", req.CPUMillicores, cgm.used.CPUMillicores, cgm.capacity.CPUMillicores},
+		{"pids", req.PIDs, cgm.used.PIDs, cgm.capacity.PIDs},
+		{"disk", req.DiskMB, cgm.used.DiskMB, cgm.capacity.DiskMB},
+	}
+	for _, c := range checks {
+		if c.capacity > 0 && c.used+c.requested > c.capacity {
+			fmt.Printf("[MEM] Not enough %s for %s (%d needed, %d available)\n", c.name, id, c.requested, c.capacity-c.used)
+			return &ResourceError{Resource: c.name, Requested: c.requested, Available: c.capacity - c.used}
+		}
 	}
 
-	cgm.usedMB += int64(mb)
-	fmt.Printf("[MEM] Allocated %dMB to %s (used: %dMB / %dMB)\n", mb, id, cgm.usedMB, cgm.totalMB)
-	return true
+	cgm.used = cgm.used.add(req)
+	cgm.reservations[id] = cgm.reservations[id].add(req)
+	fmt.Printf("[MEM] Allocated %dMB to %s (used: %dMB / %dMB)\n", req.MemoryMB, id, cgm.used.MemoryMB, cgm.capacity.MemoryMB)
+	return nil
 }
 
 // Free frees memory back to the global pool
 func (cgm *CGroupManager) Free(id string, mb int) {
+	cgm.FreeResources(id, Resources{MemoryMB: int64(mb)})
+}
+
+// FreeResources returns resources reserved by a workload to the global pool
+func (cgm *CGroupManager) FreeResources(id string, req Resources) {
 	cgm.mu.Lock()
 	defer cgm.mu.Unlock()
+	cgm.freeLocked(id, req)
+}
 
-	cgm.usedMB -= int64(mb)
-	if cgm.usedMB < 0 {
-		cgm.usedMB = 0
+// Release frees everything still reserved by a workload
+func (cgm *CGroupManager) Release(id string) {
+	cgm.mu.Lock()
+	defer cgm.mu.Unlock()
+	if r, ok := cgm.reservations[id]; ok {
+		cgm.freeLocked(id, r)
 	}
-	fmt.Printf("[MEM] Freed %dMB from %s (used: %dMB / %dMB)\n", mb, id, cgm.usedMB, cgm.totalMB)
+}
+
+func (cgm *CGroupManager) freeLocked(id string, req Resources) {
+	cgm.used = cgm.used.sub(req).clamp()
+	remaining := cgm.reservations[id].sub(req).clamp()
+	if remaining == (Resources{}) {
+		delete(cgm.reservations, id)
+	} else {
+		cgm.reservations[id] = remaining
+	}
+	fmt.Printf("[MEM] Freed %dMB from %s (used: %dMB / %dMB)\n", req.MemoryMB, id, cgm.used.MemoryMB, cgm.capacity.MemoryMB)
+}
+
+// GetReservation returns the resources currently reserved by a workload
+func (cgm *CGroupManager) GetReservation(id string) (Resources, bool) {
+	cgm.mu.RLock()
+	defer cgm.mu.RUnlock()
+	r, ok := cgm.reservations[id]
+	return r, ok
+}
+
+// Reservations returns a copy of every workload's reservation
+func (cgm *CGroupManager) Reservations() map[string]Resources {
+	cgm.mu.RLock()
+	defer cgm.mu.RUnlock()
+	result := make(map[string]Resources, len(cgm.reservations))
+	for id, r := range cgm.reservations {
+		result[id] = r
+	}
+	return result
 }
 
 // GetUsedMemory returns current total memory usage
 func (cgm *CGroupManager) GetUsedMemory() int {
 	cgm.mu.RLock()
 	defer cgm.mu.RUnlock()
-	return int(cgm.usedMB)
+	return int(cgm.used.MemoryMB)
 }
 
 // GetTotalMemory returns total memory capacity
 func (cgm *CGroupManager) GetTotalMemory() int {
 	cgm.mu.RLock()
 	defer cgm.mu.RUnlock()
-	return int(cgm.totalMB)
+	return int(cgm.capacity.MemoryMB)
+}
+
+// SetCapacity replaces total capacity. Existing reservations are kept even if
+// they no longer fit; only new allocations see the new capacity.
+func (cgm *CGroupManager) SetCapacity(capacity Resources) {
+	cgm.mu.Lock()
+	defer cgm.mu.Unlock()
+	cgm.capacity = capacity
+}
+
+// GetUsed returns resources reserved across all workloads
+func (cgm *CGroupManager) GetUsed() Resources {
+	cgm.mu.RLock()
+	defer cgm.mu.RUnlock()
+	return cgm.used
+}
+
+// GetCapacity returns total capacity for every tracked resource
+func (cgm *CGroupManager) GetCapacity() Resources {
+	cgm.mu.RLock()
+	defer cgm.mu.RUnlock()
+	return cgm.capacity
+}
+
+func (r Resources) add(o Resources) Resources {
+	return Resources{
+		MemoryMB:      r.MemoryMB + o.MemoryMB,
+		CPUMillicores: r.CPUMillicores + o.CPUMillicores,
+		PIDs:          r.PIDs + o.PIDs,
+		DiskMB:        r.DiskMB + o.DiskMB,
+	}
+}
+
+func (r Resources) sub(o Resources) Resources {
+	return Resources{
+		MemoryMB:      r.MemoryMB - o.MemoryMB,
+		CPUMillicores: r.CPUMillicores - o.CPUMillicores,
+		PIDs:          r.PIDs - o.PIDs,
+		DiskMB:        r.DiskMB - o.DiskMB,
+	}
+}
+
+// clamp floors every field at zero
+func (r Resources) clamp() Resources {
+	return Resources{
+		MemoryMB:      max(r.MemoryMB, 0),
+		CPUMillicores: max(r.CPUMillicores, 0),
+		PIDs:          max(r.PIDs, 0),
+		DiskMB:        max(r.DiskMB, 0),
+	}
 }
@@ -127,3 +127,48 @@ func TestCGroupManagerGlobalFree(t *testing.T) {
 		t.Errorf("Expected 0 MB used after free, got %d", cgm.GetUsedMemory())
 	}
 }
+
+// TestCGroupManagerAllocateResources tests that every resource is checked
+func TestCGroupManagerAllocateResources(t *testing.T) {
+	cgm := NewCGroupManagerWithCapacity(Resources{MemoryMB: 1024, CPUMillicores: 2000, PIDs: 100, DiskMB: 512})
+
+	if err := cgm.AllocateResources("test-1", Resources{MemoryMB: 256, CPUMillicores: 1500, PIDs: 50}); err != nil {
+		t.Fatalf("Expected allocation to succeed, got %v", err)
+	}
+
+	// Memory fits but CPU does not
+	err := cgm.AllocateResources("test-2", Resources{MemoryMB: 256, CPUMillicores: 1000})
+	rerr, ok := err.(*ResourceError)
+	if !ok || rerr.Resource != "cpu" {
+		t.Fatalf("Expected cpu ResourceError, got %v", err)
+	}
+
+	// Rejected request must not reserve anything
+	if used := cgm.GetUsed(); used.MemoryMB != 256 || used.CPUMillicores != 1500 {
+		t.Errorf("Expected only first reservation, got %+v", used)
+	}
+}
+
+// TestCGroupManagerUnlimitedResource tests that zero capacity is not enforced
+func TestCGroupManagerUnlimitedResource(t *testing.T) {
+	cgm := NewCGroupManager(1024)
+
+	if err := cgm.AllocateResources("test-1", Resources{MemoryMB: 128, PIDs: 100000}); err != nil {
+		t.Errorf("Expected untracked PIDs to be allowed, got %v", err)
+	}
+}
+
+// TestCGroupManagerRelease tests freeing a workload's whole reservation
+func TestCGroupManagerRelease(t *testing.T) {
+	cgm := NewCGroupManagerWithCapacity(Resources{MemoryMB: 1024, CPUMillicores: 2000})
+	cgm.AllocateResources("test-1", Resources{MemoryMB: 256, CPUMillicores: 500})
+
+	cgm.Release("test-1")
+
+	if used := cgm.GetUsed(); used != (Resources{}) {
+		t.Errorf("Expected nothing reserved after release, got %+v", used)
+	}
+	if _, ok := cgm.GetReservation("test-1"); ok {
+		t.Error("Expected reservation to be removed")
+	}
+}
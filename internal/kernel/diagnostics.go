@@ -0,0 +1,107 @@
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DiagnosticState is the kernel state captured in a diagnostic bundle
+type DiagnosticState struct {
+	Timestamp      time.Time            `json:"timestamp"`
+	Workloads      []*Workload          `json:"workloads"`
+	SchedulerQueue []Workload           `json:"scheduler_queue"`
+	Capacity       Resources            `json:"capacity"`
+	Used           Resources            `json:"used"`
+	Reservations   map[string]Resources `json:"reservations"`
+	CircuitBreaker string               `json:"circuit_breaker"`
+	ExecutorSlots  SlotUsage            `json:"executor_slots"`
+	Goroutines     int                  `json:"goroutines"`
+}
+
+// SlotUsage is how much of the executor's worker pool is taken
+type SlotUsage struct {
+	InUse int `json:"in_use"`
+	Size  int `json:"size"`
+}
+
+// DiagnosticDumper writes state bundles for debugging a stuck kernel (SIGUSR1/SIGQUIT)
+type DiagnosticDumper struct {
+	dir       string
+	store     Store
+	cgroups   *CGroupManager
+	executor  *Executor
+	scheduler func() Scheduler // The scheduler can be swapped on reload
+	logger    *zap.Logger
+}
+
+// NewDiagnosticDumper creates a dumper writing bundles under dir
+func NewDiagnosticDumper(dir string, store Store, cgroups *CGroupManager, executor *Executor, scheduler func() Scheduler, logger *zap.Logger) *DiagnosticDumper {
+	return &DiagnosticDumper{
+		dir:       dir,
+		store:     store,
+		cgroups:   cgroups,
+		executor:  executor,
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// Capture collects the current kernel state
+func (d *DiagnosticDumper) Capture() DiagnosticState {
+	state := DiagnosticState{
+		Timestamp:    time.Now(),
+		Workloads:    d.store.GetAll(),
+		Capacity:     d.cgroups.GetCapacity(),
+		Used:         d.cgroups.GetUsed(),
+		Reservations: d.cgroups.Reservations(),
+		Goroutines:   pprof.Lookup("goroutine").Count(),
+	}
+	if d.scheduler != nil {
+		state.SchedulerQueue = QueueSnapshot(d.scheduler())
+	}
+	if d.executor != nil {
+		state.CircuitBreaker = d.executor.CircuitState().String()
+		state.ExecutorSlots.InUse, state.ExecutorSlots.Size = d.executor.SlotUsage()
+	}
+	return state
+}
+
+// Dump writes a timestamped bundle directory holding state.json and
+// goroutines.txt, and returns its path. The process keeps running.
+func (d *DiagnosticDumper) Dump() (string, error) {
+	state := d.Capture()
+	bundle := filepath.Join(d.dir, "ckm-dump-"+state.Timestamp.Format("20060102T150405.000"))
+	if err := os.MkdirAll(bundle, 0o755); err != nil {
+		return "", fmt.Errorf("create bundle dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundle, "state.json"), data, 0o644); err != nil {
+		return "", fmt.Errorf("write state: %w", err)
+	}
+
+	f, err := os.Create(filepath.Join(bundle, "goroutines.txt"))
+	if err != nil {
+		return "", fmt.Errorf("write goroutines: %w", err)
+	}
+	defer f.Close()
+	// debug=2 prints every goroutine's full stack, like an unrecovered panic
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", fmt.Errorf("write goroutines: %w", err)
+	}
+
+	d.logger.Info("Diagnostic bundle written",
+		zap.String("path", bundle),
+		zap.Int("workloads", len(state.Workloads)),
+		zap.Int("goroutines", state.Goroutines))
+	return bundle, nil
+}
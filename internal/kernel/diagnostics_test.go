@@ -0,0 +1,49 @@
+package kernel
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestDiagnosticDump tests that a bundle holds state and goroutine stacks
+func TestDiagnosticDump(t *testing.T) {
+	store := NewWorkloadStore()
+	cgroups := NewCGroupManager(1024)
+	sched := NewFIFOScheduler()
+
+	w := &Workload{ID: "stuck", PID: 1001, MemoryMB: 128, Status: "running"}
+	store.Add(w)
+	cgroups.AllocateResources(w.ID, w.Resources())
+	sched.Add(*w)
+
+	d := NewDiagnosticDumper(t.TempDir(), store, cgroups, nil, func() Scheduler { return sched }, zap.NewNop())
+	bundle, err := d.Dump()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(bundle, "state.json"))
+	if err != nil {
+		t.Fatalf("Expected state.json: %v", err)
+	}
+	var state DiagnosticState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Invalid state.json: %v", err)
+	}
+	if len(state.Workloads) != 1 || len(state.SchedulerQueue) != 1 {
+		t.Errorf("Expected 1 workload and 1 queued, got %d and %d", len(state.Workloads), len(state.SchedulerQueue))
+	}
+	if state.Reservations["stuck"].MemoryMB != 128 || state.Used.MemoryMB != 128 {
+		t.Errorf("Expected 128MB reserved, got %+v", state.Reservations)
+	}
+
+	stacks, _ := os.ReadFile(filepath.Join(bundle, "goroutines.txt"))
+	if !strings.Contains(string(stacks), "TestDiagnosticDump") {
+		t.Error("Expected goroutine stacks to include the test goroutine")
+	}
+}
@@ -0,0 +1,188 @@
+package kernel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// queueFileVersion is bumped when the persisted queue format changes
+const queueFileVersion = 1
+
+// DrainTarget runs workloads and can be drained (implemented by Executor)
+type DrainTarget interface {
+	StopDispatch()
+	WaitTimeout(timeout time.Duration) bool
+	StopContainer(ctx context.Context, containerID string) error
+}
+
+// DrainReport summarizes a shutdown drain
+type DrainReport struct {
+	Queued      int  // Workloads persisted without having started
+	Interrupted int  // Running workloads stopped at the deadline
+	Completed   bool // All running workloads finished before the deadline
+}
+
+// Drainer drains the kernel on shutdown: it stops dispatch, persists the
+// queue, waits for running containers up to a deadline and then stops the
+// rest, marking them interrupted so they can be resumed after restart
+type Drainer struct {
+	store     Store
+	target    DrainTarget
+	queuePath string
+	logger    *zap.Logger
+	grace     time.Duration // How long stopped containers get to be cleaned up
+}
+
+// NewDrainer creates a drainer persisting the queue to queuePath
+func NewDrainer(store Store, target DrainTarget, queuePath string, logger *zap.Logger) *Drainer {
+	return &Drainer{
+		store:     store,
+		target:    target,
+		queuePath: queuePath,
+		logger:    logger,
+		grace:     15 * time.Second,
+	}
+}
+
+// Drain runs the drain sequence, waiting at most deadline for running workloads.
+// The caller is expected to have stopped admitting new work first.
+func (d *Drainer) Drain(deadline time.Duration) (DrainReport, error) {
+	var report DrainReport
+	d.target.StopDispatch()
+
+	// Persist the queue first so it survives even if we're killed while waiting
+	queued, err := d.persist()
+	if err != nil {
+		return report, err
+	}
+	report.Queued = queued
+	d.logger.Info("Drain: queue persisted, waiting for running workloads",
+		zap.Int("queued", queued), zap.Duration("deadline", deadline))
+
+	if report.Completed = d.target.WaitTimeout(deadline); report.Completed {
+		return report, nil
+	}
+
+	// Deadline passed: stop what's left and mark it for resume
+	ctx, cancel := context.WithTimeout(context.Background(), d.grace)
+	defer cancel()
+	for _, w := range d.store.GetAll() {
+		switch w.Status {
+		case StatusRunning:
+			if w.ContainerID == "" {
+				continue
+			}
+		case StatusScheduled, StatusCreating:
+			// Not started yet; the executor drops its container once Docker returns
+		default:
+			continue
+		}
+		if err := d.store.SetStatus(w.ID, StatusInterrupted, "ShutdownDeadline", fmt.Sprintf("stopped after the %s drain deadline", deadline)); err != nil {
+			continue // It moved on in the meantime
+		}
+		if w.ContainerID != "" {
+			if err := d.target.StopContainer(ctx, w.ContainerID); err != nil {
+				d.logger.Warn("Drain: failed to stop container", zap.String("workload", w.ID), zap.Error(err))
+			}
+		}
+		report.Interrupted++
+	}
+	d.target.WaitTimeout(d.grace)
+
+	if _, err := d.persist(); err != nil {
+		return report, err
+	}
+	d.logger.Info("Drain: interrupted running workloads", zap.Int("interrupted", report.Interrupted))
+	return report, nil
+}
+
+// persist writes queued and interrupted workloads to the queue file
+func (d *Drainer) persist() (int, error) {
+	var pending []*Workload
+	for _, w := range d.store.GetAll() {
+		if w.Status == StatusQueued || w.Status == StatusInterrupted {
+			pending = append(pending, w)
+		}
+	}
+	return len(pending), SaveQueue(d.queuePath, pending)
+}
+
+// PendingAfterRestart returns the workloads to resubmit after a restart:
+// those in the drained queue file plus any the store still has queued or
+// interrupted. The store wins over the file, so a workload the Reconciler
+// reattached or recorded as finished isn't started again. Run it after the
+// Reconciler has settled running workloads.
+func PendingAfterRestart(store Store, queued []*Workload) []*Workload {
+	byID := make(map[string]*Workload)
+	for _, w := range queued {
+		if cur, ok := store.Get(w.ID); ok && cur.Status != StatusQueued && cur.Status != StatusInterrupted {
+			continue
+		}
+		byID[w.ID] = w
+	}
+	for _, w := range store.GetAll() {
+		if w.Status == StatusQueued || w.Status == StatusInterrupted {
+			byID[w.ID] = w
+		}
+	}
+
+	// Resume parents before their children
+	pending := make([]*Workload, 0, len(byID))
+	for _, w := range byID {
+		pending = append(pending, w)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].PID < pending[j].PID })
+	return pending
+}
+
+// queueFile is the on-disk format of a persisted queue
+type queueFile struct {
+	Version   int         `json:"version"`
+	SavedAt   time.Time   `json:"saved_at"`
+	Workloads []*Workload `json:"workloads"`
+}
+
+// SaveQueue atomically writes workloads to path
+func SaveQueue(path string, workloads []*Workload) error {
+	data, err := json.MarshalIndent(queueFile{Version: queueFileVersion, SavedAt: time.Now(), Workloads: workloads}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode queue: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create queue dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write queue: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadQueue reads a persisted queue; a missing file is an empty queue
+func LoadQueue(path string) ([]*Workload, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read queue: %w", err)
+	}
+	var qf queueFile
+	if err := json.Unmarshal(data, &qf); err != nil {
+		return nil, fmt.Errorf("decode queue: %w", err)
+	}
+	if qf.Version != queueFileVersion {
+		return nil, fmt.Errorf("unsupported queue version %d", qf.Version)
+	}
+	for _, w := range qf.Workloads {
+		migrateStatus(w)
+	}
+	return qf.Workloads, nil
+}
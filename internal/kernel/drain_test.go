@@ -0,0 +1,119 @@
+package kernel
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeDrainTarget records drain calls; running workloads finish only if finish is set
+type fakeDrainTarget struct {
+	finish   bool
+	stopped  bool
+	stoppedC []string
+}
+
+func (f *fakeDrainTarget) StopDispatch() { f.stopped = true }
+
+func (f *fakeDrainTarget) WaitTimeout(time.Duration) bool { return f.finish }
+
+func (f *fakeDrainTarget) StopContainer(ctx context.Context, containerID string) error {
+	f.stoppedC = append(f.stoppedC, containerID)
+	return nil
+}
+
+// TestDrainInterruptsAtDeadline tests that running workloads are stopped and persisted
+func TestDrainInterruptsAtDeadline(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "queued", Status: "queued"})
+	store.Add(&Workload{ID: "busy", Status: "running", ContainerID: "c1"})
+	store.Add(&Workload{ID: "finished", Status: "succeeded"})
+
+	path := filepath.Join(t.TempDir(), "queue.json")
+	target := &fakeDrainTarget{}
+	d := NewDrainer(store, target, path, zap.NewNop())
+	d.grace = time.Millisecond
+
+	report, err := d.Drain(time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !target.stopped || report.Completed || report.Queued != 1 || report.Interrupted != 1 {
+		t.Errorf("Unexpected report %+v (dispatch stopped=%v)", report, target.stopped)
+	}
+	if len(target.stoppedC) != 1 || target.stoppedC[0] != "c1" {
+		t.Errorf("Expected container c1 to be stopped, got %v", target.stoppedC)
+	}
+	if w, _ := store.Get("busy"); w.Status != StatusInterrupted {
+		t.Errorf("Expected busy to be interrupted, got %s", w.Status)
+	}
+
+	persisted, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(persisted) != 2 {
+		t.Errorf("Expected queued and interrupted workloads persisted, got %d", len(persisted))
+	}
+}
+
+// TestDrainInterruptsUnstarted tests that workloads still being set up at the
+// deadline are interrupted and resumed after a restart rather than lost
+func TestDrainInterruptsUnstarted(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "scheduled", Status: "scheduled"})
+	store.Add(&Workload{ID: "creating", Status: "creating"})
+	store.Add(&Workload{ID: "created", Status: "creating", ContainerID: "c1"})
+
+	path := filepath.Join(t.TempDir(), "queue.json")
+	target := &fakeDrainTarget{}
+	d := NewDrainer(store, target, path, zap.NewNop())
+	d.grace = time.Millisecond
+
+	report, err := d.Drain(time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Interrupted != 3 {
+		t.Errorf("Expected 3 interrupted, got %+v", report)
+	}
+	if len(target.stoppedC) != 1 || target.stoppedC[0] != "c1" {
+		t.Errorf("Expected only container c1 to be stopped, got %v", target.stoppedC)
+	}
+	for _, id := range []string{"scheduled", "creating", "created"} {
+		if w, _ := store.Get(id); w.Status != StatusInterrupted {
+			t.Errorf("Expected %s to be interrupted, got %s", id, w.Status)
+		}
+	}
+
+	persisted, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pending := PendingAfterRestart(store, persisted); len(pending) != 3 {
+		t.Errorf("Expected all 3 resubmitted after a restart, got %d", len(pending))
+	}
+}
+
+// TestDrainCompletesBeforeDeadline tests that nothing is stopped when workloads finish in time
+func TestDrainCompletesBeforeDeadline(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "busy", Status: "running", ContainerID: "c1"})
+
+	target := &fakeDrainTarget{finish: true}
+	report, err := NewDrainer(store, target, filepath.Join(t.TempDir(), "queue.json"), zap.NewNop()).Drain(time.Second)
+	if err != nil || !report.Completed || len(target.stoppedC) != 0 {
+		t.Errorf("Expected a clean drain, got %+v, stopped %v, err %v", report, target.stoppedC, err)
+	}
+}
+
+// TestLoadQueueMissing tests that a missing queue file is an empty queue
+func TestLoadQueueMissing(t *testing.T) {
+	workloads, err := LoadQueue(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || len(workloads) != 0 {
+		t.Errorf("Expected empty queue, got %d workloads, err %v", len(workloads), err)
+	}
+}
@@ -0,0 +1,435 @@
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+const (
+	walFileName       = "wal.log"
+	snapshotFileName  = "snapshot.db"
+	snapshotVersion   = 2    // 1 held every workload in one frame
+	frameHeaderSize   = 8    // uint32 length + uint32 CRC-32C
+	defaultCompaction = 1000 // WAL records that trigger a snapshot
+)
+
+// maxFramePayload bounds a record; larger lengths can only come from
+// corruption. Tests lower it.
+var maxFramePayload = 64 << 20
+
+// ErrCorruptRecord is returned when a record's length or checksum is wrong
+var ErrCorruptRecord = errors.New("corrupt record")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// writeWALFrame is swapped out in tests to simulate short writes
+var writeWALFrame = writeFrame
+
+// walRecord is one mutation in the write-ahead log
+type walRecord struct {
+	Seq      uint64    `json:"seq"`
+	Op       string    `json:"op"` // "put" or "delete"
+	ID       string    `json:"id"`
+	Workload *Workload `json:"workload,omitempty"`
+}
+
+// snapshot is the header of the compacted state covering every WAL record
+// up to Seq. Count workload frames follow it, so no frame grows with the store.
+type snapshot struct {
+	Version   int         `json:"version"`
+	Seq       uint64      `json:"seq"`
+	Count     int         `json:"count"`
+	Workloads []*Workload `json:"workloads,omitempty"` // Version 1 only
+}
+
+// DurableStore is a WorkloadStore that survives restarts. Every mutation is
+// appended to a write-ahead log and fsynced before the call returns; the log
+// is compacted into a snapshot periodically. Each record carries a CRC-32C,
+// and a record torn by a crash mid-write is dropped on replay.
+type DurableStore struct {
+	*WorkloadStore
+	dir          string
+	wal          *os.File
+	walSize      int64  // End of the last complete record
+	torn         bool   // A failed append left bytes past walSize that couldn't be removed
+	seq          uint64 // Sequence number of the last record written
+	pending      int    // Records written since the last snapshot
+	compactAfter int
+	logger       *zap.Logger
+	mu           sync.Mutex // Guards wal, walSize, torn, seq and pending
+}
+
+// OpenDurableStore loads the snapshot and replays the WAL in dir, creating it if needed
+func OpenDurableStore(dir string, logger *zap.Logger) (*DurableStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+	d := &DurableStore{
+		WorkloadStore: NewWorkloadStore(),
+		dir:           dir,
+		compactAfter:  defaultCompaction,
+		logger:        logger,
+	}
+	if err := d.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := d.replayWAL(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open wal: %w", err)
+	}
+	info, err := wal.Stat()
+	if err != nil {
+		wal.Close()
+		return nil, fmt.Errorf("open wal: %w", err)
+	}
+	d.wal, d.walSize = wal, info.Size()
+	d.WorkloadStore.journal = d
+
+	// Every change is one WAL record, so resource versions carry on from the
+	// last sequence number and never repeat across restarts. Statuses from
+	// before the lifecycle was enforced are renamed on the way in.
+	d.rv = d.seq
+	for _, w := range d.workloads {
+		migrateStatus(w)
+		if w.ResourceVersion > d.rv {
+			d.rv = w.ResourceVersion
+		}
+	}
+
+	logger.Info("Workload store loaded",
+		zap.String("dir", dir),
+		zap.Int("workloads", len(d.workloads)),
+		zap.Uint64("seq", d.seq))
+	return d, nil
+}
+
+// Start compacts the WAL into a snapshot every interval until the context is cancelled
+func (d *DurableStore) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			pending := d.pending
+			d.mu.Unlock()
+			if pending == 0 {
+				continue
+			}
+			if err := d.Compact(); err != nil {
+				d.logger.Error("Store compaction failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Compact writes all workloads to a new snapshot and truncates the WAL.
+// A crash at any point leaves either the old or the new snapshot in place,
+// and WAL records already in a snapshot are skipped on replay.
+func (d *DurableStore) Compact() error {
+	d.WorkloadStore.mu.RLock()
+	defer d.WorkloadStore.mu.RUnlock()
+	return d.compactLocked()
+}
+
+// compactLocked snapshots the store; the caller holds the store lock
+func (d *DurableStore) compactLocked() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snap := snapshot{Version: snapshotVersion, Seq: d.seq, Count: len(d.workloads)}
+	path := filepath.Join(d.dir, snapshotFileName)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	err = writeJSONFrame(w, snap)
+	for _, wl := range d.workloads {
+		if err != nil {
+			break
+		}
+		err = writeJSONFrame(w, wl)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// The old snapshot and the WAL stay in place
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("install snapshot: %w", err)
+	}
+	if err := syncDir(d.dir); err != nil {
+		return err
+	}
+
+	// The snapshot now covers every record, so the WAL can start over
+	if err := d.wal.Truncate(0); err != nil {
+		return fmt.Errorf("truncate wal: %w", err)
+	}
+	if err := d.wal.Sync(); err != nil {
+		return fmt.Errorf("sync wal: %w", err)
+	}
+	d.walSize, d.torn = 0, false
+	d.pending = 0
+	common.StoreWALRecords.Set(0)
+	d.logger.Debug("Store compacted", zap.Int("workloads", snap.Count), zap.Uint64("seq", snap.Seq))
+	return nil
+}
+
+// Close flushes and closes the WAL
+func (d *DurableStore) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wal.Sync(); err != nil {
+		return err
+	}
+	return d.wal.Close()
+}
+
+// put journals a workload's new state (called with the store lock held)
+func (d *DurableStore) put(w *Workload) {
+	d.append(walRecord{Op: "put", ID: w.ID, Workload: w})
+}
+
+// delete journals a removal (called with the store lock held)
+func (d *DurableStore) delete(id string) {
+	d.append(walRecord{Op: "delete", ID: id})
+}
+
+// append writes one record and fsyncs it, compacting when the WAL grows large.
+// A failed write is cut off again, since replay stops at the first torn
+// record and would drop every record appended after it.
+func (d *DurableStore) append(rec walRecord) {
+	d.mu.Lock()
+	d.seq++
+	rec.Seq = d.seq
+	payload, err := json.Marshal(rec)
+	if err == nil && d.torn {
+		// Refuse to write past garbage until it's gone
+		err = d.truncateTorn()
+	}
+	if err == nil {
+		if err = writeWALFrame(d.wal, payload); err == nil {
+			err = d.wal.Sync()
+		}
+		if err != nil {
+			d.torn = true
+			if terr := d.truncateTorn(); terr != nil {
+				err = errors.Join(err, terr)
+			}
+		}
+	}
+	if err != nil {
+		d.mu.Unlock()
+		common.StoreWriteErrorsTotal.Inc()
+		d.logger.Error("Failed to append to workload WAL", zap.String("id", rec.ID), zap.Error(err))
+		return
+	}
+	d.walSize += int64(frameHeaderSize + len(payload))
+	d.pending++
+	common.StoreWALRecords.Set(float64(d.pending))
+	compact := d.pending >= d.compactAfter
+	d.mu.Unlock()
+
+	if compact {
+		if err := d.compactLocked(); err != nil {
+			d.logger.Error("Store compaction failed", zap.Error(err))
+		}
+	}
+}
+
+// truncateTorn cuts the WAL back to the end of the last complete record
+func (d *DurableStore) truncateTorn() error {
+	if err := d.wal.Truncate(d.walSize); err != nil {
+		return fmt.Errorf("truncate torn wal record: %w", err)
+	}
+	if err := d.wal.Sync(); err != nil {
+		return fmt.Errorf("truncate torn wal record: %w", err)
+	}
+	d.torn = false
+	return nil
+}
+
+// loadSnapshot restores the last snapshot, if there is one
+func (d *DurableStore) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(d.dir, snapshotFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	// Snapshots are installed by rename, so unlike the WAL they are never torn
+	r := bufio.NewReader(f)
+	payload, err := readFrame(r)
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(payload, &snap); err != nil {
+		return fmt.Errorf("decode snapshot: %w", err)
+	}
+	switch snap.Version {
+	case 1:
+	case snapshotVersion:
+		for i := 0; i < snap.Count; i++ {
+			if payload, err = readFrame(r); err != nil {
+				return fmt.Errorf("read snapshot workload %d: %w", i, err)
+			}
+			var w Workload
+			if err := json.Unmarshal(payload, &w); err != nil {
+				return fmt.Errorf("decode snapshot workload %d: %w", i, err)
+			}
+			snap.Workloads = append(snap.Workloads, &w)
+		}
+	default:
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	for _, w := range snap.Workloads {
+		d.workloads[w.ID] = w
+	}
+	d.seq = snap.Seq
+	return nil
+}
+
+// replayWAL applies records newer than the snapshot. Replay stops at the
+// first torn or corrupt record, and the WAL is truncated there so new
+// records aren't appended after garbage.
+func (d *DurableStore) replayWAL() error {
+	path := filepath.Join(d.dir, walFileName)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open wal: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var good int64
+	for {
+		payload, err := readFrame(r)
+		if err == io.EOF {
+			break
+		}
+		var rec walRecord
+		if err == nil {
+			err = json.Unmarshal(payload, &rec)
+		}
+		if err != nil {
+			d.logger.Warn("Dropping damaged WAL tail", zap.Int64("offset", good), zap.Error(err))
+			if err := os.Truncate(path, good); err != nil {
+				return fmt.Errorf("truncate wal: %w", err)
+			}
+			break
+		}
+		good += int64(frameHeaderSize + len(payload))
+
+		if rec.Seq <= d.seq {
+			continue // Already in the snapshot
+		}
+		switch rec.Op {
+		case "put":
+			d.workloads[rec.ID] = rec.Workload
+		case "delete":
+			delete(d.workloads, rec.ID)
+		}
+		d.seq = rec.Seq
+		d.pending++
+	}
+	return nil
+}
+
+// writeJSONFrame encodes v and writes it as one frame
+func writeJSONFrame(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, payload)
+}
+
+// writeFrame writes payload with its length and CRC-32C in a single write.
+// It refuses payloads readFrame would reject, so nothing unreadable is written.
+func writeFrame(w io.Writer, payload []byte) error {
+	if len(payload) > maxFramePayload {
+		return fmt.Errorf("record of %d bytes exceeds the %d byte limit", len(payload), maxFramePayload)
+	}
+	buf := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[frameHeaderSize:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads one frame. It returns io.EOF at a clean end of input and
+// ErrCorruptRecord for a partial frame or a checksum mismatch.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%w: short header", ErrCorruptRecord)
+	}
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if int64(length) > int64(maxFramePayload) {
+		return nil, fmt.Errorf("%w: length %d", ErrCorruptRecord, length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("%w: short payload", ErrCorruptRecord)
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptRecord)
+	}
+	return payload, nil
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open dir: %w", err)
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync dir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package kernel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func openTestStore(t *testing.T, dir string) *DurableStore {
+	t.Helper()
+	s, err := OpenDurableStore(dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return s
+}
+
+// TestDurableStoreReplay tests that mutations survive a reopen
+func TestDurableStoreReplay(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", PID: 1001, Status: "queued"})
+	s.Add(&Workload{ID: "b", PID: 1002, Status: "queued"})
+	s.SetStatus("a", "failed", "oom", "out of memory")
+	s.Mutate("a", func(w *Workload) { w.ExitCode = 137 })
+	s.Delete("b")
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	w, ok := s.Get("a")
+	if !ok || w.Status != "failed" || w.Reason != "oom" || w.ExitCode != 137 {
+		t.Errorf("Expected replayed failed workload with exit 137, got %+v", w)
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("Expected deleted workload to stay deleted")
+	}
+}
+
+// TestDurableStoreTornWrite tests that a record cut off mid-write is dropped
+func TestDurableStoreTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", Status: "queued"})
+	s.Add(&Workload{ID: "b", Status: "queued"})
+	s.Close()
+
+	// Simulate a crash halfway through the last record
+	path := filepath.Join(dir, walFileName)
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-5)
+
+	s = openTestStore(t, dir)
+	if _, ok := s.Get("a"); !ok {
+		t.Error("Expected intact record to be replayed")
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("Expected torn record to be dropped")
+	}
+
+	// New writes land after the last good record
+	s.Add(&Workload{ID: "c", Status: "queued"})
+	s.Close()
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if len(s.GetAll()) != 2 {
+		t.Errorf("Expected 2 workloads after recovery, got %d", len(s.GetAll()))
+	}
+}
+
+// TestDurableStoreFailedAppend tests that a write that fails partway doesn't
+// cost the records written after it
+func TestDurableStoreFailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", Status: "queued"})
+
+	defer func(orig func(io.Writer, []byte) error) { writeWALFrame = orig }(writeWALFrame)
+	writeWALFrame = func(w io.Writer, payload []byte) error {
+		var frame bytes.Buffer
+		writeFrame(&frame, payload)
+		w.Write(frame.Bytes()[:frame.Len()/2])
+		return errors.New("disk full")
+	}
+	s.Add(&Workload{ID: "b", Status: "queued"})
+	writeWALFrame = writeFrame
+
+	s.Add(&Workload{ID: "c", Status: "queued"})
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if _, ok := s.Get("c"); !ok {
+		t.Error("Expected the record after the failed write to survive")
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("Expected the failed record to be dropped")
+	}
+}
+
+// TestDurableStoreChecksum tests that a flipped byte stops replay at that record
+func TestDurableStoreChecksum(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", Status: "queued"})
+	s.Close()
+
+	path := filepath.Join(dir, walFileName)
+	data, _ := os.ReadFile(path)
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if len(s.GetAll()) != 0 {
+		t.Errorf("Expected corrupt record to be rejected, got %d workloads", len(s.GetAll()))
+	}
+}
+
+// TestDurableStoreCompact tests snapshots, including a crash before the WAL is truncated
+func TestDurableStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", Status: "queued"})
+	s.Add(&Workload{ID: "b", Status: "queued"})
+
+	// Keep a copy of the WAL as it was before compaction
+	wal, _ := os.ReadFile(filepath.Join(dir, walFileName))
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	s.Delete("b")
+	s.Close()
+
+	s = openTestStore(t, dir)
+	if _, ok := s.Get("b"); ok || len(s.GetAll()) != 1 {
+		t.Errorf("Expected snapshot plus WAL to hold only a, got %d workloads", len(s.GetAll()))
+	}
+	s.Close()
+
+	// Crash between installing the snapshot and truncating the WAL: old records are skipped
+	os.WriteFile(filepath.Join(dir, walFileName), wal, 0o644)
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if len(s.GetAll()) != 2 {
+		t.Errorf("Expected records covered by the snapshot to be skipped, got %d workloads", len(s.GetAll()))
+	}
+}
+
+// TestDurableStoreCompactPastFrameLimit tests that a snapshot bigger than one
+// frame can be read back, and that a workload too big for a frame never
+// replaces a readable snapshot
+func TestDurableStoreCompactPastFrameLimit(t *testing.T) {
+	defer func(limit int) { maxFramePayload = limit }(maxFramePayload)
+	maxFramePayload = 4096
+
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	for i := 0; i < 50; i++ {
+		s.Add(&Workload{ID: fmt.Sprintf("w%d", i), Status: "queued", Labels: map[string]string{"team": "storage"}})
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, snapshotFileName)); info.Size() <= int64(maxFramePayload) {
+		t.Fatalf("Expected a snapshot past the frame limit, got %d bytes", info.Size())
+	}
+
+	// Its WAL record fails, and so does the compaction that would include it
+	s.Add(&Workload{ID: "huge", Status: "queued", Image: strings.Repeat("x", maxFramePayload)})
+	if err := s.Compact(); err == nil {
+		t.Error("Expected compaction to refuse a workload bigger than a frame")
+	}
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if n := len(s.GetAll()); n != 50 {
+		t.Errorf("Expected the 50 compacted workloads after reopening, got %d", n)
+	}
+}
+
+// TestDurableStoreReadsVersion1Snapshot tests loading a snapshot written as one frame
+func TestDurableStoreReadsVersion1Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	writeJSONFrame(&buf, snapshot{Version: 1, Seq: 7, Workloads: []*Workload{{ID: "a", Status: "queued"}}})
+	os.WriteFile(filepath.Join(dir, snapshotFileName), buf.Bytes(), 0o644)
+
+	s := openTestStore(t, dir)
+	defer s.Close()
+	if _, ok := s.Get("a"); !ok || s.ResourceVersion() != 7 {
+		t.Errorf("Expected workload a at version 7, got %d workloads at %d", len(s.GetAll()), s.ResourceVersion())
+	}
+}
+
+// TestPendingAfterRestart tests which workloads are resubmitted after a restart
+func TestPendingAfterRestart(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "queued", PID: 1002, Status: "queued"})
+	store.Add(&Workload{ID: "stopped", PID: 1001, Status: StatusInterrupted})
+	store.Add(&Workload{ID: "finished", PID: 1004, Status: "succeeded"})
+	store.Add(&Workload{ID: "reattached", PID: 1003, Status: StatusRunning, ContainerID: "c1"})
+
+	pending := PendingAfterRestart(store, []*Workload{
+		{ID: "from-file", PID: 1005, Status: "queued"},
+		{ID: "reattached", PID: 1003, Status: StatusInterrupted}, // Its stop failed during the drain
+	})
+
+	if len(pending) != 3 || pending[0].ID != "stopped" || pending[2].ID != "from-file" {
+		t.Errorf("Unexpected pending workloads: %v", pending)
+	}
+}
+
+// TestDurableStoreResourceVersion tests that resource versions keep increasing across a reopen
+func TestDurableStoreResourceVersion(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a"})
+	s.Delete("a")
+	before := s.ResourceVersion()
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if s.ResourceVersion() != before {
+		t.Errorf("Expected resource version %d after reopen, got %d", before, s.ResourceVersion())
+	}
+	s.Add(&Workload{ID: "b"})
+	if w, _ := s.Get("b"); w.ResourceVersion <= before {
+		t.Errorf("Expected a version above %d, got %d", before, w.ResourceVersion)
+	}
+}
+
+// TestDurableStoreMigratesLegacyStatus tests that pre-lifecycle statuses are renamed on load
+func TestDurableStoreMigratesLegacyStatus(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	s.Add(&Workload{ID: "a", Status: "waiting"})
+	s.Add(&Workload{ID: "b", Status: "done"})
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if a, _ := s.Get("a"); a.Status != StatusQueued {
+		t.Errorf("Expected waiting to load as queued, got %s", a.Status)
+	}
+	if b, _ := s.Get("b"); b.Status != StatusSucceeded {
+		t.Errorf("Expected done to load as succeeded, got %s", b.Status)
+	}
+}
@@ -0,0 +1,154 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"ckm/internal/common"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// StatsSource provides real container resource usage (implemented by runtime.ContainerDiscovery)
+type StatsSource interface {
+	GetAllStats() []*runtime.ContainerStats
+	GetStats(containerID string) *runtime.ContainerStats
+}
+
+// ContainerStopper stops a workload's container (implemented by Executor)
+type ContainerStopper interface {
+	StopContainer(ctx context.Context, containerID string) error
+}
+
+// Evictor evicts workloads when real memory usage nears host capacity,
+// BestEffort first and then Burstable workloads using more than their request
+type Evictor struct {
+	store     Store
+	cgroups   *CGroupManager
+	stats     StatsSource
+	stopper   ContainerStopper
+	logger    *zap.Logger
+	threshold float64 // Fraction of memory capacity that triggers eviction
+	interval  time.Duration
+}
+
+// evictionCandidate is a running workload with its observed memory usage
+type evictionCandidate struct {
+	workload *Workload
+	usageMB  int64
+	overMB   int64 // Usage above the memory request
+}
+
+// NewEvictor creates a new memory pressure evictor
+func NewEvictor(store Store, cgroups *CGroupManager, stats StatsSource, stopper ContainerStopper, logger *zap.Logger, threshold float64, interval time.Duration) *Evictor {
+	return &Evictor{
+		store:     store,
+		cgroups:   cgroups,
+		stats:     stats,
+		stopper:   stopper,
+		logger:    logger,
+		threshold: threshold,
+		interval:  interval,
+	}
+}
+
+// Start runs the eviction loop until the context is cancelled
+func (e *Evictor) Start(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Check(ctx)
+		}
+	}
+}
+
+// Check evicts workloads until real memory usage drops under the threshold.
+// It returns the IDs of the evicted workloads.
+func (e *Evictor) Check(ctx context.Context) []string {
+	capacityMB := int64(e.cgroups.GetTotalMemory())
+	if capacityMB <= 0 {
+		return nil
+	}
+	limitMB := int64(float64(capacityMB) * e.threshold)
+
+	var usedMB int64
+	for _, st := range e.stats.GetAllStats() {
+		usedMB += int64(st.MemoryUsage / (1024 * 1024))
+	}
+	common.MemoryPressureUsedMB.Set(float64(usedMB))
+	if usedMB < limitMB {
+		return nil
+	}
+
+	var evicted []string
+	for _, c := range e.candidates() {
+		if usedMB < limitMB {
+			break
+		}
+		reason := fmt.Sprintf("memory pressure: host using %dMB of %dMB, %s workload using %dMB against a %dMB request",
+			usedMB, capacityMB, c.workload.QoSClass, c.usageMB, c.workload.MemoryMB)
+		if err := e.evict(ctx, c.workload, reason); err != nil {
+			e.logger.Error("Eviction failed", zap.String("workload", c.workload.ID), zap.Error(err))
+			continue
+		}
+		evicted = append(evicted, c.workload.ID)
+		usedMB -= c.usageMB
+	}
+	return evicted
+}
+
+// candidates returns evictable workloads in eviction order: BestEffort by
+// usage, then Burstable by how far they are over their request
+func (e *Evictor) candidates() []evictionCandidate {
+	var bestEffort, burstable []evictionCandidate
+	for _, w := range e.store.GetAll() {
+		if w.Status != StatusRunning || w.ContainerID == "" {
+			continue
+		}
+		st := e.stats.GetStats(w.ContainerID)
+		if st == nil {
+			continue
+		}
+		usageMB := int64(st.MemoryUsage / (1024 * 1024))
+		c := evictionCandidate{workload: w, usageMB: usageMB, overMB: usageMB - int64(w.MemoryMB)}
+
+		switch w.QoSClass {
+		case QoSBestEffort:
+			bestEffort = append(bestEffort, c)
+		case QoSBurstable:
+			if c.overMB > 0 {
+				burstable = append(burstable, c)
+			}
+		}
+	}
+
+	sort.Slice(bestEffort, func(i, j int) bool { return bestEffort[i].usageMB > bestEffort[j].usageMB })
+	sort.Slice(burstable, func(i, j int) bool { return burstable[i].overMB > burstable[j].overMB })
+	return append(bestEffort, burstable...)
+}
+
+// evict marks a workload preempted, stops its container and frees its reservation
+func (e *Evictor) evict(ctx context.Context, w *Workload, message string) error {
+	// Mark first so the executor doesn't record the stop as a failure
+	if err := e.store.SetStatus(w.ID, StatusPreempted, "MemoryPressure", message); err != nil {
+		return err // It finished in the meantime
+	}
+	if err := e.stopper.StopContainer(ctx, w.ContainerID); err != nil {
+		e.store.SetStatus(w.ID, StatusRunning, "EvictionFailed", err.Error())
+		return err
+	}
+	e.cgroups.Release(w.ID)
+	common.WorkloadEvictionsTotal.WithLabelValues(string(w.QoSClass), "memory_pressure").Inc()
+	e.logger.Warn("Workload evicted",
+		zap.String("workload", w.ID),
+		zap.String("qos", string(w.QoSClass)),
+		zap.String("message", message))
+	return nil
+}
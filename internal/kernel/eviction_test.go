@@ -0,0 +1,100 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// fakeStats serves fixed container stats
+type fakeStats map[string]*runtime.ContainerStats
+
+func (f fakeStats) GetAllStats() []*runtime.ContainerStats {
+	result := make([]*runtime.ContainerStats, 0, len(f))
+	for _, st := range f {
+		result = append(result, st)
+	}
+	return result
+}
+
+func (f fakeStats) GetStats(containerID string) *runtime.ContainerStats {
+	return f[containerID]
+}
+
+// fakeStopper records stopped containers
+type fakeStopper struct {
+	stopped []string
+}
+
+func (f *fakeStopper) StopContainer(ctx context.Context, containerID string) error {
+	f.stopped = append(f.stopped, containerID)
+	return nil
+}
+
+func mb(n uint64) uint64 { return n * 1024 * 1024 }
+
+// TestClassifyQoS tests QoS class derivation
+func TestClassifyQoS(t *testing.T) {
+	cases := []struct {
+		w    Workload
+		want QoSClass
+	}{
+		{Workload{}, QoSBestEffort},
+		{Workload{MemoryMB: 256, CPUMillicores: 500}, QoSGuaranteed},
+		{Workload{MemoryMB: 256, MemoryLimitMB: 256, CPUMillicores: 500, CPULimitMillicores: 500}, QoSGuaranteed},
+		{Workload{MemoryMB: 256}, QoSBurstable},
+		{Workload{MemoryMB: 256, MemoryLimitMB: 512, CPUMillicores: 500}, QoSBurstable},
+	}
+
+	for _, c := range cases {
+		if got := ClassifyQoS(&c.w); got != c.want {
+			t.Errorf("ClassifyQoS(%+v) = %s, want %s", c.w, got, c.want)
+		}
+	}
+}
+
+// TestEvictorOrder tests that BestEffort is evicted before over-request Burstable
+func TestEvictorOrder(t *testing.T) {
+	store := NewWorkloadStore()
+	cgroups := NewCGroupManager(1000)
+	store.Add(&Workload{ID: "guaranteed", Status: "running", ContainerID: "c1", MemoryMB: 300, QoSClass: QoSGuaranteed})
+	store.Add(&Workload{ID: "burstable", Status: "running", ContainerID: "c2", MemoryMB: 100, QoSClass: QoSBurstable})
+	store.Add(&Workload{ID: "besteffort", Status: "running", ContainerID: "c3", QoSClass: QoSBestEffort})
+
+	stats := fakeStats{
+		"c1": {ContainerID: "c1", MemoryUsage: mb(300)},
+		"c2": {ContainerID: "c2", MemoryUsage: mb(400)},
+		"c3": {ContainerID: "c3", MemoryUsage: mb(250)},
+	}
+	stopper := &fakeStopper{}
+	ev := NewEvictor(store, cgroups, stats, stopper, zap.NewNop(), 0.9, 0)
+
+	// 950MB used, limit 900MB: evicting BestEffort (250MB) is enough
+	evicted := ev.Check(context.Background())
+	if len(evicted) != 1 || evicted[0] != "besteffort" {
+		t.Fatalf("Expected only besteffort evicted, got %v", evicted)
+	}
+
+	w, _ := store.Get("besteffort")
+	if w.Status != StatusPreempted || w.Reason != "MemoryPressure" {
+		t.Errorf("Expected evicted with reason, got %s/%s", w.Status, w.Reason)
+	}
+}
+
+// TestEvictorNoPressure tests that nothing is evicted under the threshold
+func TestEvictorNoPressure(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "besteffort", Status: "running", ContainerID: "c1", QoSClass: QoSBestEffort})
+
+	stopper := &fakeStopper{}
+	ev := NewEvictor(store, NewCGroupManager(1000), fakeStats{"c1": {MemoryUsage: mb(100)}}, stopper, zap.NewNop(), 0.9, 0)
+
+	if evicted := ev.Check(context.Background()); len(evicted) != 0 {
+		t.Errorf("Expected no evictions, got %v", evicted)
+	}
+	if len(stopper.stopped) != 0 {
+		t.Errorf("Expected no containers stopped, got %v", stopper.stopped)
+	}
+}
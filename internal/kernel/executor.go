@@ -2,48 +2,122 @@ package kernel
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"ckm/internal/common"
 	"ckm/internal/runtime"
 	"go.uber.org/zap"
+
+	"github.com/docker/docker/api/types"
 )
 
+// ErrDraining is returned for workloads dispatched after the executor started draining
+var ErrDraining = errors.New("executor is draining")
+
+// ContainerRuntime is what the executor needs from Docker (implemented by runtime.DockerRuntime)
+type ContainerRuntime interface {
+	CreateContainer(ctx context.Context, imageName string, cmd []string, limits runtime.ResourceLimits, labels map[string]string) (string, error)
+	StartContainer(ctx context.Context, containerID string) error
+	WaitContainer(ctx context.Context, containerID string) (int64, error)
+	StopContainer(ctx context.Context, containerID string, timeout time.Duration) error
+	KillContainer(ctx context.Context, containerID, signal string) error
+	PauseContainer(ctx context.Context, containerID string) error
+	UnpauseContainer(ctx context.Context, containerID string) error
+	RemoveContainer(ctx context.Context, containerID string) error
+	InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	GetContainerLogs(ctx context.Context, containerID string, opts runtime.LogOptions) (io.ReadCloser, error)
+	Exec(ctx context.Context, containerID string, cmd []string, tty bool) (runtime.TerminalSession, error)
+	Attach(ctx context.Context, containerID string) (runtime.TerminalSession, error)
+}
+
 // Executor runs workloads using Docker runtime with worker pool
 type Executor struct {
-	runtime        *runtime.DockerRuntime
-	store          *WorkloadStore
+	runtime        ContainerRuntime
+	store          Store
 	logger         *zap.Logger
-	workerPool     chan struct{} // Limits concurrent executions
+	workerPool     *common.Semaphore // Limits concurrent executions
 	circuitBreaker *common.CircuitBreaker
+	oomHandler     func(ctx context.Context, w *Workload)
+	admission      AdmissionGate
+	processes      *ProcessManager
+	cgroups        *CGroupManager  // Optional; frees a workload's reservation once it finishes
+	logs           *LogStore       // Optional; saves container output before removal
+	dequeue        func(id string) // Optional; takes a workload off the scheduler queue
+	running        atomic.Int64    // Mirrors ckm_workloads_running_total
+	draining       atomic.Bool     // Set on shutdown; no new containers are started
 	wg             sync.WaitGroup
 }
 
+// AdmissionGate decides whether new workloads may be dispatched (e.g. PressureGate)
+type AdmissionGate interface {
+	Admit() (bool, string)
+}
+
 // NewExecutor creates a new workload executor with worker pool
-func NewExecutor(dockerRuntime *runtime.DockerRuntime, store *WorkloadStore, logger *zap.Logger, maxWorkers int) *Executor {
+func NewExecutor(dockerRuntime ContainerRuntime, store Store, logger *zap.Logger, maxWorkers int) *Executor {
 	return &Executor{
 		runtime:        dockerRuntime,
 		store:          store,
 		logger:         logger,
-		workerPool:     make(chan struct{}, maxWorkers),
+		workerPool:     common.NewSemaphore(maxWorkers),
 		circuitBreaker: common.NewCircuitBreaker(5, 30*time.Second), // Open after 5 failures, reset after 30s
 	}
 }
 
 // Execute runs a workload in a container with circuit breaker protection
 func (e *Executor) Execute(ctx context.Context, w *Workload) error {
+	// Track the workload in the process table; on exit it stays a zombie
+	// holding its exit status (-1 if the container never ran) until reaped
+	exitStatus := int64(-1)
+	if e.processes != nil {
+		if _, ok := e.processes.GetProcess(w.PID); !ok {
+			e.processes.CreateProcess(w.PID, w.PPID)
+		}
+		defer func() { e.processes.ExitProcess(w.PID, exitStatus) }()
+	}
+
+	// Hold dispatch while the admission gate reports pressure
+	if err := e.waitForAdmission(ctx, w); err != nil {
+		return err
+	}
+
 	// Acquire worker slot (limits concurrency)
-	e.workerPool <- struct{}{}
-	defer func() { <-e.workerPool }()
+	e.workerPool.Acquire()
+	defer e.workerPool.Release()
+
+	// A draining kernel leaves queued workloads waiting so they can be persisted
+	if e.draining.Load() {
+		return ErrDraining
+	}
+
+	// The workload leaves the queue whether it runs, was cancelled or was deleted
+	if e.dequeue != nil {
+		e.dequeue(w.ID)
+	}
+	defer e.releaseFinished(w.ID)
+
+	// Run the stored copy: changes made while queued apply, and the caller's
+	// workload is never written to while others may be reading it
+	cur, ok := e.store.Get(w.ID)
+	if !ok {
+		e.logger.Info("Workload deleted before dispatch", zap.String("workload", w.ID))
+		return nil
+	}
+	w = cur
 
 	e.wg.Add(1)
 	defer e.wg.Done()
 
-	// Update status to running
-	e.store.Update(w.ID, "running")
-	w.StartedAt = time.Now()
-	common.WorkloadsRunning.Inc()
+	if err := e.store.SetStatus(w.ID, StatusScheduled, "", ""); err != nil {
+		e.logger.Info("Workload not dispatched", zap.String("workload", w.ID), zap.Error(err))
+		return nil // Cancelled while queued
+	}
+	e.markRunning(1)
 
 	// Track execution time for metrics
 	startTime := time.Now()
@@ -54,23 +128,29 @@ func (e *Executor) Execute(ctx context.Context, w *Workload) error {
 
 	// Use circuit breaker for Docker operations
 	var containerID string
+	if err := e.store.SetStatus(w.ID, StatusCreating, "", ""); err != nil {
+		e.markRunning(-1)
+		return nil // Cancelled after it was scheduled
+	}
 	err := e.circuitBreaker.Call(func() error {
 		var createErr error
-		containerID, createErr = e.runtime.CreateContainer(ctx, w.Image, w.Command, w.MemoryMB, int64(w.Priority*512))
+		containerID, createErr = e.runtime.CreateContainer(ctx, w.Image, w.Command, containerLimits(w), ContainerLabels(w))
 		return createErr
 	})
 	if err != nil {
-		e.store.Update(w.ID, "failed")
+		e.whileCreating(w.ID, func(cur *Workload) {
+			cur.Status, cur.Reason, cur.Message = StatusFailed, "CreateFailed", err.Error()
+		})
 		common.WorkloadFailuresTotal.WithLabelValues(w.Type, "create").Inc()
 		if err == common.ErrCircuitOpen {
 			e.logger.Warn("Circuit breaker open, Docker operations paused", zap.String("workload", w.ID))
 		}
-		common.WorkloadsRunning.Dec()
+		e.markRunning(-1)
 		return err
 	}
 
 	w.ContainerID = containerID
-	e.store.Add(w)
+	e.store.Mutate(w.ID, func(cur *Workload) { cur.ContainerID = containerID })
 
 	// Track container startup time with circuit breaker
 	startupStart := time.Now()
@@ -78,58 +158,334 @@ func (e *Executor) Execute(ctx context.Context, w *Workload) error {
 		return e.runtime.StartContainer(ctx, containerID)
 	})
 	if err != nil {
-		e.store.Update(w.ID, "failed")
+		e.whileCreating(w.ID, func(cur *Workload) {
+			cur.Status, cur.Reason, cur.Message = StatusFailed, "StartFailed", err.Error()
+		})
 		common.WorkloadFailuresTotal.WithLabelValues(w.Type, "start").Inc()
-		common.WorkloadsRunning.Dec()
+		e.markRunning(-1)
 		return err
 	}
 	common.ContainerStartupTimeSeconds.Observe(time.Since(startupStart).Seconds())
 
-	// Wait for container completion
+	w.StartedAt = time.Now()
+	if !e.whileCreating(w.ID, func(cur *Workload) {
+		cur.Status, cur.Reason, cur.Message = StatusRunning, "", ""
+		cur.StartedAt = w.StartedAt
+	}) {
+		// Cancelled or interrupted while its container was being set up
+		_ = e.StopContainer(ctx, containerID)
+		e.saveLogs(ctx, w, containerID)
+		_ = e.runtime.RemoveContainer(ctx, containerID)
+		e.markRunning(-1)
+		return nil
+	}
+
+	exitStatus, err = e.await(ctx, w, containerID)
+	return err
+}
+
+// whileCreating applies fn to a workload that is still creating and reports
+// whether it was. A workload cancelled, or interrupted by a drain, while its
+// container was set up is left as it is
+func (e *Executor) whileCreating(id string, fn func(cur *Workload)) bool {
+	for {
+		cur, ok := e.store.Get(id)
+		if !ok || cur.Status != StatusCreating {
+			return false
+		}
+		fn(cur)
+		if _, err := e.store.CompareAndSwap(cur); !errors.Is(err, ErrConflict) {
+			return err == nil
+		}
+	}
+}
+
+// await waits for a started container, then records how it exited
+func (e *Executor) await(ctx context.Context, w *Workload, containerID string) (int64, error) {
 	var exitCode int64
-	err = e.circuitBreaker.Call(func() error {
+	err := e.circuitBreaker.Call(func() error {
 		var waitErr error
 		exitCode, waitErr = e.runtime.WaitContainer(ctx, containerID)
 		return waitErr
 	})
 	if err != nil {
-		e.store.Update(w.ID, "failed")
+		e.store.SetStatus(w.ID, StatusFailed, "WaitFailed", err.Error())
 		common.WorkloadFailuresTotal.WithLabelValues(w.Type, "wait").Inc()
-		common.WorkloadsRunning.Dec()
-		return err
+		e.markRunning(-1)
+		return -1, err
+	}
+
+	e.markRunning(-1)
+	e.RecordExit(ctx, w, containerID, exitCode)
+	return exitCode, nil
+}
+
+// RecordExit stores a finished container's exit status, removes the
+// container and hands OOM kills to the OOM handler
+func (e *Executor) RecordExit(ctx context.Context, w *Workload, containerID string, exitCode int64) {
+	// Save the output first, so logs are on disk once the workload shows as finished
+	e.saveLogs(ctx, w, containerID)
+
+	// Preempted and interrupted workloads keep their status; the exit code comes from the stop
+	if cur, ok := e.store.Get(w.ID); ok && (cur.Status == StatusPreempted || cur.Status == StatusInterrupted) {
+		_ = e.runtime.RemoveContainer(ctx, containerID)
+		return
 	}
 
 	// Update status based on exit code
+	e.store.Mutate(w.ID, func(cur *Workload) { cur.ExitCode = exitCode })
+	oomKilled := false
 	if exitCode == 0 {
-		e.store.Update(w.ID, "done")
+		e.store.SetStatus(w.ID, StatusSucceeded, "Completed", "container exited with code 0")
 		common.WorkloadCompleted.WithLabelValues(w.Type).Inc()
 	} else {
-		e.store.Update(w.ID, "failed")
-		common.WorkloadFailuresTotal.WithLabelValues(w.Type, "exit").Inc()
+		reason := "exit"
+		message := fmt.Sprintf("container exited with code %d", exitCode)
+		if e.wasOOMKilled(ctx, containerID) {
+			oomKilled = true
+			reason = "oom"
+			message = fmt.Sprintf("container exceeded its %dMB memory limit (exit code %d)", w.EffectiveMemoryLimitMB(), exitCode)
+		}
+		e.store.SetStatus(w.ID, StatusFailed, reason, message)
+		common.WorkloadFailuresTotal.WithLabelValues(w.Type, reason).Inc()
 	}
 
-	common.WorkloadsRunning.Dec()
-
-	// Cleanup container
+	// Cleanup container (inspect above must happen first)
 	_ = e.runtime.RemoveContainer(ctx, containerID)
-	return nil
+
+	if oomKilled && e.oomHandler != nil {
+		e.oomHandler(ctx, w)
+	}
+}
+
+// saveLogs copies a stopped container's output to the log store before the container is removed
+func (e *Executor) saveLogs(ctx context.Context, w *Workload, containerID string) {
+	if e.logs == nil {
+		return
+	}
+	logs, err := e.runtime.GetContainerLogs(ctx, containerID, runtime.LogOptions{Tail: "all"})
+	if err != nil {
+		e.logger.Warn("Failed to read container logs", zap.String("workload", w.ID), zap.Error(err))
+		return
+	}
+	defer logs.Close()
+	if _, err := e.logs.Capture(w.ID, logs); err != nil {
+		e.logger.Warn("Failed to save container logs", zap.String("workload", w.ID), zap.Error(err))
+	}
+}
+
+// Reattach resumes tracking a workload whose container survived a kernel
+// restart. The container already runs, so it doesn't take a worker slot.
+func (e *Executor) Reattach(ctx context.Context, w *Workload) {
+	e.wg.Add(1)
+	e.markRunning(1)
+	go func() {
+		defer e.wg.Done()
+		if e.processes != nil {
+			if _, ok := e.processes.GetProcess(w.PID); !ok {
+				e.processes.CreateProcess(w.PID, w.PPID)
+			}
+		}
+		exitStatus, err := e.await(ctx, w, w.ContainerID)
+		e.releaseFinished(w.ID)
+		if e.processes != nil {
+			e.processes.ExitProcess(w.PID, exitStatus)
+		}
+		if err != nil {
+			e.logger.Error("Reattached workload failed", zap.String("id", w.ID), zap.Error(err))
+		}
+	}()
+}
+
+// wasOOMKilled reports whether the kernel OOM killer stopped the container
+func (e *Executor) wasOOMKilled(ctx context.Context, containerID string) bool {
+	info, err := e.runtime.InspectContainer(ctx, containerID)
+	if err != nil || info.ContainerJSONBase == nil || info.State == nil {
+		return false
+	}
+	return info.State.OOMKilled
+}
+
+// waitForAdmission blocks until the admission gate admits the workload
+func (e *Executor) waitForAdmission(ctx context.Context, w *Workload) error {
+	if e.admission == nil {
+		return nil
+	}
+	held := false
+	for {
+		ok, reason := e.admission.Admit()
+		if ok {
+			return nil
+		}
+		if !held {
+			held = true
+			e.store.SetStatus(w.ID, StatusQueued, "PressureHeld", reason)
+			e.logger.Info("Dispatch held", zap.String("workload", w.ID), zap.String("reason", reason))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// SetAdmissionGate registers a gate consulted before every dispatch
+func (e *Executor) SetAdmissionGate(gate AdmissionGate) {
+	e.admission = gate
+}
+
+// SetProcessManager registers the process table workloads are tracked in
+func (e *Executor) SetProcessManager(pm *ProcessManager) {
+	e.processes = pm
+}
+
+// SetCGroupManager frees each workload's reservation as soon as it finishes
+func (e *Executor) SetCGroupManager(cgroups *CGroupManager) {
+	e.cgroups = cgroups
+}
+
+// releaseFinished frees the reservation of a workload that reached a terminal
+// status. Interrupted workloads keep theirs until they're resumed.
+func (e *Executor) releaseFinished(id string) {
+	if e.cgroups == nil {
+		return
+	}
+	if cur, ok := e.store.Get(id); ok && !IsTerminal(cur.Status) {
+		return
+	}
+	e.cgroups.Release(id)
+}
+
+// SetLogStore saves each container's output to logs before the container is removed
+func (e *Executor) SetLogStore(logs *LogStore) {
+	e.logs = logs
+}
+
+// SetDequeue registers what takes a workload off the scheduler queue when it's picked up
+func (e *Executor) SetDequeue(fn func(id string)) {
+	e.dequeue = fn
+}
+
+// SetOOMHandler registers a callback run after a workload is OOM killed
+func (e *Executor) SetOOMHandler(handler func(ctx context.Context, w *Workload)) {
+	e.oomHandler = handler
+}
+
+// containerLimits maps a workload's requests and limits onto Docker limits.
+// Requests set the CPU weight, limits set the hard caps.
+func containerLimits(w *Workload) runtime.ResourceLimits {
+	// Without an explicit CPU request, weight by priority as before
+	cpuShares := int64(w.Priority * 512)
+	if w.CPUMillicores > 0 {
+		cpuShares = w.CPUMillicores * 1024 / 1000
+	}
+	return runtime.ResourceLimits{
+		MemoryMB:      w.EffectiveMemoryLimitMB(),
+		CPUShares:     cpuShares,
+		CPUMillicores: w.EffectiveCPULimitMillicores(),
+		PIDsLimit:     w.PIDsLimit,
+		DiskMB:        w.DiskMB,
+	}
 }
 
 // ExecuteAsync runs workload in background goroutine
 func (e *Executor) ExecuteAsync(ctx context.Context, w *Workload) {
 	go func() {
-		if err := e.Execute(ctx, w); err != nil {
+		if err := e.Execute(ctx, w); err != nil && !errors.Is(err, ErrDraining) {
 			e.logger.Error("Workload execution failed", zap.String("id", w.ID), zap.Error(err))
 		}
 	}()
 }
 
+// SetWorkerPoolSize changes how many workloads may run at once; running
+// workloads are never interrupted when the pool shrinks
+func (e *Executor) SetWorkerPoolSize(size int) {
+	e.workerPool.SetLimit(size)
+}
+
+// SetCircuitBreakerThresholds changes when Docker calls trip the circuit breaker
+func (e *Executor) SetCircuitBreakerThresholds(maxFailures int, timeout time.Duration) {
+	e.circuitBreaker.SetThresholds(maxFailures, timeout)
+}
+
+// SlotUsage returns the worker slots in use and the pool size
+func (e *Executor) SlotUsage() (inUse, size int) {
+	return e.workerPool.Usage()
+}
+
+// CircuitState returns the Docker circuit breaker state
+func (e *Executor) CircuitState() common.CircuitState {
+	return e.circuitBreaker.GetState()
+}
+
+// Running returns the number of workloads currently running
+func (e *Executor) Running() int {
+	return int(e.running.Load())
+}
+
+// markRunning adjusts the running count and its gauge together
+func (e *Executor) markRunning(delta int64) {
+	e.running.Add(delta)
+	common.WorkloadsRunning.Add(float64(delta))
+}
+
 // Wait waits for all running workloads to complete
 func (e *Executor) Wait() {
 	e.wg.Wait()
 }
 
+// StopDispatch stops starting new containers; workloads already running continue
+func (e *Executor) StopDispatch() {
+	e.draining.Store(true)
+}
+
+// WaitTimeout waits for running workloads up to timeout and reports whether they all finished
+func (e *Executor) WaitTimeout(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // StopContainer stops a container (exposed for API server)
 func (e *Executor) StopContainer(ctx context.Context, containerID string) error {
 	return e.runtime.StopContainer(ctx, containerID, 10*time.Second)
 }
+
+// ContainerLogs streams a container's logs (exposed for the API server)
+func (e *Executor) ContainerLogs(ctx context.Context, containerID string, opts runtime.LogOptions) (io.ReadCloser, error) {
+	return e.runtime.GetContainerLogs(ctx, containerID, opts)
+}
+
+// Exec starts an interactive command in a container (exposed for the API server)
+func (e *Executor) Exec(ctx context.Context, containerID string, cmd []string, tty bool) (runtime.TerminalSession, error) {
+	return e.runtime.Exec(ctx, containerID, cmd, tty)
+}
+
+// Attach connects to a container's main process (exposed for the API server)
+func (e *Executor) Attach(ctx context.Context, containerID string) (runtime.TerminalSession, error) {
+	return e.runtime.Attach(ctx, containerID)
+}
+
+// KillContainer sends a signal to a container (exposed for signal delivery)
+func (e *Executor) KillContainer(ctx context.Context, containerID, signal string) error {
+	return e.runtime.KillContainer(ctx, containerID, signal)
+}
+
+// PauseContainer pauses a container (exposed for signal delivery)
+func (e *Executor) PauseContainer(ctx context.Context, containerID string) error {
+	return e.runtime.PauseContainer(ctx, containerID)
+}
+
+// UnpauseContainer unpauses a container (exposed for signal delivery)
+func (e *Executor) UnpauseContainer(ctx context.Context, containerID string) error {
+	return e.runtime.UnpauseContainer(ctx, containerID)
+}
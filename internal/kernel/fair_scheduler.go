@@ -2,6 +2,8 @@ package kernel
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -13,6 +15,7 @@ type FairWorkload struct {
 
 // FairScheduler schedules workloads fairly based on total runtime
 type FairScheduler struct {
+	mu      sync.Mutex // Guards queue; Add runs on concurrent API requests
 	queue   []FairWorkload
 	quantum time.Duration
 }
@@ -28,10 +31,32 @@ func NewFairScheduler(quantum time.Duration) *FairScheduler {
 // Add adds a workload to the queue
 func (s *FairScheduler) Add(w Workload) {
 	fmt.Printf("[Fair] Queued: %s\n", w.ID)
+	s.mu.Lock()
 	s.queue = append(s.queue, FairWorkload{Workload: w, RunTime: 0})
+	s.mu.Unlock()
 }
 
 // Run is a no-op; actual execution happens via Executor
 func (s *FairScheduler) Run() {
 	// Workloads are executed asynchronously by the Executor
 }
+
+// Snapshot returns a copy of the queued workloads
+func (s *FairScheduler) Snapshot() []Workload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Workload, 0, len(s.queue))
+	for _, fw := range s.queue {
+		result = append(result, fw.Workload)
+	}
+	return result
+}
+
+// Remove takes a dispatched workload off the queue
+func (s *FairScheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.queue)
+	s.queue = slices.DeleteFunc(s.queue, func(fw FairWorkload) bool { return fw.ID == id })
+	return len(s.queue) < n
+}
@@ -2,10 +2,13 @@ package kernel
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 )
 
 // FIFOScheduler schedules workloads in first-in-first-out order
 type FIFOScheduler struct {
+	mu    sync.Mutex // Guards queue; Add runs on concurrent API requests
 	queue []Workload
 }
 
@@ -19,11 +22,29 @@ func NewFIFOScheduler() *FIFOScheduler {
 // Add adds a workload to the queue
 func (s *FIFOScheduler) Add(w Workload) {
 	fmt.Printf("[FIFO] Queued PID %d (%s)\n", w.PID, w.ID)
-	w.Status = "waiting"
+	w.Status = StatusQueued
+	s.mu.Lock()
 	s.queue = append(s.queue, w)
+	s.mu.Unlock()
 }
 
 // Run is a no-op; actual execution happens via Executor
 func (s *FIFOScheduler) Run() {
 	// Workloads are executed asynchronously by the Executor
 }
+
+// Snapshot returns a copy of the queue
+func (s *FIFOScheduler) Snapshot() []Workload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Workload(nil), s.queue...)
+}
+
+// Remove takes a dispatched workload off the queue
+func (s *FIFOScheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.queue)
+	s.queue = slices.DeleteFunc(s.queue, func(w Workload) bool { return w.ID == id })
+	return len(s.queue) < n
+}
@@ -0,0 +1,188 @@
+package kernel
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// GCReport summarizes one garbage collection pass
+type GCReport struct {
+	Expired      int // Removed after their TTL
+	Capped       int // Removed to keep their type under the cap
+	Reservations int // Leftover cgroup reservations released
+	Containers   int // Leftover stopped containers removed
+	Finished     int // Finished workloads still kept
+}
+
+// GarbageCollector removes finished workloads once their TTL expires or
+// once their type has more finished workloads than the cap, oldest first.
+// It also frees what finished workloads leave behind: cgroup reservations
+// and stopped containers.
+type GarbageCollector struct {
+	store      Store
+	cgroups    *CGroupManager
+	containers ContainerInventory // Optional; without it containers are left alone
+	logger     *zap.Logger
+	now        func() time.Time
+
+	mu     sync.Mutex // Guards config, which can change on reload
+	config common.GCConfig
+}
+
+// NewGarbageCollector creates a garbage collector with the given policy
+func NewGarbageCollector(store Store, cgroups *CGroupManager, containers ContainerInventory, config common.GCConfig, logger *zap.Logger) *GarbageCollector {
+	return &GarbageCollector{
+		store:      store,
+		cgroups:    cgroups,
+		containers: containers,
+		logger:     logger,
+		now:        time.Now,
+		config:     config,
+	}
+}
+
+// SetConfig changes the TTL, cap and interval, starting with the next pass
+func (g *GarbageCollector) SetConfig(config common.GCConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.config = config
+}
+
+func (g *GarbageCollector) getConfig() common.GCConfig {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.config
+}
+
+// Start collects every interval until the context is cancelled
+func (g *GarbageCollector) Start(ctx context.Context) {
+	timer := time.NewTimer(g.getConfig().Interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			g.Collect(ctx)
+			timer.Reset(g.getConfig().Interval)
+		}
+	}
+}
+
+// Collect runs one garbage collection pass
+func (g *GarbageCollector) Collect(ctx context.Context) GCReport {
+	start := time.Now()
+	defer func() { common.GCDurationSeconds.Observe(time.Since(start).Seconds()) }()
+
+	config := g.getConfig()
+	now := g.now()
+	var report GCReport
+	live := make(map[string]bool) // Unfinished workloads, whose containers are in use
+	byType := make(map[string][]*Workload)
+	for _, w := range g.store.GetAll() {
+		if !IsTerminal(w.Status) {
+			live[w.ID] = true
+			continue
+		}
+		if now.Sub(finishedAt(w)) >= ttlAfterFinished(w, config.TTLAfterFinished) {
+			if g.collect(w, "ttl", &report) {
+				report.Expired++
+				continue
+			}
+		}
+		byType[w.Type] = append(byType[w.Type], w)
+	}
+
+	for _, finished := range byType {
+		// Newest first, so anything past the cap is the oldest
+		sort.Slice(finished, func(i, j int) bool { return finishedAt(finished[i]).After(finishedAt(finished[j])) })
+		for i, w := range finished {
+			if config.MaxFinishedPerType > 0 && i >= config.MaxFinishedPerType && g.collect(w, "cap", &report) {
+				report.Capped++
+				continue
+			}
+			report.Finished++
+			if _, ok := g.cgroups.GetReservation(w.ID); ok {
+				g.cgroups.Release(w.ID)
+				g.released("reservation", &report.Reservations)
+			}
+		}
+	}
+	common.FinishedWorkloads.Set(float64(report.Finished))
+
+	g.removeContainers(ctx, live, &report)
+
+	if report.Expired+report.Capped+report.Reservations+report.Containers > 0 {
+		g.logger.Info("Garbage collected finished workloads",
+			zap.Int("expired", report.Expired),
+			zap.Int("capped", report.Capped),
+			zap.Int("reservations", report.Reservations),
+			zap.Int("containers", report.Containers),
+			zap.Int("kept", report.Finished))
+	}
+	return report
+}
+
+// collect deletes a finished workload unless it changed since it was listed
+func (g *GarbageCollector) collect(w *Workload, reason string, report *GCReport) bool {
+	if err := g.store.CompareAndDelete(w.ID, w.ResourceVersion); err != nil {
+		return false
+	}
+	common.GCWorkloadsCollectedTotal.WithLabelValues(reason).Inc()
+	if _, ok := g.cgroups.GetReservation(w.ID); ok {
+		g.cgroups.Release(w.ID)
+		g.released("reservation", &report.Reservations)
+	}
+	return true
+}
+
+// removeContainers removes stopped managed containers whose workload has
+// finished or is gone. Running ones are left for the reconciler.
+func (g *GarbageCollector) removeContainers(ctx context.Context, live map[string]bool, report *GCReport) {
+	if g.containers == nil {
+		return
+	}
+	containers, err := g.containers.ListManagedContainers(ctx)
+	if err != nil {
+		g.logger.Warn("GC failed to list containers", zap.Error(err))
+		return
+	}
+	for _, c := range containers {
+		if c.Running || live[c.WorkloadID] {
+			continue
+		}
+		if err := g.containers.RemoveContainer(ctx, c.ID); err != nil {
+			g.logger.Warn("GC failed to remove container", zap.String("container", c.ID), zap.Error(err))
+			continue
+		}
+		g.released("container", &report.Containers)
+	}
+}
+
+// released counts a freed leftover resource
+func (g *GarbageCollector) released(kind string, count *int) {
+	*count++
+	common.GCResourcesReleasedTotal.WithLabelValues(kind).Inc()
+}
+
+// finishedAt is when a workload finished; workloads stored before
+// CompletedAt was recorded fall back to when they were created
+func finishedAt(w *Workload) time.Time {
+	if !w.CompletedAt.IsZero() {
+		return w.CompletedAt
+	}
+	return w.CreatedAt
+}
+
+// ttlAfterFinished is how long a finished workload is kept
+func ttlAfterFinished(w *Workload, defaultTTL time.Duration) time.Duration {
+	if w.TTLSecondsAfterFinished != nil {
+		return time.Duration(*w.TTLSecondsAfterFinished) * time.Second
+	}
+	return defaultTTL
+}
@@ -0,0 +1,93 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ckm/internal/common"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// finish adds a workload and moves it to a terminal status
+func finish(s Store, w *Workload, status string) {
+	s.Add(w)
+	s.SetStatus(w.ID, status, "", "")
+}
+
+// TestGCExpiresFinishedWorkloads tests the default and per-workload TTLs
+func TestGCExpiresFinishedWorkloads(t *testing.T) {
+	store := NewWorkloadStore()
+	cgroups := NewCGroupManager(1024)
+	zero := 0
+	finish(store, &Workload{ID: "default-ttl", Status: StatusRunning}, StatusSucceeded)
+	finish(store, &Workload{ID: "no-ttl", Status: StatusRunning, TTLSecondsAfterFinished: &zero}, StatusFailed)
+	store.Add(&Workload{ID: "running", Status: StatusRunning})
+	cgroups.AllocateResources("default-ttl", Resources{MemoryMB: 64})
+
+	gc := NewGarbageCollector(store, cgroups, nil, common.GCConfig{TTLAfterFinished: time.Hour, Interval: time.Minute}, zap.NewNop())
+	report := gc.Collect(context.Background())
+	if report.Expired != 1 || report.Finished != 1 {
+		t.Fatalf("Expected 1 expired and 1 kept, got %+v", report)
+	}
+	if _, ok := store.Get("no-ttl"); ok {
+		t.Error("Expected the zero-TTL workload to be collected")
+	}
+	// The kept workload's reservation is a leftover and is freed
+	if _, ok := cgroups.GetReservation("default-ttl"); ok || report.Reservations != 1 {
+		t.Errorf("Expected the finished workload's reservation to be released, got %+v", report)
+	}
+
+	gc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	gc.Collect(context.Background())
+	if _, ok := store.Get("default-ttl"); ok {
+		t.Error("Expected the workload to be collected after the default TTL")
+	}
+	if _, ok := store.Get("running"); !ok {
+		t.Error("Expected the running workload to be kept")
+	}
+}
+
+// TestGCCapsFinishedPerType tests that only the newest finished workloads of a type are kept
+func TestGCCapsFinishedPerType(t *testing.T) {
+	store := NewWorkloadStore()
+	for _, id := range []string{"old", "middle", "new"} {
+		finish(store, &Workload{ID: id, Type: "batch", Status: StatusRunning}, StatusSucceeded)
+		time.Sleep(time.Millisecond)
+	}
+	finish(store, &Workload{ID: "service", Type: "service", Status: StatusRunning}, StatusSucceeded)
+
+	gc := NewGarbageCollector(store, NewCGroupManager(1024), nil, common.GCConfig{TTLAfterFinished: time.Hour, MaxFinishedPerType: 2, Interval: time.Minute}, zap.NewNop())
+	report := gc.Collect(context.Background())
+	if report.Capped != 1 || report.Finished != 3 {
+		t.Fatalf("Expected 1 capped and 3 kept, got %+v", report)
+	}
+	if _, ok := store.Get("old"); ok {
+		t.Error("Expected the oldest batch workload to be collected")
+	}
+	for _, id := range []string{"middle", "new", "service"} {
+		if _, ok := store.Get(id); !ok {
+			t.Errorf("Expected %s to be kept", id)
+		}
+	}
+}
+
+// TestGCRemovesLeftoverContainers tests that only stopped containers of finished or gone workloads are removed
+func TestGCRemovesLeftoverContainers(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "live", Status: StatusCreating})
+	finish(store, &Workload{ID: "done", Status: StatusRunning}, StatusSucceeded)
+	inventory := &fakeInventory{containers: []runtime.ManagedContainer{
+		{ID: "c-gone", WorkloadID: "gone"},
+		{ID: "c-done", WorkloadID: "done"},
+		{ID: "c-live", WorkloadID: "live"},
+		{ID: "c-running", WorkloadID: "gone", Running: true},
+	}}
+
+	gc := NewGarbageCollector(store, NewCGroupManager(1024), inventory, common.GCConfig{TTLAfterFinished: time.Hour, Interval: time.Minute}, zap.NewNop())
+	report := gc.Collect(context.Background())
+	if report.Containers != 2 || len(inventory.removed) != 2 || inventory.removed[0] != "c-gone" || inventory.removed[1] != "c-done" {
+		t.Errorf("Expected c-gone and c-done removed, got %v", inventory.removed)
+	}
+}
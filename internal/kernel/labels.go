@@ -0,0 +1,170 @@
+package kernel
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Label keys and values are up to 63 alphanumerics, '-', '_' and '.',
+// starting and ending with an alphanumeric; values may be empty. Keys may
+// have a DNS-style prefix ending in '/'.
+var (
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	labelKeyPattern   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+)
+
+// ValidateLabels checks that every label key and value is well formed
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if !labelValuePattern.MatchString(v) {
+			return fmt.Errorf("invalid value %q for label %s", v, k)
+		}
+	}
+	return nil
+}
+
+// selectorOp is how a requirement compares a label
+type selectorOp int
+
+const (
+	opEquals selectorOp = iota
+	opNotEquals
+	opIn
+	opNotIn
+	opExists
+	opNotExists
+)
+
+// requirement is one comma-separated term of a selector
+type requirement struct {
+	key    string
+	op     selectorOp
+	values []string
+}
+
+// Selector matches workloads by label, like Kubernetes label selectors:
+// "env=prod,tier!=batch", "app", "!canary", "zone in (a,b)", "zone notin (c)".
+// All requirements must match; an empty selector matches everything.
+type Selector struct {
+	requirements []requirement
+}
+
+// ParseSelector parses a comma-separated label selector
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitTerms(s) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		req, err := parseRequirement(term)
+		if err != nil {
+			return Selector{}, err
+		}
+		sel.requirements = append(sel.requirements, req)
+	}
+	return sel, nil
+}
+
+// splitTerms splits on commas outside parentheses
+func splitTerms(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, s[start:])
+}
+
+func parseRequirement(term string) (requirement, error) {
+	var req requirement
+	switch {
+	case strings.HasPrefix(term, "!"):
+		req = requirement{key: strings.TrimSpace(term[1:]), op: opNotExists}
+	case strings.Contains(term, "!="):
+		k, v, _ := strings.Cut(term, "!=")
+		req = requirement{key: strings.TrimSpace(k), op: opNotEquals, values: []string{strings.TrimSpace(v)}}
+	case strings.Contains(term, "=="):
+		k, v, _ := strings.Cut(term, "==")
+		req = requirement{key: strings.TrimSpace(k), op: opEquals, values: []string{strings.TrimSpace(v)}}
+	case strings.Contains(term, "="):
+		k, v, _ := strings.Cut(term, "=")
+		req = requirement{key: strings.TrimSpace(k), op: opEquals, values: []string{strings.TrimSpace(v)}}
+	case strings.Contains(term, "("):
+		fields := strings.Fields(term[:strings.Index(term, "(")])
+		if len(fields) != 2 || !strings.HasSuffix(term, ")") {
+			return req, fmt.Errorf("invalid selector term %q", term)
+		}
+		switch fields[1] {
+		case "in":
+			req.op = opIn
+		case "notin":
+			req.op = opNotIn
+		default:
+			return req, fmt.Errorf("invalid selector operator %q in %q", fields[1], term)
+		}
+		req.key = fields[0]
+		list := term[strings.Index(term, "(")+1 : len(term)-1]
+		for _, v := range strings.Split(list, ",") {
+			req.values = append(req.values, strings.TrimSpace(v))
+		}
+	default:
+		req = requirement{key: term, op: opExists}
+	}
+
+	if !labelKeyPattern.MatchString(req.key) {
+		return req, fmt.Errorf("invalid label key %q in selector", req.key)
+	}
+	for _, v := range req.values {
+		if !labelValuePattern.MatchString(v) {
+			return req, fmt.Errorf("invalid label value %q in selector", v)
+		}
+	}
+	return req, nil
+}
+
+// Empty reports whether the selector has no requirements
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether labels satisfy every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s.requirements {
+		v, ok := labels[req.key]
+		switch req.op {
+		case opEquals, opIn:
+			if !ok || !slices.Contains(req.values, v) {
+				return false
+			}
+		case opNotEquals, opNotIn:
+			if ok && slices.Contains(req.values, v) {
+				return false
+			}
+		case opExists:
+			if !ok {
+				return false
+			}
+		case opNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
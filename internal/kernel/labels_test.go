@@ -0,0 +1,57 @@
+package kernel
+
+import "testing"
+
+// TestSelectorMatches tests equality, set and existence requirements
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "tier": "web", "zone": "eu-1"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env==prod,tier!=batch", true},
+		{"env=prod,tier=batch", false},
+		{"tier!=web", false},
+		{"missing!=x", true},
+		{"zone in (eu-1, us-1)", true},
+		{"zone notin (eu-1)", false},
+		{"env=prod,zone in (us-1,us-2)", false},
+		{"env", true},
+		{"!env", false},
+		{"!canary", true},
+		{"example.com/team", false},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Errorf("ParseSelector(%q) failed: %v", tt.selector, err)
+			continue
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+// TestParseSelectorInvalid tests that malformed selectors are rejected
+func TestParseSelectorInvalid(t *testing.T) {
+	for _, s := range []string{"env=pr od", "=prod", "zone in eu-1", "zone within (a)", "bad key=x", "zone in (a"} {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("Expected ParseSelector(%q) to fail", s)
+		}
+	}
+}
+
+// TestValidateLabels tests label key and value syntax
+func TestValidateLabels(t *testing.T) {
+	if err := ValidateLabels(map[string]string{"env": "prod", "example.com/team": "a-b_c.d", "empty": ""}); err != nil {
+		t.Errorf("Expected valid labels, got %v", err)
+	}
+	for _, labels := range []map[string]string{{"-env": "prod"}, {"env": "prod!"}, {"": "x"}} {
+		if err := ValidateLabels(labels); err == nil {
+			t.Errorf("Expected %v to be rejected", labels)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package kernel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Workload lifecycle. A workload is pending until it's admitted, queued
+// until a worker picks it, scheduled while it holds a worker slot, creating
+// while its container is set up, and running until it finishes.
+const (
+	StatusPending   = "pending"
+	StatusQueued    = "queued"
+	StatusScheduled = "scheduled"
+	StatusCreating  = "creating"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled" // Deleted before it finished
+	StatusPreempted = "preempted" // Stopped by the kernel to relieve resource pressure
+
+	// StatusInterrupted marks a workload stopped by a shutdown drain; it is resumed after restart
+	StatusInterrupted = "interrupted"
+)
+
+// transitions lists the statuses each status may move to
+var transitions = map[string][]string{
+	StatusPending: {StatusQueued, StatusCancelled, StatusFailed},
+	StatusQueued:  {StatusQueued, StatusScheduled, StatusCancelled, StatusFailed},
+	// Interrupted when a drain deadline stops it before it started
+	StatusScheduled: {StatusCreating, StatusCancelled, StatusFailed, StatusInterrupted},
+	StatusCreating:  {StatusRunning, StatusCancelled, StatusFailed, StatusInterrupted},
+	// Running again when reattached after a restart
+	StatusRunning: {StatusRunning, StatusSucceeded, StatusFailed, StatusCancelled, StatusPreempted, StatusInterrupted},
+	// The stop failed, so it kept running
+	StatusPreempted: {StatusRunning},
+	// A drained workload's container may still be found after a restart
+	StatusInterrupted: {StatusRunning, StatusSucceeded, StatusFailed, StatusCancelled},
+}
+
+// legacyStatuses maps statuses written before the lifecycle was enforced
+var legacyStatuses = map[string]string{
+	"waiting": StatusQueued,
+	"done":    StatusSucceeded,
+	"evicted": StatusPreempted,
+}
+
+// migrateStatus renames a legacy status loaded from disk
+func migrateStatus(w *Workload) {
+	if status, ok := legacyStatuses[w.Status]; ok {
+		w.Status = status
+	}
+}
+
+// Transition is one recorded status change
+type Transition struct {
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	Reason  string    `json:"reason,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// TransitionError is returned for a status change the lifecycle doesn't allow
+type TransitionError struct {
+	ID, From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("workload %s cannot move from %s to %s", e.ID, e.From, e.To)
+}
+
+// CanTransition reports whether a workload may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether a workload has finished. Preempted counts,
+// though a failed stop can still return it to running.
+func IsTerminal(status string) bool {
+	switch status {
+	case StatusSucceeded, StatusFailed, StatusCancelled, StatusPreempted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package kernel
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestCanTransition tests the workload lifecycle
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusQueued, true},
+		{StatusQueued, StatusScheduled, true},
+		{StatusScheduled, StatusCreating, true},
+		{StatusCreating, StatusRunning, true},
+		{StatusRunning, StatusSucceeded, true},
+		{StatusRunning, StatusPreempted, true},
+		{StatusQueued, StatusCancelled, true},
+		{StatusSucceeded, StatusRunning, false},
+		{StatusFailed, StatusQueued, false},
+		{StatusQueued, StatusRunning, false},
+		{StatusPending, StatusSucceeded, false},
+		{StatusCancelled, StatusCancelled, false},
+		{"bogus", StatusRunning, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// TestSetStatusRejectsIllegalTransition tests that finished workloads stay finished
+func TestSetStatusRejectsIllegalTransition(t *testing.T) {
+	s := NewWorkloadStore()
+	s.Add(&Workload{ID: "a", Status: StatusRunning})
+	s.SetStatus("a", StatusSucceeded, "Completed", "")
+
+	var transitionErr *TransitionError
+	if err := s.SetStatus("a", StatusRunning, "", ""); !errors.As(err, &transitionErr) {
+		t.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	if s.Mutate("a", func(w *Workload) { w.Status = StatusQueued }) {
+		t.Error("Expected Mutate to reject an illegal status change")
+	}
+	if w, _ := s.Get("a"); w.Status != StatusSucceeded {
+		t.Errorf("Expected status to stay succeeded, got %s", w.Status)
+	}
+	if err := s.SetStatus("missing", StatusQueued, "", ""); !errors.Is(err, ErrWorkloadNotFound) {
+		t.Errorf("Expected ErrWorkloadNotFound, got %v", err)
+	}
+}
+
+// TestTransitionHistory tests that every transition is recorded with its reason
+func TestTransitionHistory(t *testing.T) {
+	s := NewWorkloadStore()
+	s.Add(&Workload{ID: "a", Status: StatusPending})
+	for _, status := range []string{StatusQueued, StatusScheduled, StatusCreating, StatusRunning} {
+		if err := s.SetStatus("a", status, "", ""); err != nil {
+			t.Fatalf("Expected %s to be allowed, got %v", status, err)
+		}
+	}
+	s.SetStatus("a", StatusFailed, "oom", "out of memory")
+	s.SetStatus("a", StatusRunning, "", "") // Rejected, so not recorded
+
+	w, _ := s.Get("a")
+	want := []string{StatusPending, StatusQueued, StatusScheduled, StatusCreating, StatusRunning, StatusFailed}
+	if len(w.History) != len(want) {
+		t.Fatalf("Expected %d transitions, got %+v", len(want), w.History)
+	}
+	for i, tr := range w.History {
+		if tr.To != want[i] || tr.Time.IsZero() {
+			t.Errorf("Transition %d: expected to %s with a time, got %+v", i, want[i], tr)
+		}
+		if i > 0 && tr.From != want[i-1] {
+			t.Errorf("Transition %d: expected from %s, got %s", i, want[i-1], tr.From)
+		}
+	}
+	if last := w.History[len(w.History)-1]; last.Reason != "oom" || last.Message != "out of memory" {
+		t.Errorf("Expected the failure reason in history, got %+v", last)
+	}
+	if w.CompletedAt.IsZero() {
+		t.Error("Expected CompletedAt to be set on a terminal status")
+	}
+}
@@ -0,0 +1,95 @@
+package kernel
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+)
+
+// LoadFrequency is how often the run queue is sampled (LOAD_FREQ in Linux)
+const LoadFrequency = 5 * time.Second
+
+// loadWindows are the 1, 5 and 15 minute averaging windows
+var loadWindows = [3]time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// LoadAverages are exponentially damped run queue averages, like /proc/loadavg
+type LoadAverages struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// LoadAverage tracks 1, 5 and 15 minute load averages of runnable plus running workloads
+type LoadAverage struct {
+	sample   func() int
+	interval time.Duration
+	decay    [3]float64 // exp(-interval/window) per window
+	loads    [3]float64
+	last     int // Most recent run queue sample
+	mu       sync.RWMutex
+}
+
+// NewLoadAverage creates a load average sampling the run queue every interval
+func NewLoadAverage(sample func() int, interval time.Duration) *LoadAverage {
+	l := &LoadAverage{sample: sample, interval: interval}
+	for i, window := range loadWindows {
+		l.decay[i] = math.Exp(-interval.Seconds() / window.Seconds())
+	}
+	return l
+}
+
+// RunQueueSampler counts queued (waiting) workloads plus those the executor is running
+func RunQueueSampler(store Store, executor *Executor) func() int {
+	return func() int {
+		return store.CountByStatus(StatusQueued) + executor.Running()
+	}
+}
+
+// Start samples the run queue until the context is cancelled
+func (l *LoadAverage) Start(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Tick()
+		}
+	}
+}
+
+// Tick takes one run queue sample and folds it into the averages
+func (l *LoadAverage) Tick() {
+	n := l.sample()
+
+	l.mu.Lock()
+	l.last = n
+	for i := range l.loads {
+		l.loads[i] = l.loads[i]*l.decay[i] + float64(n)*(1-l.decay[i])
+	}
+	avg := LoadAverages{Load1: l.loads[0], Load5: l.loads[1], Load15: l.loads[2]}
+	l.mu.Unlock()
+
+	common.LoadAverage.WithLabelValues("1m").Set(avg.Load1)
+	common.LoadAverage.WithLabelValues("5m").Set(avg.Load5)
+	common.LoadAverage.WithLabelValues("15m").Set(avg.Load15)
+}
+
+// Get returns the current load averages
+func (l *LoadAverage) Get() LoadAverages {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return LoadAverages{Load1: l.loads[0], Load5: l.loads[1], Load15: l.loads[2]}
+}
+
+// Runnable returns the run queue length from the most recent sample
+func (l *LoadAverage) Runnable() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.last
+}
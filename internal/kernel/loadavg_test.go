@@ -0,0 +1,48 @@
+package kernel
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLoadAverageDamping tests that averages converge like the Linux load average
+func TestLoadAverageDamping(t *testing.T) {
+	n := 1
+	l := NewLoadAverage(func() int { return n }, LoadFrequency)
+
+	// One minute of a constant run queue of 1 brings load1 to 1 - 1/e
+	for i := 0; i < 12; i++ {
+		l.Tick()
+	}
+	avg := l.Get()
+	if math.Abs(avg.Load1-(1-math.Exp(-1))) > 0.001 {
+		t.Errorf("Expected load1 ~0.632, got %.3f", avg.Load1)
+	}
+	if !(avg.Load1 > avg.Load5 && avg.Load5 > avg.Load15) {
+		t.Errorf("Expected shorter windows to react faster, got %+v", avg)
+	}
+
+	// With an empty run queue the averages decay again
+	n = 0
+	l.Tick()
+	if l.Get().Load1 >= avg.Load1 {
+		t.Errorf("Expected load1 to decay, got %.3f", l.Get().Load1)
+	}
+	if l.Runnable() != 0 {
+		t.Errorf("Expected last sample 0, got %d", l.Runnable())
+	}
+}
+
+// TestRunQueueSampler tests that queued workloads count toward the run queue
+func TestRunQueueSampler(t *testing.T) {
+	store := NewWorkloadStore()
+	store.Add(&Workload{ID: "a", Status: "queued"})
+	store.Add(&Workload{ID: "b", Status: "queued"})
+	store.Add(&Workload{ID: "c", Status: "succeeded"})
+	e := &Executor{}
+	e.running.Add(3)
+
+	if got := RunQueueSampler(store, e)(); got != 5 {
+		t.Errorf("Expected run queue length 5, got %d", got)
+	}
+}
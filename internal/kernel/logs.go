@@ -0,0 +1,276 @@
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// LogStore keeps each workload's container output in files under dir, so
+// it can still be read after the container is removed. Each workload has a
+// current file plus rotated ones (.1 is the newest), in the JSON-lines
+// format of Docker's json-file driver.
+type LogStore struct {
+	dir    string
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex // Guards config and locks
+	config common.ContainerLogsConfig
+	locks  map[string]*workloadLock // Serialize each workload's writes and rotations
+}
+
+// workloadLock is held while one workload's log files are written, rotated or read
+type workloadLock struct {
+	sync.Mutex
+	refs int // Callers holding or waiting for it
+}
+
+// NewLogStore creates a log store writing under dir
+func NewLogStore(dir string, config common.ContainerLogsConfig, logger *zap.Logger) (*LogStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	return &LogStore{dir: dir, logger: logger, now: time.Now, config: config, locks: make(map[string]*workloadLock)}, nil
+}
+
+// SetConfig changes rotation and retention, starting with the next write or prune
+func (l *LogStore) SetConfig(config common.ContainerLogsConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = config
+}
+
+func (l *LogStore) getConfig() common.ContainerLogsConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// lock takes a workload's lock, so captures of different workloads run side
+// by side. The returned function releases it.
+func (l *LogStore) lock(workloadID string) (unlock func()) {
+	l.mu.Lock()
+	wl, ok := l.locks[workloadID]
+	if !ok {
+		wl = &workloadLock{}
+		l.locks[workloadID] = wl
+	}
+	wl.refs++
+	l.mu.Unlock()
+
+	wl.Lock()
+	return func() {
+		wl.Unlock()
+		l.mu.Lock()
+		if wl.refs--; wl.refs == 0 {
+			delete(l.locks, workloadID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// path returns a workload's current log file (n = 0) or its nth rotated one.
+// The ID is escaped so it can't leave dir.
+func (l *LogStore) path(workloadID string, n int) string {
+	name := "workload-" + url.PathEscape(workloadID) + ".log"
+	if n > 0 {
+		name += "." + strconv.Itoa(n)
+	}
+	return filepath.Join(l.dir, name)
+}
+
+// Has reports whether any logs were saved for a workload
+func (l *LogStore) Has(workloadID string) bool {
+	_, err := os.Stat(l.path(workloadID, 0))
+	return err == nil
+}
+
+// Capture appends a container's log stream (see runtime.ReadLogStream) to
+// the workload's log file, rotating it by size. It returns the lines written.
+func (l *LogStore) Capture(workloadID string, r io.Reader) (int, error) {
+	config := l.getConfig()
+	defer l.lock(workloadID)()
+	maxSize := int64(config.MaxSizeMB) << 20
+
+	f, size, err := l.openLocked(workloadID)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	defer func() {
+		w.Flush()
+		f.Close()
+	}()
+
+	lines := 0
+	err = runtime.ReadLogStream(r, func(entry runtime.LogEntry) error {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if size > 0 && size+int64(len(line)) > maxSize {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			f.Close()
+			if err := l.rotateLocked(workloadID, config.MaxFiles); err != nil {
+				return err
+			}
+			if f, size, err = l.openLocked(workloadID); err != nil {
+				return err
+			}
+			w.Reset(f)
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		size += int64(len(line))
+		lines++
+		return nil
+	})
+	if err != nil {
+		return lines, fmt.Errorf("capture logs for %s: %w", workloadID, err)
+	}
+	return lines, w.Flush()
+}
+
+// openLocked opens a workload's current log file for appending and returns its size
+func (l *LogStore) openLocked(workloadID string) (*os.File, int64, error) {
+	f, err := os.OpenFile(l.path(workloadID, 0), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("open log file: %w", err)
+	}
+	return f, info.Size(), nil
+}
+
+// rotateLocked shifts every file of a workload up by one, dropping those
+// past keep, so the current file becomes .1
+func (l *LogStore) rotateLocked(workloadID string, keep int) error {
+	// Files past the limit, including any left after max_files was lowered
+	for n := keep - 1; ; n++ {
+		err := os.Remove(l.path(workloadID, n))
+		if n > keep-1 && errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+	}
+	for n := keep - 2; n >= 0; n-- {
+		err := os.Rename(l.path(workloadID, n), l.path(workloadID, n+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Read returns a workload's saved lines written after since (zero for all),
+// oldest first. A non-negative tail keeps only that many lines from the end.
+func (l *LogStore) Read(workloadID string, since time.Time, tail int) ([]runtime.LogEntry, error) {
+	config := l.getConfig()
+	defer l.lock(workloadID)()
+
+	var entries []runtime.LogEntry
+	for n := config.MaxFiles - 1; n >= 0; n-- {
+		f, err := os.Open(l.path(workloadID, n))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read logs: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var entry runtime.LogEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue // Torn by a crash mid-write
+			}
+			if !since.IsZero() && !entry.Time.After(since) {
+				continue
+			}
+			entries = append(entries, entry)
+			if tail >= 0 && len(entries) > tail {
+				entries = entries[1:]
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read logs: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// Prune deletes log files that haven't been written for longer than the
+// retention period and returns how many it removed
+func (l *LogStore) Prune() int {
+	config := l.getConfig()
+	if config.Retention <= 0 {
+		return 0
+	}
+
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		l.logger.Warn("Failed to list log files", zap.Error(err))
+		return 0
+	}
+	cutoff := l.now().Add(-config.Retention)
+	removed := 0
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "workload-") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.dir, file.Name())); err != nil {
+			l.logger.Warn("Failed to remove log file", zap.String("file", file.Name()), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		l.logger.Info("Removed expired workload logs", zap.Int("files", removed))
+	}
+	return removed
+}
+
+// Start prunes expired log files every interval until the context is cancelled
+func (l *LogStore) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Prune()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package kernel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ckm/internal/common"
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// dockerLogs builds a multiplexed Docker log stream; stream 1 is stdout, 2 is stderr
+func dockerLogs(start time.Time, lines ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	for i, line := range lines {
+		stream := byte(1)
+		if strings.HasPrefix(line, "err:") {
+			stream = 2
+		}
+		payload := start.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano) + " " + line + "\n"
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		buf.Write(header)
+		buf.WriteString(payload)
+	}
+	return &buf
+}
+
+func newTestLogStore(t *testing.T, config common.ContainerLogsConfig) *LogStore {
+	t.Helper()
+	logs, err := NewLogStore(t.TempDir(), config, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create log store: %v", err)
+	}
+	return logs
+}
+
+// TestLogStoreCaptureAndRead tests demultiplexing, since and tail
+func TestLogStoreCaptureAndRead(t *testing.T) {
+	logs := newTestLogStore(t, common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 2})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if n, err := logs.Capture("job", dockerLogs(start, "one", "err:two", "three", "four")); err != nil || n != 4 {
+		t.Fatalf("Expected 4 lines captured, got %d %v", n, err)
+	}
+
+	all, err := logs.Read("job", time.Time{}, -1)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Expected 4 lines, got %d %v", len(all), err)
+	}
+	if all[1].Stream != runtime.StreamStderr || all[1].Log != "err:two" || !all[1].Time.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected stderr line with its timestamp, got %+v", all[1])
+	}
+	if tail, _ := logs.Read("job", time.Time{}, 2); len(tail) != 2 || tail[0].Log != "three" {
+		t.Errorf("Expected the last 2 lines, got %+v", tail)
+	}
+	if since, _ := logs.Read("job", start.Add(time.Second), -1); len(since) != 2 || since[0].Log != "three" {
+		t.Errorf("Expected lines after the second one, got %+v", since)
+	}
+	if logs.Has("other") {
+		t.Error("Expected no logs for another workload")
+	}
+}
+
+// TestLogStoreRotation tests size-based rotation and the file limit
+func TestLogStoreRotation(t *testing.T) {
+	logs := newTestLogStore(t, common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 2})
+	line := strings.Repeat("x", 1000)
+	lines := make([]string, 1500) // About 1.6MB once encoded
+	for i := range lines {
+		lines[i] = line
+	}
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := logs.Capture("job", dockerLogs(start, lines...)); err != nil {
+			t.Fatalf("Capture failed: %v", err)
+		}
+	}
+
+	for n, want := range []bool{true, true, false} {
+		_, err := os.Stat(logs.path("job", n))
+		if (err == nil) != want {
+			t.Errorf("File %d exists = %v, want %v", n, err == nil, want)
+		}
+	}
+	if info, _ := os.Stat(logs.path("job", 1)); info.Size() > 1<<20 {
+		t.Errorf("Expected rotated files under 1MB, got %d bytes", info.Size())
+	}
+	// The oldest lines were dropped with the third file
+	if entries, _ := logs.Read("job", time.Time{}, -1); len(entries) == 0 || len(entries) >= 3000 {
+		t.Errorf("Expected some but not all 3000 lines kept, got %d", len(entries))
+	}
+}
+
+// TestLogStorePrune tests retention by age
+func TestLogStorePrune(t *testing.T) {
+	logs := newTestLogStore(t, common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 1, Retention: time.Hour})
+	logs.Capture("old", dockerLogs(time.Now(), "a"))
+	logs.Capture("new", dockerLogs(time.Now(), "b"))
+	stale := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(logs.path("old", 0), stale, stale)
+
+	if removed := logs.Prune(); removed != 1 || logs.Has("old") || !logs.Has("new") {
+		t.Errorf("Expected only the stale file removed, got %d", removed)
+	}
+}
+
+// TestLogStoreEscapesIDs tests that a workload ID can't name a file outside the log dir
+func TestLogStoreEscapesIDs(t *testing.T) {
+	logs := newTestLogStore(t, common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 1})
+	if _, err := logs.Capture("../escape", dockerLogs(time.Now(), "a")); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if filepath.Dir(logs.path("../escape", 0)) != logs.dir || !logs.Has("../escape") {
+		t.Errorf("Expected the file inside the log dir, got %s", logs.path("../escape", 0))
+	}
+}
+
+// TestLogStoreCapturesInParallel tests that a slow stream doesn't hold up other workloads
+func TestLogStoreCapturesInParallel(t *testing.T) {
+	logs := newTestLogStore(t, common.ContainerLogsConfig{MaxSizeMB: 1, MaxFiles: 1})
+	slow, stalled := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := logs.Capture("slow", slow)
+		done <- err
+	}()
+	stalled.Write(dockerLogs(time.Now(), "first").Bytes())
+
+	fast := make(chan int, 1)
+	go func() {
+		n, _ := logs.Capture("fast", dockerLogs(time.Now(), "a", "b"))
+		fast <- n
+	}()
+	select {
+	case n := <-fast:
+		if n != 2 {
+			t.Errorf("Expected 2 lines captured, got %d", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a capture to finish while another workload's stream is open")
+	}
+
+	stalled.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Slow capture failed: %v", err)
+	}
+	if lines, _ := logs.Read("slow", time.Time{}, -1); len(lines) != 1 || len(logs.locks) != 0 {
+		t.Errorf("Expected the slow line saved and no locks left, got %v and %d locks", lines, len(logs.locks))
+	}
+}
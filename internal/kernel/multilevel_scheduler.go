@@ -31,3 +31,13 @@ func (m *MultilevelScheduler) Add(w Workload) {
 func (m *MultilevelScheduler) Run() {
 	// Workloads are executed asynchronously by the Executor
 }
+
+// Snapshot returns the VM queue followed by the task queue
+func (m *MultilevelScheduler) Snapshot() []Workload {
+	return append(QueueSnapshot(m.vmQueue), QueueSnapshot(m.taskQueue)...)
+}
+
+// Remove takes a dispatched workload off whichever queue holds it
+func (m *MultilevelScheduler) Remove(id string) bool {
+	return Dequeue(m.vmQueue, id) || Dequeue(m.taskQueue, id)
+}
@@ -0,0 +1,132 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// OOMRetryPolicy resubmits an OOM-killed workload with more memory
+type OOMRetryPolicy struct {
+	Factor      float64 `json:"factor"`        // Memory multiplier per retry, e.g. 2.0
+	MaxMemoryMB int     `json:"max_memory_mb"` // Memory is never raised above this
+	MaxRetries  int     `json:"max_retries"`   // 0 = retry until MaxMemoryMB is reached
+}
+
+// Next returns the memory for the next attempt, or false if no retry is allowed
+func (p *OOMRetryPolicy) Next(memoryMB, attempt int) (int, bool) {
+	if p == nil || p.Factor <= 1 || memoryMB <= 0 {
+		return 0, false
+	}
+	if p.MaxRetries > 0 && attempt >= p.MaxRetries {
+		return 0, false
+	}
+	next := int(float64(memoryMB) * p.Factor)
+	if p.MaxMemoryMB > 0 && next > p.MaxMemoryMB {
+		next = p.MaxMemoryMB
+	}
+	if next <= memoryMB {
+		return 0, false
+	}
+	return next, true
+}
+
+// OOMRetrier resubmits OOM-killed workloads according to their OOMRetryPolicy
+type OOMRetrier struct {
+	store    Store
+	cgroups  *CGroupManager
+	executor *Executor
+	logger   *zap.Logger
+}
+
+// NewOOMRetrier creates a new OOM retrier
+func NewOOMRetrier(store Store, cgroups *CGroupManager, executor *Executor, logger *zap.Logger) *OOMRetrier {
+	return &OOMRetrier{
+		store:    store,
+		cgroups:  cgroups,
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// Handle resubmits w as a new workload with scaled memory (registered via Executor.SetOOMHandler)
+func (r *OOMRetrier) Handle(ctx context.Context, w *Workload) {
+	retry, ok := r.Resubmit(w)
+	if !ok {
+		return
+	}
+	r.executor.ExecuteAsync(ctx, retry)
+}
+
+// Resubmit builds and reserves the next attempt for an OOM-killed workload
+func (r *OOMRetrier) Resubmit(w *Workload) (*Workload, bool) {
+	limitMB, ok := w.OOMRetry.Next(w.EffectiveMemoryLimitMB(), w.Attempt)
+	if !ok {
+		r.logger.Info("OOM retry not allowed", zap.String("workload", w.ID), zap.Int("attempt", w.Attempt))
+		return nil, false
+	}
+
+	pid, nspid, err := AllocPID(w.Namespace)
+	if err != nil {
+		r.logger.Warn("OOM retry rejected", zap.String("workload", w.ID), zap.Error(err))
+		return nil, false
+	}
+
+	original := w.ID
+	if w.RetryOf != "" {
+		original = w.RetryOf
+	}
+	retry := &Workload{
+		ID:                 fmt.Sprintf("%s-oom-%d", original, w.Attempt+1),
+		PID:                pid,
+		Namespace:          w.Namespace,
+		NSPID:              nspid,
+		PPID:               w.PPID,
+		Type:               w.Type,
+		CPUTime:            w.CPUTime,
+		MemoryMB:           scaleRequest(w.MemoryMB, w.EffectiveMemoryLimitMB(), limitMB),
+		MemoryLimitMB:      limitMB,
+		CPUMillicores:      w.CPUMillicores,
+		CPULimitMillicores: w.CPULimitMillicores,
+		PIDsLimit:          w.PIDsLimit,
+		DiskMB:             w.DiskMB,
+		Status:             StatusPending,
+		Priority:           w.Priority,
+		FilePath:           w.FilePath,
+		Image:              w.Image,
+		Command:            w.Command,
+		OOMRetry:           w.OOMRetry,
+		RetryOf:            original,
+		Attempt:            w.Attempt + 1,
+
+		TTLSecondsAfterFinished: w.TTLSecondsAfterFinished,
+	}
+	retry.QoSClass = ClassifyQoS(retry)
+
+	// The OOM-killed container is gone, so hand its reservation to the retry
+	r.cgroups.Release(w.ID)
+	if err := r.cgroups.AllocateResources(retry.ID, retry.Resources()); err != nil {
+		ReleasePID(pid)
+		r.logger.Warn("OOM retry rejected", zap.String("workload", retry.ID), zap.Error(err))
+		return nil, false
+	}
+
+	r.store.Add(retry)
+	r.store.SetStatus(retry.ID, StatusQueued, "OOMRetry", fmt.Sprintf("attempt %d with %dMB", retry.Attempt, limitMB))
+	common.WorkloadOOMRetriesTotal.WithLabelValues(w.Type).Inc()
+	r.logger.Info("Resubmitting OOM-killed workload",
+		zap.String("workload", w.ID),
+		zap.String("retry", retry.ID),
+		zap.Int("memory_limit_mb", limitMB))
+	return retry, true
+}
+
+// scaleRequest raises the memory request in proportion to the new limit
+func scaleRequest(requestMB, oldLimitMB, newLimitMB int) int {
+	if requestMB <= 0 || oldLimitMB <= 0 {
+		return requestMB
+	}
+	return requestMB * newLimitMB / oldLimitMB
+}
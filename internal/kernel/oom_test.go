@@ -0,0 +1,58 @@
+package kernel
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestOOMRetryPolicyNext tests memory scaling and caps
+func TestOOMRetryPolicyNext(t *testing.T) {
+	p := &OOMRetryPolicy{Factor: 2, MaxMemoryMB: 768, MaxRetries: 2}
+
+	if next, ok := p.Next(256, 0); !ok || next != 512 {
+		t.Errorf("Expected 512MB, got %d (%v)", next, ok)
+	}
+	if next, ok := p.Next(512, 1); !ok || next != 768 {
+		t.Errorf("Expected cap of 768MB, got %d (%v)", next, ok)
+	}
+	if _, ok := p.Next(768, 1); ok {
+		t.Error("Expected no retry once at max memory")
+	}
+	if _, ok := p.Next(256, 2); ok {
+		t.Error("Expected no retry after max retries")
+	}
+
+	var none *OOMRetryPolicy
+	if _, ok := none.Next(256, 0); ok {
+		t.Error("Expected no retry without a policy")
+	}
+}
+
+// TestOOMRetrierResubmit tests that a retry is stored with more memory
+func TestOOMRetrierResubmit(t *testing.T) {
+	store := NewWorkloadStore()
+	cgroups := NewCGroupManager(1024)
+	r := NewOOMRetrier(store, cgroups, nil, zap.NewNop())
+
+	w := &Workload{ID: "job", MemoryMB: 256, Status: "failed", OOMRetry: &OOMRetryPolicy{Factor: 2}}
+	store.Add(w)
+	cgroups.AllocateResources(w.ID, w.Resources())
+
+	retry, ok := r.Resubmit(w)
+	if !ok {
+		t.Fatal("Expected retry to be resubmitted")
+	}
+	if retry.ID != "job-oom-1" || retry.RetryOf != "job" || retry.Attempt != 1 {
+		t.Errorf("Unexpected retry identity: %s/%s/%d", retry.ID, retry.RetryOf, retry.Attempt)
+	}
+	if retry.MemoryMB != 512 || retry.MemoryLimitMB != 512 {
+		t.Errorf("Expected 512MB request and limit, got %d/%d", retry.MemoryMB, retry.MemoryLimitMB)
+	}
+	if cgroups.GetUsedMemory() != 512 {
+		t.Errorf("Expected reservation moved to retry, got %dMB used", cgroups.GetUsedMemory())
+	}
+	if _, ok := store.Get("job-oom-1"); !ok {
+		t.Error("Expected retry in store")
+	}
+}
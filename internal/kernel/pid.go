@@ -0,0 +1,255 @@
+package kernel
+
+import (
+	"errors"
+	"sync"
+
+	"ckm/internal/common"
+)
+
+const (
+	DefaultPIDMax = 32768           // Like /proc/sys/kernel/pid_max
+	firstPID      = common.FirstPID // Global PIDs below this are reserved (init is 1)
+)
+
+var ErrPIDExhausted = errors.New("no free PIDs")
+
+// PIDAllocator hands out PIDs from a bitmap, wrapping around at pidMax and
+// skipping PIDs that are still in use (like the Linux PID allocator)
+type PIDAllocator struct {
+	bitmap []uint64
+	minPID int
+	pidMax int // Exclusive upper bound
+	last   int // Last PID handed out
+	used   int
+}
+
+// NewPIDAllocator creates an allocator for PIDs in [minPID, pidMax)
+func NewPIDAllocator(minPID, pidMax int) *PIDAllocator {
+	return &PIDAllocator{
+		bitmap: make([]uint64, (pidMax+63)/64),
+		minPID: minPID,
+		pidMax: pidMax,
+		last:   minPID - 1,
+	}
+}
+
+// Alloc returns the next free PID after the last one handed out
+func (a *PIDAllocator) Alloc() (int, error) {
+	span := a.pidMax - a.minPID
+	for i := 1; i <= span; i++ {
+		pid := a.minPID + (a.last-a.minPID+i)%span
+		if !a.InUse(pid) {
+			a.set(pid)
+			a.last = pid
+			return pid, nil
+		}
+	}
+	return 0, ErrPIDExhausted
+}
+
+// Reserve marks a specific PID as in use (e.g. when restoring state)
+func (a *PIDAllocator) Reserve(pid int) bool {
+	if pid < a.minPID || pid >= a.pidMax || a.InUse(pid) {
+		return false
+	}
+	a.set(pid)
+	return true
+}
+
+// Free returns a PID to the pool
+func (a *PIDAllocator) Free(pid int) {
+	if pid < a.minPID || pid >= a.pidMax || !a.InUse(pid) {
+		return
+	}
+	a.bitmap[pid/64] &^= 1 << (pid % 64)
+	a.used--
+}
+
+// InUse reports whether a PID is allocated
+func (a *PIDAllocator) InUse(pid int) bool {
+	if pid < 0 || pid >= a.pidMax {
+		return false
+	}
+	return a.bitmap[pid/64]&(1<<(pid%64)) != 0
+}
+
+// Used returns the number of allocated PIDs
+func (a *PIDAllocator) Used() int {
+	return a.used
+}
+
+// Last returns the most recently allocated PID
+func (a *PIDAllocator) Last() int {
+	return a.last
+}
+
+func (a *PIDAllocator) set(pid int) {
+	a.bitmap[pid/64] |= 1 << (pid % 64)
+	a.used++
+}
+
+// PIDNamespace gives a tenant its own PID numbering starting at 1
+type PIDNamespace struct {
+	Name     string
+	alloc    *PIDAllocator
+	toGlobal map[int]int // Namespace PID -> global PID
+}
+
+// PIDManager allocates global PIDs and per-tenant namespace PIDs
+type PIDManager struct {
+	global     *PIDAllocator
+	namespaces map[string]*PIDNamespace
+	owners     map[int]string // Global PID -> namespace
+	toLocal    map[int]int    // Global PID -> namespace PID
+	pidMax     int
+	mu         sync.Mutex
+}
+
+// NewPIDManager creates a PID manager with the given pid_max
+func NewPIDManager(pidMax int) *PIDManager {
+	return &PIDManager{
+		global:     NewPIDAllocator(firstPID, pidMax),
+		namespaces: make(map[string]*PIDNamespace),
+		owners:     make(map[int]string),
+		toLocal:    make(map[int]int),
+		pidMax:     pidMax,
+	}
+}
+
+// Alloc allocates a global PID and, for a non-root namespace, a PID inside it
+func (m *PIDManager) Alloc(namespace string) (global, local int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	global, err = m.global.Alloc()
+	if err != nil {
+		return 0, 0, err
+	}
+	local = global
+	if namespace != "" {
+		ns := m.namespaceLocked(namespace)
+		if local, err = ns.alloc.Alloc(); err != nil {
+			m.global.Free(global)
+			return 0, 0, err
+		}
+		ns.toGlobal[local] = global
+	}
+	m.owners[global] = namespace
+	m.toLocal[global] = local
+	return global, local, nil
+}
+
+// Reserve marks a known global/namespace PID pair as in use (e.g. when restoring state)
+func (m *PIDManager) Reserve(global int, namespace string, local int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.global.Reserve(global) {
+		return false
+	}
+	if namespace == "" {
+		local = global
+	} else {
+		ns := m.namespaceLocked(namespace)
+		if !ns.alloc.Reserve(local) {
+			m.global.Free(global)
+			return false
+		}
+		ns.toGlobal[local] = global
+	}
+	m.owners[global] = namespace
+	m.toLocal[global] = local
+	return true
+}
+
+// Free releases a global PID and its namespace PID for reuse
+func (m *PIDManager) Free(global int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if namespace, ok := m.owners[global]; ok && namespace != "" {
+		ns := m.namespaces[namespace]
+		local := m.toLocal[global]
+		ns.alloc.Free(local)
+		delete(ns.toGlobal, local)
+	}
+	delete(m.owners, global)
+	delete(m.toLocal, global)
+	m.global.Free(global)
+}
+
+// Translate maps a namespace PID to its global PID
+func (m *PIDManager) Translate(namespace string, local int) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if namespace == "" {
+		return local, m.global.InUse(local)
+	}
+	ns, ok := m.namespaces[namespace]
+	if !ok {
+		return 0, false
+	}
+	global, ok := ns.toGlobal[local]
+	return global, ok
+}
+
+// Used returns the number of global PIDs in use
+func (m *PIDManager) Used() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.global.Used()
+}
+
+// Last returns the most recently allocated global PID, like the last field of /proc/loadavg
+func (m *PIDManager) Last() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.global.Last()
+}
+
+// namespaceLocked returns a namespace, creating it on first use
+func (m *PIDManager) namespaceLocked(name string) *PIDNamespace {
+	ns, ok := m.namespaces[name]
+	if !ok {
+		ns = &PIDNamespace{
+			Name:     name,
+			alloc:    NewPIDAllocator(1, m.pidMax),
+			toGlobal: make(map[int]int),
+		}
+		m.namespaces[name] = ns
+	}
+	return ns
+}
+
+// --- Default PID manager shared by the kernel ---
+
+var (
+	defaultPIDs   = NewPIDManager(DefaultPIDMax)
+	defaultPIDsMu sync.RWMutex
+)
+
+// SetPIDMax replaces the default PID manager; call before any PID is allocated
+func SetPIDMax(pidMax int) {
+	defaultPIDsMu.Lock()
+	defer defaultPIDsMu.Unlock()
+	defaultPIDs = NewPIDManager(pidMax)
+}
+
+// PIDs returns the default PID manager
+func PIDs() *PIDManager {
+	defaultPIDsMu.RLock()
+	defer defaultPIDsMu.RUnlock()
+	return defaultPIDs
+}
+
+// AllocPID allocates a PID from the default manager
+func AllocPID(namespace string) (global, local int, err error) {
+	return PIDs().Alloc(namespace)
+}
+
+// ReleasePID frees a PID in the default manager once its process is reaped
+func ReleasePID(pid int) {
+	PIDs().Free(pid)
+}
@@ -0,0 +1,77 @@
+package kernel
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPIDAllocatorWraparound tests that allocation wraps at pid_max and skips in-use PIDs
+func TestPIDAllocatorWraparound(t *testing.T) {
+	a := NewPIDAllocator(10, 14)
+
+	for want := 10; want < 14; want++ {
+		pid, err := a.Alloc()
+		if err != nil || pid != want {
+			t.Fatalf("Expected PID %d, got %d (err=%v)", want, pid, err)
+		}
+	}
+	if _, err := a.Alloc(); !errors.Is(err, ErrPIDExhausted) {
+		t.Fatalf("Expected ErrPIDExhausted, got %v", err)
+	}
+
+	// Freed PIDs are reused after wrapping; 10 is still in use and skipped
+	a.Free(12)
+	a.Free(11)
+	if pid, _ := a.Alloc(); pid != 11 {
+		t.Errorf("Expected wraparound to PID 11, got %d", pid)
+	}
+	if pid, _ := a.Alloc(); pid != 12 {
+		t.Errorf("Expected PID 12, got %d", pid)
+	}
+	if a.Used() != 4 {
+		t.Errorf("Expected 4 PIDs in use, got %d", a.Used())
+	}
+}
+
+// TestPIDManagerNamespaces tests per-tenant PID numbering and translation
+func TestPIDManagerNamespaces(t *testing.T) {
+	m := NewPIDManager(DefaultPIDMax)
+
+	g1, l1, _ := m.Alloc("tenant-a")
+	g2, l2, _ := m.Alloc("tenant-b")
+	g3, l3, _ := m.Alloc("tenant-a")
+
+	if l1 != 1 || l2 != 1 || l3 != 2 {
+		t.Errorf("Expected namespace PIDs 1, 1, 2, got %d, %d, %d", l1, l2, l3)
+	}
+	if g1 < firstPID || g1 == g2 || g2 == g3 {
+		t.Errorf("Expected distinct global PIDs >= %d, got %d, %d, %d", firstPID, g1, g2, g3)
+	}
+	if got, ok := m.Translate("tenant-a", 2); !ok || got != g3 {
+		t.Errorf("Expected tenant-a PID 2 -> %d, got %d (ok=%v)", g3, got, ok)
+	}
+
+	m.Free(g1)
+	if _, ok := m.Translate("tenant-a", 1); ok {
+		t.Error("Expected freed namespace PID to no longer translate")
+	}
+	if m.Used() != 2 {
+		t.Errorf("Expected 2 global PIDs in use, got %d", m.Used())
+	}
+}
+
+// TestPIDManagerRootNamespace tests that the root namespace uses global PIDs
+func TestPIDManagerRootNamespace(t *testing.T) {
+	m := NewPIDManager(DefaultPIDMax)
+
+	global, local, err := m.Alloc("")
+	if err != nil || global != local {
+		t.Fatalf("Expected global == local in root namespace, got %d/%d (err=%v)", global, local, err)
+	}
+	if !m.Reserve(global+5, "", 0) {
+		t.Error("Expected Reserve to succeed for a free PID")
+	}
+	if m.Reserve(global, "", 0) {
+		t.Error("Expected Reserve to fail for a PID in use")
+	}
+}
@@ -0,0 +1,212 @@
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// PSILine holds one line ("some" or "full") of a /proc/pressure file
+type PSILine struct {
+	Avg10  float64 // % of time stalled over the last 10s
+	Avg60  float64
+	Avg300 float64
+	Total  uint64 // Total stall time in microseconds
+}
+
+// PSIStats holds pressure-stall information for one resource
+type PSIStats struct {
+	Some PSILine
+	Full PSILine // Always zero for cpu on older kernels
+}
+
+// ReadPSI parses /proc/pressure/<resource> under procRoot (normally "/proc")
+func ReadPSI(procRoot, resource string) (PSIStats, error) {
+	var stats PSIStats
+	f, err := os.Open(filepath.Join(procRoot, "pressure", resource))
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var line *PSILine
+		switch fields[0] {
+		case "some":
+			line = &stats.Some
+		case "full":
+			line = &stats.Full
+		default:
+			return stats, fmt.Errorf("psi %s: unexpected line %q", resource, scanner.Text())
+		}
+		for _, kv := range fields[1:] {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return stats, fmt.Errorf("psi %s: malformed field %q", resource, kv)
+			}
+			switch key {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return stats, fmt.Errorf("psi %s: %w", resource, err)
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// PressureGate holds new dispatches while the host is under PSI pressure
+type PressureGate struct {
+	procRoot string
+	config   common.PressureConfig
+	logger   *zap.Logger
+	stats    map[string]PSIStats
+	reason   string // Why dispatch is held, empty when admitting
+	mu       sync.RWMutex
+}
+
+// NewPressureGate creates a PSI admission gate reading from procRoot
+func NewPressureGate(procRoot string, config common.PressureConfig, logger *zap.Logger) *PressureGate {
+	return &PressureGate{
+		procRoot: procRoot,
+		config:   config,
+		logger:   logger,
+		stats:    make(map[string]PSIStats),
+	}
+}
+
+// SetConfig changes the thresholds and read interval; they apply from the next reading
+func (g *PressureGate) SetConfig(config common.PressureConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.config = config
+}
+
+func (g *PressureGate) getConfig() common.PressureConfig {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.config
+}
+
+// Start refreshes pressure readings until the context is cancelled
+func (g *PressureGate) Start(ctx context.Context) {
+	if err := g.Refresh(); err != nil {
+		g.logger.Warn("PSI unavailable, admission gate disabled", zap.Error(err))
+	}
+
+	timer := time.NewTimer(g.getConfig().Interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := g.Refresh(); err != nil {
+				g.logger.Debug("Failed to read PSI", zap.Error(err))
+			}
+			timer.Reset(g.getConfig().Interval)
+		}
+	}
+}
+
+// Refresh re-reads every pressure file and re-evaluates the thresholds.
+// If PSI can't be read the gate fails open.
+func (g *PressureGate) Refresh() error {
+	stats := make(map[string]PSIStats)
+	for _, resource := range []string{"memory", "cpu", "io"} {
+		st, err := ReadPSI(g.procRoot, resource)
+		if err != nil {
+			g.mu.Lock()
+			g.reason = ""
+			g.mu.Unlock()
+			return err
+		}
+		stats[resource] = st
+		exportPSI(resource, st)
+	}
+
+	config := g.getConfig()
+	reason := ""
+	checks := []struct {
+		name      string
+		value     float64
+		threshold float64
+	}{
+		{"memory some", stats["memory"].Some.Avg10, config.MemorySome},
+		{"memory full", stats["memory"].Full.Avg10, config.MemoryFull},
+		{"cpu some", stats["cpu"].Some.Avg10, config.CPUSome},
+		{"io some", stats["io"].Some.Avg10, config.IOSome},
+		{"io full", stats["io"].Full.Avg10, config.IOFull},
+	}
+	for _, c := range checks {
+		if c.threshold > 0 && c.value > c.threshold {
+			reason = fmt.Sprintf("%s pressure %.2f%% above %.2f%%", c.name, c.value, c.threshold)
+			break
+		}
+	}
+
+	g.mu.Lock()
+	if reason != g.reason {
+		if reason != "" {
+			g.logger.Warn("Holding dispatch under pressure", zap.String("reason", reason))
+		} else {
+			g.logger.Info("Pressure cleared, resuming dispatch")
+		}
+	}
+	g.stats = stats
+	g.reason = reason
+	g.mu.Unlock()
+
+	if reason != "" {
+		common.AdmissionHeld.Set(1)
+	} else {
+		common.AdmissionHeld.Set(0)
+	}
+	return nil
+}
+
+// Admit reports whether a new workload may be dispatched, and why not
+func (g *PressureGate) Admit() (bool, string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.reason == "", g.reason
+}
+
+// GetStats returns the latest reading for a resource ("memory", "cpu", "io")
+func (g *PressureGate) GetStats(resource string) (PSIStats, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	st, ok := g.stats[resource]
+	return st, ok
+}
+
+// exportPSI publishes a pressure reading to Prometheus
+func exportPSI(resource string, st PSIStats) {
+	for kind, line := range map[string]PSILine{"some": st.Some, "full": st.Full} {
+		common.PressureStallPercent.WithLabelValues(resource, kind, "10s").Set(line.Avg10)
+		common.PressureStallPercent.WithLabelValues(resource, kind, "60s").Set(line.Avg60)
+		common.PressureStallPercent.WithLabelValues(resource, kind, "300s").Set(line.Avg300)
+	}
+}
@@ -0,0 +1,93 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// writePSIFixture writes /proc/pressure files under a temporary procfs root
+func writePSIFixture(t *testing.T, memory, cpu, io string) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "pressure")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"memory": memory, "cpu": cpu, "io": io} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const idlePSI = "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"
+
+// TestReadPSI tests parsing a pressure file
+func TestReadPSI(t *testing.T) {
+	root := writePSIFixture(t,
+		"some avg10=12.50 avg60=3.10 avg300=0.80 total=123456\nfull avg10=4.00 avg60=1.00 avg300=0.20 total=6543\n",
+		"some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n", idlePSI)
+
+	st, err := ReadPSI(root, "memory")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if st.Some.Avg10 != 12.5 || st.Some.Total != 123456 || st.Full.Avg60 != 1.0 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+
+	// cpu has no "full" line on older kernels
+	st, err = ReadPSI(root, "cpu")
+	if err != nil || st.Full.Avg10 != 0 {
+		t.Errorf("Expected cpu without full line to parse, got %+v, %v", st, err)
+	}
+}
+
+// TestPressureGateHolds tests holding and resuming dispatch
+func TestPressureGateHolds(t *testing.T) {
+	root := writePSIFixture(t,
+		"some avg10=55.00 avg60=20.00 avg300=5.00 total=1\nfull avg10=2.00 avg60=0.00 avg300=0.00 total=1\n",
+		idlePSI, idlePSI)
+	g := NewPressureGate(root, common.DefaultKernelConfig().Pressure, zap.NewNop())
+
+	if err := g.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ok, reason := g.Admit(); ok || reason == "" {
+		t.Error("Expected dispatch to be held under memory pressure")
+	}
+
+	// A reloaded threshold applies from the next reading
+	config := common.DefaultKernelConfig().Pressure
+	config.MemorySome = 60
+	g.SetConfig(config)
+	g.Refresh()
+	if ok, _ := g.Admit(); !ok {
+		t.Error("Expected dispatch to resume under a raised threshold")
+	}
+	config.MemorySome = 40
+	g.SetConfig(config)
+
+	os.WriteFile(filepath.Join(root, "pressure", "memory"), []byte(idlePSI), 0o644)
+	g.Refresh()
+	if ok, _ := g.Admit(); !ok {
+		t.Error("Expected dispatch to resume once pressure cleared")
+	}
+}
+
+// TestPressureGateFailsOpen tests admitting when PSI is unavailable
+func TestPressureGateFailsOpen(t *testing.T) {
+	g := NewPressureGate(t.TempDir(), common.DefaultKernelConfig().Pressure, zap.NewNop())
+
+	if err := g.Refresh(); err == nil {
+		t.Error("Expected error without pressure files")
+	}
+	if ok, _ := g.Admit(); !ok {
+		t.Error("Expected gate to admit when PSI is unavailable")
+	}
+}
@@ -2,10 +2,13 @@ package kernel
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 )
 
 // PriorityScheduler schedules workloads by priority (lower number = higher priority)
 type PriorityScheduler struct {
+	mu    sync.Mutex // Guards queue; Add runs on concurrent API requests
 	queue []Workload
 }
 
@@ -19,11 +22,29 @@ func NewPriorityScheduler() *PriorityScheduler {
 // Add adds a workload to the queue
 func (s *PriorityScheduler) Add(w Workload) {
 	fmt.Printf("[Priority] Queued: %s (priority %d)\n", w.ID, w.Priority)
-	w.Status = "waiting"
+	w.Status = StatusQueued
+	s.mu.Lock()
 	s.queue = append(s.queue, w)
+	s.mu.Unlock()
 }
 
 // Run is a no-op; actual execution happens via Executor
 func (s *PriorityScheduler) Run() {
 	// Workloads are executed asynchronously by the Executor
 }
+
+// Snapshot returns a copy of the queue
+func (s *PriorityScheduler) Snapshot() []Workload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Workload(nil), s.queue...)
+}
+
+// Remove takes a dispatched workload off the queue
+func (s *PriorityScheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.queue)
+	s.queue = slices.DeleteFunc(s.queue, func(w Workload) bool { return w.ID == id })
+	return len(s.queue) < n
+}
@@ -2,12 +2,16 @@ package kernel
 
 import (
 	"sync"
+	"time"
 )
 
+// InitPID is the PID orphaned processes are reparented to
+const InitPID = 1
+
 // ProcessGroup represents a group of related processes (like Unix process groups)
 type ProcessGroup struct {
 	ID        int
-	LeaderPID int   // PID of the group leader
+	LeaderPID int // PID of the group leader
 	PIDs      []int
 	mu        sync.RWMutex
 }
@@ -16,27 +20,34 @@ type ProcessGroup struct {
 type ProcessManager struct {
 	processes     map[int]*ProcessInfo
 	processGroups map[int]*ProcessGroup
-	sessions      map[int][]int // Session ID -> PIDs
+	sessions      map[int]*Session // Session ID -> session
+	exited        chan struct{}    // Closed and replaced whenever a process exits
 	mu            sync.RWMutex
 }
 
 // ProcessInfo stores process metadata
 type ProcessInfo struct {
 	PID      int
-	PPID     int    // Parent PID
-	PGID     int    // Process group ID
-	SID      int    // Session ID
-	State    string // "running", "terminated"
-	Children []int  // Child PIDs
+	PPID     int       // Parent PID
+	PGID     int       // Process group ID
+	SID      int       // Session ID
+	State    string    // "running", "stopped", "zombie", "terminated"
+	Children []int     // Child PIDs
+	ExitCode int64     // Exit status held by a zombie until reaped
+	ExitedAt time.Time // When the process exited
 }
 
 // NewProcessManager creates a new process manager
 func NewProcessManager() *ProcessManager {
-	return &ProcessManager{
+	pm := &ProcessManager{
 		processes:     make(map[int]*ProcessInfo),
 		processGroups: make(map[int]*ProcessGroup),
-		sessions:      make(map[int][]int),
+		sessions:      make(map[int]*Session),
+		exited:        make(chan struct{}),
 	}
+	// init adopts orphans and never exits
+	pm.processes[InitPID] = &ProcessInfo{PID: InitPID, State: "running"}
+	return pm
 }
 
 // CreateProcess creates a new process with parent relationship
@@ -54,6 +65,18 @@ func (pm *ProcessManager) CreateProcess(pid, ppid int) *ProcessInfo {
 	if ppid > 0 {
 		if parent, ok := pm.processes[ppid]; ok {
 			parent.Children = append(parent.Children, pid)
+
+			// Like fork(), inherit the parent's process group and session
+			info.PGID = parent.PGID
+			info.SID = parent.SID
+			if pg, ok := pm.processGroups[parent.PGID]; ok {
+				pg.mu.Lock()
+				pg.PIDs = append(pg.PIDs, pid)
+				pg.mu.Unlock()
+			}
+			if sess, ok := pm.sessions[parent.SID]; ok {
+				sess.PIDs = append(sess.PIDs, pid)
+			}
 		}
 	}
 
@@ -104,11 +127,11 @@ func (pm *ProcessManager) GetProcessTree(pid int) []*ProcessInfo {
 
 	var result []*ProcessInfo
 	var traverse func(int)
-	
-	// Recursive traversal
+
+	// Recursive traversal (copies, so callers can't race with updates)
 	traverse = func(p int) {
 		if info, ok := pm.processes[p]; ok {
-			result = append(result, info)
+			result = append(result, info.copy())
 			for _, child := range info.Children {
 				traverse(child)
 			}
@@ -119,12 +142,106 @@ func (pm *ProcessManager) GetProcessTree(pid int) []*ProcessInfo {
 	return result
 }
 
-// TerminateProcess marks a process as terminated
+// GetProcess returns a copy of a process's info
+func (pm *ProcessManager) GetProcess(pid int) (ProcessInfo, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	info, ok := pm.processes[pid]
+	if !ok {
+		return ProcessInfo{}, false
+	}
+	return *info.copy(), true
+}
+
+// copy returns a snapshot of the process info
+func (info *ProcessInfo) copy() *ProcessInfo {
+	c := *info
+	c.Children = append([]int(nil), info.Children...)
+	return &c
+}
+
+// Count returns the number of processes in the table, including init and zombies
+func (pm *ProcessManager) Count() int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return len(pm.processes)
+}
+
+// SetProcessState updates a process's state (e.g. "stopped" after SIGSTOP)
+func (pm *ProcessManager) SetProcessState(pid int, state string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if info, ok := pm.processes[pid]; ok && !info.exited() {
+		info.State = state
+	}
+}
+
+// GetProcessGroup returns a copy of the PIDs in a process group
+func (pm *ProcessManager) GetProcessGroup(groupID int) ([]int, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	pg, ok := pm.processGroups[groupID]
+	if !ok {
+		return nil, false
+	}
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+	return append([]int(nil), pg.PIDs...), true
+}
+
+// RemoveProcess drops a process that never ran (its admission was undone)
+// and frees its PID
+func (pm *ProcessManager) RemoveProcess(pid int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.reapLocked(pid)
+}
+
+// TerminateProcess marks a process as terminated without an exit status
+// (e.g. its workload was deleted); its children are reparented to init
 func (pm *ProcessManager) TerminateProcess(pid int) {
+	pm.exit(pid, "terminated", -1)
+}
+
+// ExitProcess turns a process into a zombie holding its exit status until a
+// wait reaps it; its children are reparented to init
+func (pm *ProcessManager) ExitProcess(pid int, exitCode int64) {
+	pm.exit(pid, "zombie", exitCode)
+}
+
+func (pm *ProcessManager) exit(pid int, state string, exitCode int64) {
+	if pid == InitPID {
+		return
+	}
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	if info, ok := pm.processes[pid]; ok {
-		info.State = "terminated"
+	info, ok := pm.processes[pid]
+	if !ok || info.exited() {
+		return
 	}
+	info.State = state
+	info.ExitCode = exitCode
+	info.ExitedAt = time.Now()
+
+	// Orphans are adopted by init
+	initProc := pm.processes[InitPID]
+	for _, child := range info.Children {
+		if c, ok := pm.processes[child]; ok {
+			c.PPID = InitPID
+			initProc.Children = append(initProc.Children, child)
+		}
+	}
+	info.Children = nil
+
+	// Wake up blocked waiters
+	close(pm.exited)
+	pm.exited = make(chan struct{})
+}
+
+// exited reports whether the process has exited (zombie or terminated)
+func (info *ProcessInfo) exited() bool {
+	return info.State == "zombie" || info.State == "terminated"
 }
@@ -94,3 +94,38 @@ func TestProcessManagerTerminateProcess(t *testing.T) {
 		t.Errorf("Expected state terminated, got %s", info.State)
 	}
 }
+
+// TestProcessManagerChildInheritsGroup tests fork-like group and session inheritance
+func TestProcessManagerChildInheritsGroup(t *testing.T) {
+	pm := NewProcessManager()
+
+	pm.CreateProcess(1001, 0)
+	pg := pm.CreateProcessGroup(1001, 1001)
+	child := pm.CreateProcess(1002, 1001)
+
+	if child.PGID != 1001 {
+		t.Errorf("Expected child PGID 1001, got %d", child.PGID)
+	}
+	if len(pg.PIDs) != 2 {
+		t.Errorf("Expected 2 PIDs in group, got %d", len(pg.PIDs))
+	}
+}
+
+// TestProcessManagerGetProcess tests that lookups return copies
+func TestProcessManagerGetProcess(t *testing.T) {
+	pm := NewProcessManager()
+	pm.CreateProcess(1001, 0)
+
+	info, ok := pm.GetProcess(1001)
+	if !ok {
+		t.Fatal("Expected process to be found")
+	}
+	info.State = "terminated"
+
+	if pm.processes[1001].State != "running" {
+		t.Error("Expected GetProcess to return a copy")
+	}
+	if _, ok := pm.GetProcess(9999); ok {
+		t.Error("Expected unknown PID to not be found")
+	}
+}
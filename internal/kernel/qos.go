@@ -0,0 +1,22 @@
+package kernel
+
+// QoSClass is the quality-of-service class of a workload (like Kubernetes)
+type QoSClass string
+
+const (
+	QoSGuaranteed QoSClass = "Guaranteed" // Requests equal limits for memory and CPU
+	QoSBurstable  QoSClass = "Burstable"  // Some request set, may burst up to its limit
+	QoSBestEffort QoSClass = "BestEffort" // No requests or limits, evicted first
+)
+
+// ClassifyQoS derives the QoS class from a workload's requests and limits
+func ClassifyQoS(w *Workload) QoSClass {
+	if w.MemoryMB == 0 && w.MemoryLimitMB == 0 && w.CPUMillicores == 0 && w.CPULimitMillicores == 0 {
+		return QoSBestEffort
+	}
+	if w.MemoryMB > 0 && w.EffectiveMemoryLimitMB() == w.MemoryMB &&
+		w.CPUMillicores > 0 && w.EffectiveCPULimitMillicores() == w.CPUMillicores {
+		return QoSGuaranteed
+	}
+	return QoSBurstable
+}
@@ -0,0 +1,233 @@
+package kernel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// OrphanPolicy decides what happens to labelled containers the store doesn't account for
+type OrphanPolicy string
+
+const (
+	OrphanAdopt  OrphanPolicy = "adopt"  // Track running orphans as new workloads
+	OrphanRemove OrphanPolicy = "remove" // Force-remove them
+	OrphanIgnore OrphanPolicy = "ignore" // Leave them alone
+)
+
+// ContainerInventory lists and removes CKM's containers (implemented by runtime.DockerRuntime)
+type ContainerInventory interface {
+	ListManagedContainers(ctx context.Context) ([]runtime.ManagedContainer, error)
+	RemoveContainer(ctx context.Context, containerID string) error
+}
+
+// Reattacher resumes tracking surviving containers (implemented by Executor)
+type Reattacher interface {
+	Reattach(ctx context.Context, w *Workload)
+	RecordExit(ctx context.Context, w *Workload, containerID string, exitCode int64)
+}
+
+// ReconcileReport counts what a startup reconciliation did
+type ReconcileReport struct {
+	Reattached int // Still running, being waited on again
+	Recorded   int // Finished while the kernel was down; exit codes recorded
+	Adopted    int // Unknown but running, now tracked as workloads
+	Removed    int // Unknown or stale, removed
+	Ignored    int // Unknown, left alone
+	Lost       int // Running in the store but without a container; marked failed
+}
+
+// Reconciler matches labelled containers against the store after a restart
+type Reconciler struct {
+	store     Store
+	cgroups   *CGroupManager
+	inventory ContainerInventory
+	attacher  Reattacher
+	policy    OrphanPolicy
+	logger    *zap.Logger
+}
+
+// NewReconciler creates a reconciler applying policy to unknown containers
+func NewReconciler(store Store, cgroups *CGroupManager, inventory ContainerInventory, attacher Reattacher, policy OrphanPolicy, logger *zap.Logger) *Reconciler {
+	return &Reconciler{
+		store:     store,
+		cgroups:   cgroups,
+		inventory: inventory,
+		attacher:  attacher,
+		policy:    policy,
+		logger:    logger,
+	}
+}
+
+// ContainerLabels returns the labels identifying a workload's container
+func ContainerLabels(w *Workload) map[string]string {
+	return map[string]string{
+		runtime.LabelManaged:    "true",
+		runtime.LabelWorkloadID: w.ID,
+		runtime.LabelPID:        strconv.Itoa(w.PID),
+		runtime.LabelSpecHash:   SpecHash(w),
+	}
+}
+
+// SpecHash fingerprints what a workload's container was created from, so a
+// container left from an older spec with the same ID isn't mistaken for it.
+// Only fields that can't change after creation count: CPU shares follow the
+// priority when there's no CPU request, and priority can be patched while running.
+func SpecHash(w *Workload) string {
+	limits := containerLimits(w)
+	if w.CPUMillicores == 0 {
+		limits.CPUShares = 0
+	}
+	spec, _ := json.Marshal(struct {
+		Image   string
+		Command []string
+		Limits  runtime.ResourceLimits
+	}{w.Image, w.Command, limits})
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Reconcile re-attaches running containers, records exits that happened
+// while the kernel was down and applies the orphan policy to the rest.
+// Run it before resuming the queue so matched workloads aren't resubmitted.
+func (r *Reconciler) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	var report ReconcileReport
+	containers, err := r.inventory.ListManagedContainers(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list containers: %w", err)
+	}
+
+	settled := make(map[string]bool) // Workloads matched to a container
+	for _, c := range containers {
+		w, ok := r.match(c)
+		switch {
+		case ok && c.Running:
+			r.reattach(ctx, w, c)
+			settled[w.ID] = true
+			report.Reattached++
+		case ok:
+			settled[w.ID] = true
+			r.store.Mutate(w.ID, func(cur *Workload) { cur.ContainerID = c.ID })
+			r.attacher.RecordExit(ctx, w, c.ID, c.ExitCode)
+			report.Recorded++
+			r.logger.Info("Recorded exit of container that finished while down",
+				zap.String("workload", w.ID), zap.Int64("exit_code", c.ExitCode))
+		default:
+			r.orphan(ctx, c, settled, &report)
+		}
+	}
+
+	// Workloads the store shows running without a container were lost with it
+	for _, w := range r.store.GetAll() {
+		if !settled[w.ID] && (w.Status == StatusScheduled || w.Status == StatusCreating || w.Status == StatusRunning) {
+			r.store.SetStatus(w.ID, StatusFailed, "KernelRestart", "container was gone after a kernel restart")
+			report.Lost++
+		}
+	}
+
+	r.logger.Info("Reconciled containers with the store",
+		zap.Int("reattached", report.Reattached),
+		zap.Int("recorded", report.Recorded),
+		zap.Int("adopted", report.Adopted),
+		zap.Int("removed", report.Removed),
+		zap.Int("ignored", report.Ignored),
+		zap.Int("lost", report.Lost))
+	return report, nil
+}
+
+// match finds the unfinished workload a container belongs to
+func (r *Reconciler) match(c runtime.ManagedContainer) (*Workload, bool) {
+	w, ok := r.store.Get(c.WorkloadID)
+	if !ok || w.PID != c.PID || SpecHash(w) != c.SpecHash {
+		return nil, false
+	}
+	if w.ContainerID != "" && w.ContainerID != c.ID {
+		return nil, false
+	}
+	switch w.Status {
+	case StatusRunning, StatusInterrupted:
+		return w, true
+	case StatusCreating:
+		// Killed between starting the container and recording it; one that
+		// never started is an orphan and the workload is lost
+		return w, c.Running
+	}
+	return nil, false
+}
+
+// reattach restores a running workload's PID, reservation and status and waits on it again
+func (r *Reconciler) reattach(ctx context.Context, w *Workload, c runtime.ManagedContainer) {
+	if !PIDs().Reserve(w.PID, w.Namespace, w.NSPID) {
+		r.logger.Warn("PID already taken, keeping it for the reattached workload", zap.String("workload", w.ID), zap.Int("pid", w.PID))
+	}
+	if err := r.cgroups.AllocateResources(w.ID, w.Resources()); err != nil {
+		r.logger.Warn("Reattached workload exceeds current capacity", zap.String("workload", w.ID), zap.Error(err))
+	}
+	w.ContainerID = c.ID
+	w.Status, w.Reason, w.Message = StatusRunning, "Reattached", "container survived a kernel restart"
+	r.store.Mutate(w.ID, func(cur *Workload) { cur.ContainerID = w.ContainerID })
+	if err := r.store.SetStatus(w.ID, w.Status, w.Reason, w.Message); err != nil {
+		r.logger.Warn("Failed to mark reattached workload running", zap.String("workload", w.ID), zap.Error(err))
+	}
+	r.attacher.Reattach(ctx, w)
+	r.logger.Info("Reattached running container", zap.String("workload", w.ID), zap.String("container", c.ID[:12]))
+}
+
+// orphan applies the policy to a container the store doesn't account for.
+// Stopped orphans are always removed; there's nothing left to adopt.
+func (r *Reconciler) orphan(ctx context.Context, c runtime.ManagedContainer, settled map[string]bool, report *ReconcileReport) {
+	policy := r.policy
+	if !c.Running && policy == OrphanAdopt {
+		policy = OrphanRemove
+	}
+
+	switch policy {
+	case OrphanAdopt:
+		w := r.adopt(c)
+		r.attacher.Reattach(ctx, w)
+		settled[w.ID] = true
+		report.Adopted++
+		r.logger.Info("Adopted orphaned container", zap.String("workload", w.ID), zap.String("container", c.ID[:12]))
+	case OrphanRemove:
+		if err := r.inventory.RemoveContainer(ctx, c.ID); err != nil {
+			r.logger.Warn("Failed to remove orphaned container", zap.String("container", c.ID[:12]), zap.Error(err))
+			return
+		}
+		report.Removed++
+	default:
+		report.Ignored++
+		r.logger.Info("Ignoring orphaned container", zap.String("container", c.ID[:12]), zap.String("workload", c.WorkloadID))
+	}
+}
+
+// adopt registers a running orphan as a workload with a fresh PID
+func (r *Reconciler) adopt(c runtime.ManagedContainer) *Workload {
+	id := c.WorkloadID
+	if id == "" {
+		id = "adopted-" + c.ID[:12]
+	} else if _, exists := r.store.Get(id); exists {
+		id = fmt.Sprintf("%s-adopted-%s", id, c.ID[:12])
+	}
+
+	w := &Workload{
+		ID:          id,
+		Type:        "container",
+		Image:       c.Image,
+		QoSClass:    QoSBestEffort, // Its requests are unknown, so it reserves nothing
+		Status:      StatusRunning,
+		Reason:      "Adopted",
+		Message:     "orphaned container adopted after a kernel restart",
+		ContainerID: c.ID,
+	}
+	if pid, nspid, err := AllocPID(""); err == nil {
+		w.PID, w.NSPID = pid, nspid
+	}
+	r.store.Add(w)
+	return w
+}
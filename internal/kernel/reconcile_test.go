@@ -0,0 +1,147 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	"ckm/internal/runtime"
+	"go.uber.org/zap"
+)
+
+// fakeInventory serves a fixed container list and records removals
+type fakeInventory struct {
+	containers []runtime.ManagedContainer
+	removed    []string
+}
+
+func (f *fakeInventory) ListManagedContainers(ctx context.Context) ([]runtime.ManagedContainer, error) {
+	return f.containers, nil
+}
+
+func (f *fakeInventory) RemoveContainer(ctx context.Context, containerID string) error {
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+// fakeAttacher records reattached workloads and recorded exits
+type fakeAttacher struct {
+	reattached []string
+	exits      map[string]int64
+}
+
+func (f *fakeAttacher) Reattach(ctx context.Context, w *Workload) {
+	f.reattached = append(f.reattached, w.ID)
+}
+
+func (f *fakeAttacher) RecordExit(ctx context.Context, w *Workload, containerID string, exitCode int64) {
+	f.exits[w.ID] = exitCode
+}
+
+// labelled returns a container carrying a workload's labels
+func labelled(id string, w *Workload, running bool, exitCode int64) runtime.ManagedContainer {
+	return runtime.ManagedContainer{
+		ID:         id,
+		WorkloadID: w.ID,
+		PID:        w.PID,
+		SpecHash:   SpecHash(w),
+		Running:    running,
+		ExitCode:   exitCode,
+	}
+}
+
+func setupReconciler(policy OrphanPolicy, containers func(store Store) []runtime.ManagedContainer) (*Reconciler, Store, *fakeInventory, *fakeAttacher) {
+	store := NewWorkloadStore()
+	running := &Workload{ID: "web", PID: 4001, Image: "nginx", MemoryMB: 64, Status: "running", ContainerID: "aaaaaaaaaaaa01"}
+	finished := &Workload{ID: "batch", PID: 4002, Image: "alpine", Status: "running"}
+	lost := &Workload{ID: "lost", PID: 4003, Image: "alpine", Status: "running"}
+	store.Add(running)
+	store.Add(finished)
+	store.Add(lost)
+
+	inv := &fakeInventory{containers: containers(store)}
+	att := &fakeAttacher{exits: make(map[string]int64)}
+	r := NewReconciler(store, NewCGroupManager(1024), inv, att, policy, zap.NewNop())
+	return r, store, inv, att
+}
+
+// TestReconcileMatches tests reattaching, recording exits and failing lost workloads
+func TestReconcileMatches(t *testing.T) {
+	r, store, _, att := setupReconciler(OrphanIgnore, func(store Store) []runtime.ManagedContainer {
+		web, _ := store.Get("web")
+		batch, _ := store.Get("batch")
+		return []runtime.ManagedContainer{
+			labelled("aaaaaaaaaaaa01", web, true, 0),
+			labelled("bbbbbbbbbbbb02", batch, false, 3),
+		}
+	})
+
+	report, err := r.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Reattached != 1 || report.Recorded != 1 || report.Lost != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(att.reattached) != 1 || att.reattached[0] != "web" || att.exits["batch"] != 3 {
+		t.Errorf("Expected web reattached and batch exit 3, got %v %v", att.reattached, att.exits)
+	}
+	if w, _ := store.Get("lost"); w.Status != "failed" || w.Reason != "KernelRestart" {
+		t.Errorf("Expected lost workload to fail, got %s/%s", w.Status, w.Reason)
+	}
+	if w, _ := store.Get("web"); w.Status != "running" || w.Reason != "Reattached" {
+		t.Errorf("Expected web to stay running, got %s/%s", w.Status, w.Reason)
+	}
+}
+
+// TestReconcileSpecMismatch tests that a container from an older spec is an orphan
+func TestReconcileSpecMismatch(t *testing.T) {
+	r, _, inv, att := setupReconciler(OrphanRemove, func(store Store) []runtime.ManagedContainer {
+		web, _ := store.Get("web")
+		c := labelled("aaaaaaaaaaaa01", web, true, 0)
+		c.SpecHash = "stale"
+		return []runtime.ManagedContainer{c}
+	})
+
+	report, _ := r.Reconcile(context.Background())
+	if report.Removed != 1 || len(inv.removed) != 1 || len(att.reattached) != 0 {
+		t.Errorf("Expected stale container to be removed, got %+v", report)
+	}
+}
+
+// TestReconcilePriorityPatched tests that a priority change while running
+// doesn't make the workload's own container look stale
+func TestReconcilePriorityPatched(t *testing.T) {
+	r, _, inv, att := setupReconciler(OrphanRemove, func(store Store) []runtime.ManagedContainer {
+		web, _ := store.Get("web")
+		c := labelled("aaaaaaaaaaaa01", web, true, 0)
+		store.Mutate("web", func(w *Workload) { w.Priority = 9 })
+		return []runtime.ManagedContainer{c}
+	})
+
+	report, _ := r.Reconcile(context.Background())
+	if report.Reattached != 1 || len(inv.removed) != 0 || len(att.reattached) != 1 {
+		t.Errorf("Expected the container to be reattached, got %+v", report)
+	}
+}
+
+// TestReconcileAdopt tests adopting a running container the store doesn't know
+func TestReconcileAdopt(t *testing.T) {
+	r, store, _, att := setupReconciler(OrphanAdopt, func(Store) []runtime.ManagedContainer {
+		return []runtime.ManagedContainer{
+			{ID: "cccccccccccc03", WorkloadID: "gone", PID: 4999, Image: "redis", Running: true},
+			{ID: "dddddddddddd04", WorkloadID: "gone-too", PID: 4998, Running: false},
+		}
+	})
+
+	report, _ := r.Reconcile(context.Background())
+	if report.Adopted != 1 || report.Removed != 1 {
+		t.Errorf("Expected one adopted and one stopped orphan removed, got %+v", report)
+	}
+	w, ok := store.Get("gone")
+	if !ok || w.Status != "running" || w.ContainerID != "cccccccccccc03" || w.Image != "redis" {
+		t.Errorf("Expected adopted workload, got %+v", w)
+	}
+	if len(att.reattached) != 1 || att.reattached[0] != "gone" {
+		t.Errorf("Expected adopted workload to be waited on, got %v", att.reattached)
+	}
+}
@@ -0,0 +1,77 @@
+package kernel
+
+import (
+	"fmt"
+	"sync"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// ConfigApplier applies a validated config change to one component
+type ConfigApplier func(old, new common.KernelConfig) error
+
+// Reloader re-reads the config file (on SIGHUP) and applies changes in place
+type Reloader struct {
+	path     string
+	current  common.KernelConfig
+	appliers []ConfigApplier
+	logger   *zap.Logger
+	mu       sync.Mutex
+}
+
+// NewReloader creates a reloader starting from the config already in effect
+func NewReloader(path string, current common.KernelConfig, logger *zap.Logger) *Reloader {
+	return &Reloader{path: path, current: current, logger: logger}
+}
+
+// OnReload registers a component to update when the config changes
+func (r *Reloader) OnReload(fn ConfigApplier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.appliers = append(r.appliers, fn)
+}
+
+// Current returns the config in effect
+func (r *Reloader) Current() common.KernelConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload reads and validates the config file, logs the diff and applies it.
+// An invalid file is rejected and the running config is left untouched.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := common.LoadKernelConfig(r.path)
+	if err != nil {
+		r.logger.Error("Config reload rejected", zap.String("path", r.path), zap.Error(err))
+		common.ConfigReloadsTotal.WithLabelValues("rejected").Inc()
+		return err
+	}
+
+	diff := common.DiffKernelConfig(r.current, next)
+	if len(diff) == 0 {
+		r.logger.Info("Config reloaded, nothing changed", zap.String("path", r.path))
+		common.ConfigReloadsTotal.WithLabelValues("unchanged").Inc()
+		return nil
+	}
+
+	var errs []error
+	for _, apply := range r.appliers {
+		if err := apply(r.current, next); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.current = next
+	r.logger.Info("Config reloaded", zap.String("path", r.path), zap.Strings("changes", diff))
+
+	if len(errs) > 0 {
+		common.ConfigReloadsTotal.WithLabelValues("partial").Inc()
+		return fmt.Errorf("config reload partially applied: %v", errs)
+	}
+	common.ConfigReloadsTotal.WithLabelValues("applied").Inc()
+	return nil
+}
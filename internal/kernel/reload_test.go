@@ -0,0 +1,63 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// TestReloaderApplies tests that a changed config reaches the appliers
+func TestReloaderApplies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ckm.yaml")
+	os.WriteFile(path, []byte("worker_pool_size: 4\n"), 0644)
+
+	r := NewReloader(path, common.DefaultKernelConfig(), zap.NewNop())
+	var applied common.KernelConfig
+	r.OnReload(func(old, new common.KernelConfig) error {
+		applied = new
+		return nil
+	})
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if applied.WorkerPoolSize != 4 || r.Current().WorkerPoolSize != 4 {
+		t.Errorf("Expected worker pool size 4, got applied=%d current=%d", applied.WorkerPoolSize, r.Current().WorkerPoolSize)
+	}
+}
+
+// TestReloaderRejectsInvalid tests that an invalid file leaves the config unchanged
+func TestReloaderRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ckm.yaml")
+	os.WriteFile(path, []byte("worker_pool_size: 4\nscheduler: lottery\n"), 0644)
+
+	r := NewReloader(path, common.DefaultKernelConfig(), zap.NewNop())
+	called := false
+	r.OnReload(func(old, new common.KernelConfig) error {
+		called = true
+		return nil
+	})
+
+	if err := r.Reload(); err == nil {
+		t.Fatal("Expected invalid config to be rejected")
+	}
+	if called || !reflect.DeepEqual(r.Current(), common.DefaultKernelConfig()) {
+		t.Error("Expected rejected config not to be applied")
+	}
+}
+
+// TestNewSchedulerByName tests scheduler selection from config
+func TestNewSchedulerByName(t *testing.T) {
+	for _, name := range common.SchedulerNames {
+		if _, err := NewSchedulerByName(name, 0); err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+	if _, err := NewSchedulerByName("lottery", 0); err == nil {
+		t.Error("Expected unknown scheduler to fail")
+	}
+}
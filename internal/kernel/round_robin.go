@@ -2,11 +2,14 @@ package kernel
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 )
 
 // RoundRobinScheduler schedules workloads in round-robin fashion with time quantum
 type RoundRobinScheduler struct {
+	mu      sync.Mutex // Guards queue; Add runs on concurrent API requests
 	queue   []Workload
 	quantum time.Duration
 }
@@ -22,11 +25,29 @@ func NewRoundRobinScheduler(quantum time.Duration) *RoundRobinScheduler {
 // Add adds a workload to the queue
 func (s *RoundRobinScheduler) Add(w Workload) {
 	fmt.Printf("[RR] Queued: %s\n", w.ID)
-	w.Status = "waiting"
+	w.Status = StatusQueued
+	s.mu.Lock()
 	s.queue = append(s.queue, w)
+	s.mu.Unlock()
 }
 
 // Run is a no-op; actual execution happens via Executor
 func (s *RoundRobinScheduler) Run() {
 	// Workloads are executed asynchronously by the Executor
 }
+
+// Snapshot returns a copy of the queue
+func (s *RoundRobinScheduler) Snapshot() []Workload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Workload(nil), s.queue...)
+}
+
+// Remove takes a dispatched workload off the queue
+func (s *RoundRobinScheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.queue)
+	s.queue = slices.DeleteFunc(s.queue, func(w Workload) bool { return w.ID == id })
+	return len(s.queue) < n
+}
@@ -1,26 +1,94 @@
 package kernel
 
 import (
-	"sync"
+	"fmt"
+	"maps"
 	"time"
 )
 
 // Workload is the core unit handled by all schedulers
 type Workload struct {
-	ID          string        // Unique identifier
-	PID         int           // Process ID
-	Type        string        // "container", "task", "vm"
-	CPUTime     time.Duration // Expected execution time
-	MemoryMB    int           // Memory limit in MB
-	Status      string        // "waiting", "running", "done", "failed"
-	Priority    int           // Scheduling priority (lower = higher)
-	FilePath    string        // Source file path
-	Image       string        // Docker image name
-	Command     []string      // Container command
-	CreatedAt   time.Time     // Creation timestamp
-	StartedAt   time.Time     // Start timestamp
-	CompletedAt time.Time     // Completion timestamp
-	ContainerID string        // Docker container ID
+	ID                 string          // Unique identifier
+	PID                int             // Global process ID
+	Namespace          string          // PID namespace (tenant), "" for the root namespace
+	NSPID              int             // Process ID inside Namespace
+	PPID               int             // Parent workload's PID, 0 for top-level workloads
+	Type               string          // "container", "task", "vm"
+	CPUTime            time.Duration   // Expected execution time
+	MemoryMB           int             // Memory request in MB (reserved)
+	MemoryLimitMB      int             // Memory limit in MB, 0 = same as request
+	CPUMillicores      int64           // CPU request in millicores (1000 = 1 CPU)
+	CPULimitMillicores int64           // CPU limit in millicores, 0 = same as request
+	PIDsLimit          int64           // Maximum number of processes
+	DiskMB             int64           // Ephemeral storage limit in MB
+	QoSClass           QoSClass        // Derived from requests and limits
+	Status             string          // One of the Status* lifecycle constants
+	Reason             string          // Machine-readable cause of the last status change
+	Message            string          // Human-readable detail for Reason
+	ExitCode           int64           // Container exit code once finished
+	OOMRetry           *OOMRetryPolicy // Resubmit with more memory after an OOM kill (opt-in)
+	RetryOf            string          // ID of the workload this one retries
+	Attempt            int             // Retry attempt number, 0 for the original
+	Priority           int             // Scheduling priority (lower = higher)
+	FilePath           string          // Source file path
+	Image              string          // Docker image name
+	Command            []string        // Container command
+	CreatedAt          time.Time       // Creation timestamp
+	StartedAt          time.Time       // Start timestamp
+	CompletedAt        time.Time       // Completion timestamp
+	ContainerID        string          // Docker container ID
+	Signals            []SignalRecord  // Signals delivered through the API
+	History            []Transition    // Every status change, oldest first
+	ResourceVersion    uint64          // Store version of the last change to this workload
+
+	// Key/value pairs for selecting workloads, e.g. env=prod
+	Labels map[string]string
+	// Seconds a finished workload is kept before garbage collection; nil uses the global default
+	TTLSecondsAfterFinished *int
+}
+
+// Clone returns a copy that shares no mutable state with w
+func (w *Workload) Clone() *Workload {
+	c := *w
+	c.Command = append([]string(nil), w.Command...)
+	c.Signals = append([]SignalRecord(nil), w.Signals...)
+	c.History = append([]Transition(nil), w.History...)
+	c.Labels = maps.Clone(w.Labels)
+	if w.OOMRetry != nil {
+		policy := *w.OOMRetry
+		c.OOMRetry = &policy
+	}
+	if w.TTLSecondsAfterFinished != nil {
+		ttl := *w.TTLSecondsAfterFinished
+		c.TTLSecondsAfterFinished = &ttl
+	}
+	return &c
+}
+
+// Resources returns the resources this workload reserves from the CGroupManager
+func (w *Workload) Resources() Resources {
+	return Resources{
+		MemoryMB:      int64(w.MemoryMB),
+		CPUMillicores: w.CPUMillicores,
+		PIDs:          w.PIDsLimit,
+		DiskMB:        w.DiskMB,
+	}
+}
+
+// EffectiveMemoryLimitMB returns the memory a workload may burst up to
+func (w *Workload) EffectiveMemoryLimitMB() int {
+	if w.MemoryLimitMB > 0 {
+		return w.MemoryLimitMB
+	}
+	return w.MemoryMB
+}
+
+// EffectiveCPULimitMillicores returns the CPU a workload may burst up to
+func (w *Workload) EffectiveCPULimitMillicores() int64 {
+	if w.CPULimitMillicores > 0 {
+		return w.CPULimitMillicores
+	}
+	return w.CPUMillicores
 }
 
 // Scheduler is the interface implemented by all strategies (FIFO, RR, etc.)
@@ -29,17 +97,46 @@ type Scheduler interface {
 	Run()
 }
 
-// --- PID Generation (Thread-Safe) ---
+// QueueSnapshotter is implemented by schedulers that can report their queue
+type QueueSnapshotter interface {
+	Snapshot() []Workload
+}
 
-var (
-	pidCounter = 1000
-	pidMutex   sync.Mutex
-)
+// QueueSnapshot returns a scheduler's queued workloads, or nil if it can't report them
+func QueueSnapshot(s Scheduler) []Workload {
+	if qs, ok := s.(QueueSnapshotter); ok {
+		return qs.Snapshot()
+	}
+	return nil
+}
+
+// QueueRemover is implemented by schedulers that drop workloads once they're dispatched
+type QueueRemover interface {
+	Remove(id string) bool
+}
+
+// Dequeue takes a workload off a scheduler's queue and reports whether it was there
+func Dequeue(s Scheduler, id string) bool {
+	if qr, ok := s.(QueueRemover); ok {
+		return qr.Remove(id)
+	}
+	return false
+}
 
-// NextPID returns a globally unique process ID (like a real OS)
-func NextPID() int {
-	pidMutex.Lock()
-	defer pidMutex.Unlock()
-	pidCounter++
-	return pidCounter
-}
\ No newline at end of file
+// NewSchedulerByName creates a scheduler from its config name (see common.SchedulerNames)
+func NewSchedulerByName(name string, quantum time.Duration) (Scheduler, error) {
+	switch name {
+	case "fifo":
+		return NewFIFOScheduler(), nil
+	case "round_robin":
+		return NewRoundRobinScheduler(quantum), nil
+	case "priority":
+		return NewPriorityScheduler(), nil
+	case "fair":
+		return NewFairScheduler(quantum), nil
+	case "multilevel":
+		return NewMultilevelScheduler(NewFIFOScheduler(), NewRoundRobinScheduler(quantum)), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduler %q", name)
+	}
+}
@@ -1,20 +1,12 @@
 package kernel
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
 
-// TestNextPID tests PID generation
-func TestNextPID(t *testing.T) {
-	pid1 := NextPID()
-	pid2 := NextPID()
-
-	if pid2 <= pid1 {
-		t.Errorf("Expected pid2 > pid1, got pid1=%d, pid2=%d", pid1, pid2)
-	}
-}
-
 // TestFIFOSchedulerAdd tests FIFO scheduler add operation
 func TestFIFOSchedulerAdd(t *testing.T) {
 	s := NewFIFOScheduler()
@@ -84,3 +76,34 @@ func TestMultilevelSchedulerRouting(t *testing.T) {
 		t.Errorf("Expected task queue length 1, got %d", len(taskSched.queue))
 	}
 }
+
+// TestSchedulerQueueConcurrent tests that concurrent adds aren't lost and
+// dispatched workloads leave the queue
+func TestSchedulerQueueConcurrent(t *testing.T) {
+	for _, name := range []string{"fifo", "round_robin", "priority", "fair", "multilevel"} {
+		s, _ := NewSchedulerByName(name, time.Second)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				s.Add(Workload{ID: fmt.Sprintf("w%d", i), Type: []string{"task", "vm"}[i%2]})
+			}()
+			go func() {
+				defer wg.Done()
+				QueueSnapshot(s)
+			}()
+		}
+		wg.Wait()
+		if n := len(QueueSnapshot(s)); n != 50 {
+			t.Errorf("%s: expected 50 queued, got %d", name, n)
+		}
+
+		if !Dequeue(s, "w7") || Dequeue(s, "w7") {
+			t.Errorf("%s: expected w7 to be dequeued once", name)
+		}
+		if n := len(QueueSnapshot(s)); n != 49 {
+			t.Errorf("%s: expected 49 queued after dispatch, got %d", name, n)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Session states
+const (
+	SessionActive    = "active"
+	SessionSuspended = "suspended"
+	SessionClosed    = "closed"
+)
+
+var (
+	ErrNoSuchSession = errors.New("session not found")
+	ErrSessionClosed = errors.New("session is closed")
+	ErrNotInSession  = errors.New("process group is not in session")
+)
+
+// Session groups every job an interactive user launches (like a login session)
+type Session struct {
+	ID             int       `json:"sid"`
+	User           string    `json:"user"`
+	State          string    `json:"state"`
+	ForegroundPGID int       `json:"foreground_pgid"` // Job attached to the user, 0 if none
+	PIDs           []int     `json:"pids"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// copy returns a snapshot of the session
+func (s *Session) copy() Session {
+	c := *s
+	c.PIDs = append([]int(nil), s.PIDs...)
+	return c
+}
+
+// CreateSession starts a new session, like setsid()
+func (pm *ProcessManager) CreateSession(sid int, user string) Session {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	sess := &Session{
+		ID:        sid,
+		User:      user,
+		State:     SessionActive,
+		CreatedAt: time.Now(),
+	}
+	pm.sessions[sid] = sess
+	return sess.copy()
+}
+
+// GetSession returns a copy of a session
+func (pm *ProcessManager) GetSession(sid int) (Session, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	sess, ok := pm.sessions[sid]
+	if !ok {
+		return Session{}, false
+	}
+	return sess.copy(), true
+}
+
+// JoinSession moves a process and its descendants into a session
+func (pm *ProcessManager) JoinSession(sid, pid int) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	sess, ok := pm.sessions[sid]
+	if !ok {
+		return ErrNoSuchSession
+	}
+	if sess.State == SessionClosed {
+		return ErrSessionClosed
+	}
+
+	var join func(int)
+	join = func(p int) {
+		info, ok := pm.processes[p]
+		if !ok {
+			return
+		}
+		info.SID = sid
+		sess.PIDs = append(sess.PIDs, p)
+		for _, child := range info.Children {
+			join(child)
+		}
+	}
+	join(pid)
+	return nil
+}
+
+// SetForeground makes a process group the session's foreground job, like tcsetpgrp()
+func (pm *ProcessManager) SetForeground(sid, pgid int) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	sess, ok := pm.sessions[sid]
+	if !ok {
+		return ErrNoSuchSession
+	}
+	if pgid != 0 && !pm.groupInSessionLocked(sid, pgid) {
+		return ErrNotInSession
+	}
+	sess.ForegroundPGID = pgid
+	return nil
+}
+
+// SetSessionState updates a session's state. Closing a session with no
+// members left removes it.
+func (pm *ProcessManager) SetSessionState(sid int, state string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if sess, ok := pm.sessions[sid]; ok {
+		sess.State = state
+		pm.releaseSessionLocked(sid)
+	}
+}
+
+// releaseSessionLocked removes a closed session once its last member is reaped
+// and frees its ID, which was allocated as a PID
+func (pm *ProcessManager) releaseSessionLocked(sid int) {
+	sess, ok := pm.sessions[sid]
+	if !ok || sess.State != SessionClosed || len(sess.PIDs) > 0 {
+		return
+	}
+	delete(pm.sessions, sid)
+	ReleasePID(sid)
+}
+
+// SessionGroups returns the process groups with live members in a session
+func (pm *ProcessManager) SessionGroups(sid int) []int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	sess, ok := pm.sessions[sid]
+	if !ok {
+		return nil
+	}
+	seen := make(map[int]bool)
+	var groups []int
+	for _, pid := range sess.PIDs {
+		info, ok := pm.processes[pid]
+		if !ok || info.exited() || info.PGID == 0 || seen[info.PGID] {
+			continue
+		}
+		seen[info.PGID] = true
+		groups = append(groups, info.PGID)
+	}
+	return groups
+}
+
+// groupInSessionLocked reports whether any live session member belongs to pgid
+func (pm *ProcessManager) groupInSessionLocked(sid, pgid int) bool {
+	for _, pid := range pm.sessions[sid].PIDs {
+		if info, ok := pm.processes[pid]; ok && info.PGID == pgid && !info.exited() {
+			return true
+		}
+	}
+	return false
+}
+
+// groupHasState reports whether any process in a group is in the given state
+func (pm *ProcessManager) groupHasState(pgid int, state string) bool {
+	pids, _ := pm.GetProcessGroup(pgid)
+	for _, pid := range pids {
+		if info, ok := pm.GetProcess(pid); ok && info.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+// HangupSession closes a session and sends SIGHUP to all of its process groups.
+// Stopped groups get SIGCONT first so they can handle the hang-up. Members
+// that haven't started yet are cancelled instead.
+func (d *SignalDeliverer) HangupSession(ctx context.Context, sid int) ([]string, error) {
+	if _, ok := d.processes.GetSession(sid); !ok {
+		return nil, ErrNoSuchSession
+	}
+	// Close first so no job joins after its members are collected
+	d.processes.SetSessionState(sid, SessionClosed)
+	if sess, ok := d.processes.GetSession(sid); ok {
+		d.cancelUnstarted(sess)
+	}
+
+	var delivered []string
+	for _, pgid := range d.processes.SessionGroups(sid) {
+		if d.processes.groupHasState(pgid, "stopped") {
+			_, _ = d.SignalGroup(ctx, pgid, "SIGCONT")
+		}
+		ids, err := d.SignalGroup(ctx, pgid, "SIGHUP")
+		if err != nil && !errors.Is(err, ErrNoSuchGroup) {
+			return delivered, err
+		}
+		delivered = append(delivered, ids...)
+	}
+	return delivered, nil
+}
+
+// cancelUnstarted cancels the session's pending, queued, scheduled and
+// creating workloads, freeing their reservations and ending their processes. The
+// executor drops them when it gets to them.
+func (d *SignalDeliverer) cancelUnstarted(sess Session) {
+	members := make(map[int]bool, len(sess.PIDs))
+	for _, pid := range sess.PIDs {
+		members[pid] = true
+	}
+	message := fmt.Sprintf("session %d was hung up", sess.ID)
+	for _, w := range d.store.GetAll() {
+		if !members[w.PID] {
+			continue
+		}
+		switch w.Status {
+		case StatusPending, StatusQueued, StatusScheduled, StatusCreating:
+		default:
+			continue
+		}
+		if err := d.store.SetStatus(w.ID, StatusCancelled, "SessionHangup", message); err != nil {
+			continue // Started or finished in the meantime
+		}
+		if d.cgroups != nil {
+			d.cgroups.Release(w.ID)
+		}
+		d.processes.TerminateProcess(w.PID)
+		d.logger.Info("Workload cancelled by hang-up", zap.String("workload", w.ID), zap.Int("sid", sess.ID))
+	}
+}
+
+// SuspendSession stops every background job in a session (SIGSTOP)
+func (d *SignalDeliverer) SuspendSession(ctx context.Context, sid int) ([]string, error) {
+	return d.signalBackground(ctx, sid, SessionSuspended, "SIGSTOP", "running")
+}
+
+// ResumeSession continues the background jobs stopped by SuspendSession (SIGCONT)
+func (d *SignalDeliverer) ResumeSession(ctx context.Context, sid int) ([]string, error) {
+	return d.signalBackground(ctx, sid, SessionActive, "SIGCONT", "stopped")
+}
+
+// signalBackground signals background groups that have a process in fromState
+func (d *SignalDeliverer) signalBackground(ctx context.Context, sid int, state, sig, fromState string) ([]string, error) {
+	sess, ok := d.processes.GetSession(sid)
+	if !ok {
+		return nil, ErrNoSuchSession
+	}
+	if sess.State == SessionClosed {
+		return nil, ErrSessionClosed
+	}
+	d.processes.SetSessionState(sid, state)
+
+	var delivered []string
+	for _, pgid := range d.processes.SessionGroups(sid) {
+		if pgid == sess.ForegroundPGID || !d.processes.groupHasState(pgid, fromState) {
+			continue
+		}
+		ids, err := d.SignalGroup(ctx, pgid, sig)
+		if err != nil && !errors.Is(err, ErrNoSuchGroup) {
+			return delivered, err
+		}
+		delivered = append(delivered, ids...)
+	}
+	return delivered, nil
+}
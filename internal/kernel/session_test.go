@@ -0,0 +1,180 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// setupSession creates a session with a foreground job (4001 -> 4002) and a background job (4003)
+func setupSession(t *testing.T) (*WorkloadStore, *ProcessManager, *fakeSignaler, *SignalDeliverer) {
+	t.Helper()
+	store := NewWorkloadStore()
+	pm := NewProcessManager()
+	sig := &fakeSignaler{}
+	d := NewSignalDeliverer(store, pm, sig, zap.NewNop())
+
+	pm.CreateSession(4000, "alice")
+	for _, pid := range []int{4001, 4003} {
+		pm.CreateProcess(pid, 0)
+		pm.CreateProcessGroup(pid, pid)
+		if err := pm.JoinSession(4000, pid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pm.CreateProcess(4002, 4001)
+	pm.SetForeground(4000, 4001)
+
+	store.Add(&Workload{ID: "notebook", PID: 4001, Status: "running", ContainerID: "c1"})
+	store.Add(&Workload{ID: "helper", PID: 4002, Status: "running", ContainerID: "c2"})
+	store.Add(&Workload{ID: "batch", PID: 4003, Status: "running", ContainerID: "c3"})
+	return store, pm, sig, d
+}
+
+// TestSessionMembership tests that children inherit the session
+func TestSessionMembership(t *testing.T) {
+	_, pm, _, _ := setupSession(t)
+
+	info, _ := pm.GetProcess(4002)
+	if info.SID != 4000 {
+		t.Errorf("Expected child in session 4000, got %d", info.SID)
+	}
+	if groups := pm.SessionGroups(4000); len(groups) != 2 {
+		t.Errorf("Expected 2 process groups, got %v", groups)
+	}
+	if err := pm.SetForeground(4000, 9999); !errors.Is(err, ErrNotInSession) {
+		t.Errorf("Expected ErrNotInSession, got %v", err)
+	}
+}
+
+// TestSuspendSessionStopsBackground tests that only background jobs are stopped
+func TestSuspendSessionStopsBackground(t *testing.T) {
+	_, pm, sig, d := setupSession(t)
+
+	stopped, err := d.SuspendSession(context.Background(), 4000)
+	if err != nil {
+		t.Fatalf("Expected suspend to succeed, got %v", err)
+	}
+	if len(stopped) != 1 || stopped[0] != "batch" {
+		t.Errorf("Expected only batch stopped, got %v", stopped)
+	}
+	if sess, _ := pm.GetSession(4000); sess.State != SessionSuspended {
+		t.Errorf("Expected suspended session, got %s", sess.State)
+	}
+
+	sig.calls = nil
+	resumed, _ := d.ResumeSession(context.Background(), 4000)
+	if len(resumed) != 1 || sig.calls[0] != "c3:unpause" {
+		t.Errorf("Expected batch resumed, got %v", sig.calls)
+	}
+}
+
+// TestHangupSession tests SIGHUP cascading to every job
+func TestHangupSession(t *testing.T) {
+	store, pm, _, d := setupSession(t)
+
+	// Admitted and joined, but not yet queued
+	pm.CreateProcess(4006, 0)
+	pm.CreateProcessGroup(4006, 4006)
+	if err := pm.JoinSession(4000, 4006); err != nil {
+		t.Fatal(err)
+	}
+	store.Add(&Workload{ID: "joining", PID: 4006, Status: StatusPending})
+
+	hung, err := d.HangupSession(context.Background(), 4000)
+	if err != nil {
+		t.Fatalf("Expected hang-up to succeed, got %v", err)
+	}
+	if w, _ := store.Get("joining"); w.Status != StatusCancelled {
+		t.Errorf("Expected the pending member cancelled, got %s", w.Status)
+	}
+	if len(hung) != 3 {
+		t.Errorf("Expected 3 workloads hung up, got %v", hung)
+	}
+	w, _ := store.Get("helper")
+	if len(w.Signals) != 1 || w.Signals[0].Signal != "SIGHUP" {
+		t.Errorf("Expected SIGHUP recorded, got %+v", w.Signals)
+	}
+	if err := pm.JoinSession(4000, 4001); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+}
+
+// TestClosedSessionReleasesID tests that a closed session frees its ID once its last member is reaped
+func TestClosedSessionReleasesID(t *testing.T) {
+	pm := NewProcessManager()
+	for _, sid := range []int{4900, 4910} {
+		if !PIDs().Reserve(sid, "", sid) {
+			t.Fatalf("Failed to reserve session ID %d", sid)
+		}
+		pm.CreateSession(sid, "alice")
+	}
+	pm.CreateProcess(4901, 0)
+	if err := pm.JoinSession(4900, 4901); err != nil {
+		t.Fatal(err)
+	}
+
+	pm.SetSessionState(4900, SessionClosed)
+	if _, ok := pm.GetSession(4900); !ok || !PIDs().global.InUse(4900) {
+		t.Fatal("Expected a closed session to stay while it has members")
+	}
+	pm.ExitProcess(4901, 0)
+	pm.ReapOrphans()
+	if _, ok := pm.GetSession(4900); ok || PIDs().global.InUse(4900) {
+		t.Error("Expected the session and its ID released after its last member was reaped")
+	}
+
+	pm.SetSessionState(4910, SessionClosed)
+	if _, ok := pm.GetSession(4910); ok || PIDs().global.InUse(4910) {
+		t.Error("Expected closing an empty session to release it")
+	}
+}
+
+// TestHangupCancelsQueuedMembers tests that a hang-up cancels jobs that haven't started
+func TestHangupCancelsQueuedMembers(t *testing.T) {
+	store, pm, _, d := setupSession(t)
+	cgroups := NewCGroupManager(1024)
+	d.SetCGroupManager(cgroups)
+
+	pm.CreateProcess(4005, 0)
+	pm.CreateProcessGroup(4005, 4005)
+	if err := pm.JoinSession(4000, 4005); err != nil {
+		t.Fatal(err)
+	}
+	waiting := &Workload{ID: "waiting", PID: 4005, MemoryMB: 64, Status: StatusPending}
+	store.Add(waiting)
+	store.SetStatus("waiting", StatusQueued, "Admitted", "")
+	if err := cgroups.AllocateResources("waiting", waiting.Resources()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Admitted and joined, but not yet queued
+	pm.CreateProcess(4006, 0)
+	pm.CreateProcessGroup(4006, 4006)
+	if err := pm.JoinSession(4000, 4006); err != nil {
+		t.Fatal(err)
+	}
+	store.Add(&Workload{ID: "joining", PID: 4006, Status: StatusPending})
+
+	hung, err := d.HangupSession(context.Background(), 4000)
+	if err != nil {
+		t.Fatalf("Expected hang-up to succeed, got %v", err)
+	}
+	if w, _ := store.Get("joining"); w.Status != StatusCancelled {
+		t.Errorf("Expected the pending member cancelled, got %s", w.Status)
+	}
+	if len(hung) != 3 {
+		t.Errorf("Expected only the 3 running workloads hung up, got %v", hung)
+	}
+	if w, _ := store.Get("waiting"); w.Status != StatusCancelled || w.Reason != "SessionHangup" {
+		t.Errorf("Expected the queued member cancelled, got %s %s", w.Status, w.Reason)
+	}
+	if _, ok := cgroups.GetReservation("waiting"); ok {
+		t.Error("Expected the queued member's reservation released")
+	}
+	if info, _ := pm.GetProcess(4005); info.State != "terminated" {
+		t.Errorf("Expected the queued member's process terminated, got %s", info.State)
+	}
+}
@@ -0,0 +1,171 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ckm/internal/common"
+	"go.uber.org/zap"
+)
+
+// SignalRecord is a signal delivered to a workload
+type SignalRecord struct {
+	Signal      string    `json:"signal"`
+	PGID        int       `json:"pgid,omitempty"` // Set when sent to the whole process group
+	DeliveredAt time.Time `json:"delivered_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// ContainerSignaler delivers signals to containers (implemented by Executor)
+type ContainerSignaler interface {
+	KillContainer(ctx context.Context, containerID, signal string) error
+	PauseContainer(ctx context.Context, containerID string) error
+	UnpauseContainer(ctx context.Context, containerID string) error
+}
+
+var (
+	ErrUnsupportedSignal = errors.New("unsupported signal")
+	ErrWorkloadNotFound  = errors.New("workload not found")
+	ErrNotRunning        = errors.New("workload is not running")
+	ErrNoSuchGroup       = errors.New("process group not found")
+)
+
+// supportedSignals lists deliverable signals; SIGSTOP and SIGCONT become pause and unpause
+var supportedSignals = map[string]bool{
+	"SIGTERM": true,
+	"SIGKILL": true,
+	"SIGINT":  true,
+	"SIGUSR1": true,
+	"SIGHUP":  true,
+	"SIGSTOP": true,
+	"SIGCONT": true,
+}
+
+// ParseSignal normalizes a signal name ("term", "SIGTERM") to its SIG-prefixed form
+func ParseSignal(name string) (string, error) {
+	sig := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(sig, "SIG") {
+		sig = "SIG" + sig
+	}
+	if !supportedSignals[sig] {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedSignal, name)
+	}
+	return sig, nil
+}
+
+// SignalDeliverer sends kill-style signals to workloads and process groups
+type SignalDeliverer struct {
+	store     Store
+	processes *ProcessManager
+	signaler  ContainerSignaler
+	cgroups   *CGroupManager // Optional: frees the reservations of cancelled workloads
+	logger    *zap.Logger
+}
+
+// NewSignalDeliverer creates a new signal deliverer
+func NewSignalDeliverer(store Store, processes *ProcessManager, signaler ContainerSignaler, logger *zap.Logger) *SignalDeliverer {
+	return &SignalDeliverer{
+		store:     store,
+		processes: processes,
+		signaler:  signaler,
+		logger:    logger,
+	}
+}
+
+// SetCGroupManager lets hang-ups free the reservations of workloads they cancel
+func (d *SignalDeliverer) SetCGroupManager(cgroups *CGroupManager) {
+	d.cgroups = cgroups
+}
+
+// SignalWorkload delivers a signal to one workload's container
+func (d *SignalDeliverer) SignalWorkload(ctx context.Context, id, signal string) error {
+	sig, err := ParseSignal(signal)
+	if err != nil {
+		return err
+	}
+	w, ok := d.store.Get(id)
+	if !ok {
+		return ErrWorkloadNotFound
+	}
+	return d.deliver(ctx, w, sig, 0)
+}
+
+// SignalGroup delivers a signal to every running workload in a process group,
+// like kill(-pgid, sig). It returns the IDs of the workloads signalled.
+func (d *SignalDeliverer) SignalGroup(ctx context.Context, pgid int, signal string) ([]string, error) {
+	sig, err := ParseSignal(signal)
+	if err != nil {
+		return nil, err
+	}
+	pids, ok := d.processes.GetProcessGroup(pgid)
+	if !ok {
+		return nil, ErrNoSuchGroup
+	}
+
+	members := make(map[int]bool, len(pids))
+	for _, pid := range pids {
+		members[pid] = true
+	}
+
+	var delivered []string
+	var lastErr error
+	for _, w := range d.store.GetAll() {
+		if !members[w.PID] || w.Status != StatusRunning {
+			continue
+		}
+		if err := d.deliver(ctx, w, sig, pgid); err != nil {
+			lastErr = err
+			continue
+		}
+		delivered = append(delivered, w.ID)
+	}
+	if len(delivered) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return delivered, nil
+}
+
+// deliver sends sig to a workload's container and records the attempt on it
+func (d *SignalDeliverer) deliver(ctx context.Context, w *Workload, sig string, pgid int) error {
+	if w.Status != StatusRunning || w.ContainerID == "" {
+		return ErrNotRunning
+	}
+
+	var err error
+	switch sig {
+	case "SIGSTOP":
+		err = d.signaler.PauseContainer(ctx, w.ContainerID)
+	case "SIGCONT":
+		err = d.signaler.UnpauseContainer(ctx, w.ContainerID)
+	default:
+		err = d.signaler.KillContainer(ctx, w.ContainerID, sig)
+	}
+
+	record := SignalRecord{Signal: sig, PGID: pgid, DeliveredAt: time.Now()}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	d.store.Mutate(w.ID, func(cur *Workload) {
+		cur.Signals = append(cur.Signals, record)
+	})
+	if err != nil {
+		d.logger.Warn("Signal delivery failed", zap.String("workload", w.ID), zap.String("signal", sig), zap.Error(err))
+		return err
+	}
+
+	// Mirror job-control state in the process table
+	if d.processes != nil {
+		switch sig {
+		case "SIGSTOP":
+			d.processes.SetProcessState(w.PID, "stopped")
+		case "SIGCONT":
+			d.processes.SetProcessState(w.PID, "running")
+		}
+	}
+	common.SignalsDeliveredTotal.WithLabelValues(sig).Inc()
+	d.logger.Info("Signal delivered", zap.String("workload", w.ID), zap.String("signal", sig), zap.Int("pgid", pgid))
+	return nil
+}
@@ -0,0 +1,98 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeSignaler records container operations
+type fakeSignaler struct {
+	calls []string
+}
+
+func (f *fakeSignaler) KillContainer(ctx context.Context, containerID, signal string) error {
+	f.calls = append(f.calls, containerID+":"+signal)
+	return nil
+}
+
+func (f *fakeSignaler) PauseContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, containerID+":pause")
+	return nil
+}
+
+func (f *fakeSignaler) UnpauseContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, containerID+":unpause")
+	return nil
+}
+
+// TestParseSignal tests signal name normalization
+func TestParseSignal(t *testing.T) {
+	if sig, err := ParseSignal("term"); err != nil || sig != "SIGTERM" {
+		t.Errorf("Expected SIGTERM, got %s (%v)", sig, err)
+	}
+	if _, err := ParseSignal("SIGSEGV"); !errors.Is(err, ErrUnsupportedSignal) {
+		t.Errorf("Expected ErrUnsupportedSignal, got %v", err)
+	}
+}
+
+// TestSignalWorkload tests delivery and recording on a single workload
+func TestSignalWorkload(t *testing.T) {
+	store := NewWorkloadStore()
+	pm := NewProcessManager()
+	sig := &fakeSignaler{}
+	d := NewSignalDeliverer(store, pm, sig, zap.NewNop())
+
+	store.Add(&Workload{ID: "job", PID: 3001, Status: "running", ContainerID: "c1"})
+	store.Add(&Workload{ID: "queued", PID: 3002, Status: "queued"})
+	pm.CreateProcess(3001, 0)
+
+	if err := d.SignalWorkload(context.Background(), "job", "SIGSTOP"); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if len(sig.calls) != 1 || sig.calls[0] != "c1:pause" {
+		t.Errorf("Expected SIGSTOP to pause, got %v", sig.calls)
+	}
+
+	w, _ := store.Get("job")
+	if len(w.Signals) != 1 || w.Signals[0].Signal != "SIGSTOP" || w.Signals[0].DeliveredAt.IsZero() {
+		t.Errorf("Expected SIGSTOP recorded, got %+v", w.Signals)
+	}
+	if info, _ := pm.GetProcess(3001); info.State != "stopped" {
+		t.Errorf("Expected process stopped, got %s", info.State)
+	}
+
+	if err := d.SignalWorkload(context.Background(), "queued", "SIGTERM"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning, got %v", err)
+	}
+}
+
+// TestSignalGroup tests kill(-pgid) style delivery
+func TestSignalGroup(t *testing.T) {
+	store := NewWorkloadStore()
+	pm := NewProcessManager()
+	sig := &fakeSignaler{}
+	d := NewSignalDeliverer(store, pm, sig, zap.NewNop())
+
+	store.Add(&Workload{ID: "leader", PID: 3001, Status: "running", ContainerID: "c1"})
+	store.Add(&Workload{ID: "child", PID: 3002, Status: "running", ContainerID: "c2"})
+	store.Add(&Workload{ID: "other", PID: 3003, Status: "running", ContainerID: "c3"})
+	pm.CreateProcess(3001, 0)
+	pm.CreateProcessGroup(3001, 3001)
+	pm.CreateProcess(3002, 3001)
+	pm.CreateProcess(3003, 0)
+
+	delivered, err := d.SignalGroup(context.Background(), 3001, "SIGTERM")
+	if err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if len(delivered) != 2 || len(sig.calls) != 2 {
+		t.Errorf("Expected 2 group members signalled, got %v", delivered)
+	}
+
+	if _, err := d.SignalGroup(context.Background(), 9999, "SIGTERM"); !errors.Is(err, ErrNoSuchGroup) {
+		t.Errorf("Expected ErrNoSuchGroup, got %v", err)
+	}
+}
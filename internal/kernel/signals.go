@@ -22,8 +22,8 @@ func NewSignalHandler() *SignalHandler {
 		handlers: make(map[os.Signal][]func()),
 	}
 	
-	// Register common signals
-	signal.Notify(sh.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	// Register common signals (SIGHUP reloads config, SIGUSR1/SIGQUIT dump diagnostics)
+	signal.Notify(sh.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP, syscall.SIGUSR1)
 	return sh
 }
 
@@ -1,62 +1,212 @@
 package kernel
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
 
+// ErrConflict is returned when a workload changed since the version an update was based on
+var ErrConflict = errors.New("workload was modified; get it again and retry")
+
+// ErrWorkloadExists is returned when creating a workload whose ID is taken
+var ErrWorkloadExists = errors.New("workload already exists")
+
+// ErrStoreNotEmpty is returned when restoring into a store that already holds workloads
+var ErrStoreNotEmpty = errors.New("store already holds workloads")
+
+// Store holds workload state; WorkloadStore keeps it in memory and
+// DurableStore also persists it to disk. Workloads go in and come out as
+// copies, so callers never share memory with the store or each other.
+type Store interface {
+	Add(w *Workload)
+	Create(w *Workload) error
+	Get(id string) (*Workload, bool)
+	GetAll() []*Workload
+	CountByStatus(status string) int
+	Update(id string, status string) error
+	SetStatus(id, status, reason, message string) error
+	Mutate(id string, fn func(*Workload)) bool
+	Delete(id string)
+	CompareAndSwap(w *Workload) (*Workload, error)
+	CompareAndDelete(id string, rv uint64) error
+	ResourceVersion() uint64
+	Snapshot() ([]*Workload, uint64)
+	Restore(workloads []*Workload) error
+	Watch(ctx context.Context, fromRV uint64) (<-chan WatchEvent, error)
+}
+
+// journal records each mutation while the store lock is held, so records
+// are written in the order mutations were applied
+type journal interface {
+	put(w *Workload)
+	delete(id string)
+}
+
 // WorkloadStore manages workload state in memory (thread-safe)
 type WorkloadStore struct {
-	workloads map[string]*Workload
-	mu        sync.RWMutex
+	workloads   map[string]*Workload
+	journal     journal // Optional; set by DurableStore
+	rv          uint64  // Resource version of the latest change
+	history     []WatchEvent
+	historySize int
+	watchers    map[*watcher]struct{}
+	mu          sync.RWMutex
 }
 
 // NewWorkloadStore creates a new workload store
 func NewWorkloadStore() *WorkloadStore {
 	return &WorkloadStore{
-		workloads: make(map[string]*Workload),
+		workloads:   make(map[string]*Workload),
+		historySize: DefaultWatchHistory,
+		watchers:    make(map[*watcher]struct{}),
 	}
 }
 
-// Add stores a workload
+// Add stores a copy of a workload, setting CreatedAt and ResourceVersion on w.
+// It replaces any workload with the same ID, as resubmitting one after a
+// restart does; new workloads go through Create.
 func (s *WorkloadStore) Add(w *Workload) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.addLocked(w)
+}
+
+// Create stores a copy of a new workload like Add, or returns
+// ErrWorkloadExists if its ID is taken
+func (s *WorkloadStore) Create(w *Workload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.workloads[w.ID]; exists {
+		return ErrWorkloadExists
+	}
+	s.addLocked(w)
+	return nil
+}
+
+func (s *WorkloadStore) addLocked(w *Workload) {
 	w.CreatedAt = time.Now()
-	s.workloads[w.ID] = w
+	event := EventModified
+	if _, exists := s.workloads[w.ID]; !exists {
+		event = EventAdded
+	}
+	stored := w.Clone()
+	entry := Transition{To: w.Status, Reason: w.Reason, Message: w.Message, Time: w.CreatedAt}
+	if n := len(stored.History); n > 0 {
+		entry.From = stored.History[n-1].To // Resubmitted after a restart
+	}
+	stored.History = append(stored.History, entry)
+	s.workloads[w.ID] = stored
+	s.recordEvent(event, stored)
+	w.ResourceVersion = stored.ResourceVersion
+	w.History = append([]Transition(nil), stored.History...)
 }
 
-// Get retrieves a workload by ID
+// Get returns a copy of a workload by ID
 func (s *WorkloadStore) Get(id string) (*Workload, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	w, ok := s.workloads[id]
-	return w, ok
+	if !ok {
+		return nil, false
+	}
+	return w.Clone(), true
 }
 
-// GetAll returns all workloads
+// GetAll returns copies of all workloads
 func (s *WorkloadStore) GetAll() []*Workload {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	result := make([]*Workload, 0, len(s.workloads))
 	for _, w := range s.workloads {
-		result = append(result, w)
+		result = append(result, w.Clone())
 	}
 	return result
 }
 
-// Update updates workload status
-func (s *WorkloadStore) Update(id string, status string) bool {
+// Snapshot returns copies of all workloads together with the resource
+// version they were read at
+func (s *WorkloadStore) Snapshot() ([]*Workload, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Workload, 0, len(s.workloads))
+	for _, w := range s.workloads {
+		result = append(result, w.Clone())
+	}
+	return result, s.rv
+}
+
+// Restore adds workloads as they were saved, keeping their CreatedAt and
+// history. It only fills an empty store and returns ErrStoreNotEmpty otherwise.
+func (s *WorkloadStore) Restore(workloads []*Workload) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	w, ok := s.workloads[id]
+	if len(s.workloads) > 0 {
+		return ErrStoreNotEmpty
+	}
+	for _, w := range workloads {
+		stored := w.Clone()
+		migrateStatus(stored)
+		s.workloads[w.ID] = stored
+		s.recordEvent(EventAdded, stored)
+	}
+	return nil
+}
+
+// CountByStatus returns the number of workloads with the given status
+func (s *WorkloadStore) CountByStatus(status string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, w := range s.workloads {
+		if w.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Update moves a workload to a new status
+func (s *WorkloadStore) Update(id string, status string) error {
+	return s.SetStatus(id, status, "", "")
+}
+
+// SetStatus moves a workload to a new status and records the transition
+// with its reason. It returns a *TransitionError if the lifecycle doesn't
+// allow the move.
+func (s *WorkloadStore) SetStatus(id, status, reason, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.workloads[id]
+	if !ok {
+		return ErrWorkloadNotFound
+	}
+	if !CanTransition(cur.Status, status) {
+		return &TransitionError{ID: id, From: cur.Status, To: status}
+	}
+	next := cur.Clone()
+	next.Status, next.Reason, next.Message = status, reason, message
+	s.commitLocked(cur, next)
+	return nil
+}
+
+// Mutate applies fn to a copy of a stored workload while holding the store
+// lock and stores the result. A status change must be one the lifecycle
+// allows; otherwise nothing is stored and Mutate returns false.
+func (s *WorkloadStore) Mutate(id string, fn func(*Workload)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.workloads[id]
 	if !ok {
 		return false
 	}
-	w.Status = status
-	if status == "done" || status == "failed" {
-		w.CompletedAt = time.Now()
+	next := cur.Clone()
+	fn(next)
+	if next.Status != cur.Status && !CanTransition(cur.Status, next.Status) {
+		return false
 	}
+	s.commitLocked(cur, next)
 	return true
 }
 
@@ -64,5 +214,87 @@ func (s *WorkloadStore) Update(id string, status string) bool {
 func (s *WorkloadStore) Delete(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.workloads, id)
+	if w, ok := s.workloads[id]; ok {
+		s.deleteLocked(w)
+	}
+}
+
+// deleteLocked removes a stored workload; the caller holds s.mu
+func (s *WorkloadStore) deleteLocked(w *Workload) {
+	delete(s.workloads, w.ID)
+	s.rv++
+	if s.journal != nil {
+		s.journal.delete(w.ID)
+	}
+	s.publish(WatchEvent{Type: EventDeleted, Object: w.Clone(), ResourceVersion: s.rv})
+}
+
+// CompareAndSwap replaces a workload with a copy of w if it's still at
+// w.ResourceVersion, and returns the stored result with its new version
+func (s *WorkloadStore) CompareAndSwap(w *Workload) (*Workload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.workloads[w.ID]
+	if !ok {
+		return nil, ErrWorkloadNotFound
+	}
+	if cur.ResourceVersion != w.ResourceVersion {
+		return nil, ErrConflict
+	}
+	if w.Status != cur.Status && !CanTransition(cur.Status, w.Status) {
+		return nil, &TransitionError{ID: w.ID, From: cur.Status, To: w.Status}
+	}
+	stored := w.Clone()
+	stored.CreatedAt = cur.CreatedAt
+	s.commitLocked(cur, stored)
+	return stored.Clone(), nil
+}
+
+// CompareAndDelete removes a workload if it's still at rv; rv 0 matches any version
+func (s *WorkloadStore) CompareAndDelete(id string, rv uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.workloads[id]
+	if !ok {
+		return ErrWorkloadNotFound
+	}
+	if rv != 0 && cur.ResourceVersion != rv {
+		return ErrConflict
+	}
+	s.deleteLocked(cur)
+	return nil
+}
+
+// commitLocked stores next in place of cur, recording a status change in
+// its history; the caller holds s.mu and has validated the change
+func (s *WorkloadStore) commitLocked(cur, next *Workload) {
+	if next.Status != cur.Status || next.Reason != cur.Reason || next.Message != cur.Message {
+		now := time.Now()
+		if IsTerminal(next.Status) {
+			next.CompletedAt = now
+		} else {
+			next.CompletedAt = time.Time{}
+		}
+		next.History = append(next.History, Transition{
+			From: cur.Status, To: next.Status, Reason: next.Reason, Message: next.Message, Time: now,
+		})
+	}
+	s.workloads[next.ID] = next
+	s.record(next)
+}
+
+// record journals and publishes a modification; the caller holds s.mu
+func (s *WorkloadStore) record(w *Workload) {
+	s.recordEvent(EventModified, w)
+}
+
+// recordEvent stamps a workload with the next resource version, journals
+// it and publishes the change; the caller holds s.mu
+func (s *WorkloadStore) recordEvent(event EventType, w *Workload) {
+	s.rv++
+	w.ResourceVersion = s.rv
+	if s.journal != nil {
+		s.journal.put(w)
+	}
+	s.publish(WatchEvent{Type: event, Object: w.Clone(), ResourceVersion: s.rv})
 }
@@ -1,6 +1,9 @@
 package kernel
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -53,17 +56,17 @@ func TestWorkloadStoreGetAll(t *testing.T) {
 func TestWorkloadStoreUpdate(t *testing.T) {
 	s := NewWorkloadStore()
 
-	w := &Workload{ID: "test-1", Status: "waiting"}
+	w := &Workload{ID: "test-1", Status: "queued"}
 	s.Add(w)
 
 	// Update status
-	if !s.Update("test-1", "running") {
-		t.Error("Expected update to succeed")
+	if err := s.Update("test-1", "scheduled"); err != nil {
+		t.Errorf("Expected update to succeed, got %v", err)
 	}
 
 	got, _ := s.Get("test-1")
-	if got.Status != "running" {
-		t.Errorf("Expected status running, got %s", got.Status)
+	if got.Status != "scheduled" {
+		t.Errorf("Expected status scheduled, got %s", got.Status)
 	}
 }
 
@@ -75,7 +78,7 @@ func TestWorkloadStoreUpdateCompleted(t *testing.T) {
 	s.Add(w)
 
 	// Update to done
-	s.Update("test-1", "done")
+	s.Update("test-1", "succeeded")
 
 	got, _ := s.Get("test-1")
 	if got.CompletedAt.IsZero() {
@@ -116,3 +119,170 @@ func TestWorkloadStoreConcurrent(t *testing.T) {
 		<-done
 	}
 }
+
+// nextEvent receives one watch event or fails the test
+func nextEvent(t *testing.T, ch <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("Watch closed unexpectedly")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for watch event")
+	}
+	return WatchEvent{}
+}
+
+// TestWorkloadStoreWatch tests typed events with increasing resource versions
+func TestWorkloadStoreWatch(t *testing.T) {
+	s := NewWorkloadStore()
+	s.Add(&Workload{ID: "a", Status: "queued"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := s.Watch(ctx, 0)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if e := nextEvent(t, ch); e.Type != EventAdded || e.Object.ID != "a" || e.ResourceVersion != 1 {
+		t.Errorf("Expected initial ADDED a@1, got %s %s@%d", e.Type, e.Object.ID, e.ResourceVersion)
+	}
+
+	s.SetStatus("a", "scheduled", "", "")
+	s.Delete("a")
+	if e := nextEvent(t, ch); e.Type != EventModified || e.Object.Status != "scheduled" || e.ResourceVersion != 2 {
+		t.Errorf("Expected MODIFIED scheduled@2, got %s %s@%d", e.Type, e.Object.Status, e.ResourceVersion)
+	}
+	if e := nextEvent(t, ch); e.Type != EventDeleted || e.ResourceVersion != 3 {
+		t.Errorf("Expected DELETED@3, got %s@%d", e.Type, e.ResourceVersion)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+// TestWorkloadStoreWatchResume tests resuming from history and the gone error
+func TestWorkloadStoreWatchResume(t *testing.T) {
+	s := NewWorkloadStore()
+	s.historySize = 2
+	for _, id := range []string{"a", "b", "c"} {
+		s.Add(&Workload{ID: id})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := s.Watch(ctx, 1)
+	if err != nil {
+		t.Fatalf("Expected resume from 1 to succeed, got %v", err)
+	}
+	if e := nextEvent(t, ch); e.Object.ID != "b" || e.ResourceVersion != 2 {
+		t.Errorf("Expected b@2 first, got %s@%d", e.Object.ID, e.ResourceVersion)
+	}
+
+	if _, err := s.Watch(ctx, 0); err != nil {
+		t.Errorf("Expected a fresh watch to succeed, got %v", err)
+	}
+	s.historySize = 1
+	s.Add(&Workload{ID: "d"})
+	if _, err := s.Watch(ctx, 1); !errors.Is(err, ErrResourceVersionGone) {
+		t.Errorf("Expected ErrResourceVersionGone, got %v", err)
+	}
+}
+
+// TestWorkloadStoreWatchSlow tests that a watcher that stops reading is closed
+func TestWorkloadStoreWatchSlow(t *testing.T) {
+	s := NewWorkloadStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := s.Watch(ctx, 0)
+
+	for i := 0; i <= watchBuffer; i++ {
+		s.Add(&Workload{ID: "a"})
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != watchBuffer {
+		t.Errorf("Expected %d buffered events before close, got %d", watchBuffer, n)
+	}
+}
+
+// TestWorkloadStoreCopies tests that callers never share memory with the store
+func TestWorkloadStoreCopies(t *testing.T) {
+	s := NewWorkloadStore()
+	w := &Workload{ID: "a", Status: "queued", Command: []string{"sleep", "1"}}
+	s.Add(w)
+	w.Status = "changed"
+	w.Command[0] = "changed"
+
+	got, _ := s.Get("a")
+	if got.Status != "queued" || got.Command[0] != "sleep" {
+		t.Errorf("Expected the store to keep its own copy, got %+v", got)
+	}
+	got.Status = "changed"
+	if again, _ := s.Get("a"); again.Status != "queued" {
+		t.Errorf("Expected Get to return a copy, got status %s", again.Status)
+	}
+}
+
+// TestWorkloadStoreCompareAndSwap tests that updates based on a stale version fail
+func TestWorkloadStoreCompareAndSwap(t *testing.T) {
+	s := NewWorkloadStore()
+	s.Add(&Workload{ID: "a", Priority: 1})
+
+	first, _ := s.Get("a")
+	second, _ := s.Get("a")
+
+	first.Priority = 2
+	updated, err := s.CompareAndSwap(first)
+	if err != nil || updated.Priority != 2 || updated.ResourceVersion <= first.ResourceVersion {
+		t.Fatalf("Expected swap to succeed with a new version, got %+v, %v", updated, err)
+	}
+
+	second.Priority = 3
+	if _, err := s.CompareAndSwap(second); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale update, got %v", err)
+	}
+	if err := s.CompareAndDelete("a", second.ResourceVersion); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale delete, got %v", err)
+	}
+	if err := s.CompareAndDelete("a", updated.ResourceVersion); err != nil {
+		t.Errorf("Expected delete at the current version to succeed, got %v", err)
+	}
+	if _, err := s.CompareAndSwap(updated); !errors.Is(err, ErrWorkloadNotFound) {
+		t.Errorf("Expected ErrWorkloadNotFound after delete, got %v", err)
+	}
+}
+
+// TestWorkloadStoreConcurrentUpdates tests that CAS retries lose no updates
+func TestWorkloadStoreConcurrentUpdates(t *testing.T) {
+	s := NewWorkloadStore()
+	s.Add(&Workload{ID: "a"})
+
+	const workers, increments = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < increments; {
+				w, _ := s.Get("a")
+				w.Priority++
+				if _, err := s.CompareAndSwap(w); err == nil {
+					n++
+				}
+				s.Mutate("a", func(cur *Workload) { cur.CPUTime++ })
+				_ = s.GetAll()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if w, _ := s.Get("a"); w.Priority != workers*increments {
+		t.Errorf("Expected priority %d, got %d", workers*increments, w.Priority)
+	}
+}
@@ -0,0 +1,150 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNoChild       = errors.New("no such child process") // ECHILD
+	ErrNoExitedChild = errors.New("no child has exited")   // WNOHANG with nothing to reap
+)
+
+// WaitAny can be passed as the child PID to wait for any child, like waitpid(-1)
+const WaitAny = -1
+
+// Wait blocks until a child of ppid has exited, then reaps it and returns its
+// final info, like waitpid(). With nohang it returns ErrNoExitedChild instead of blocking.
+func (pm *ProcessManager) Wait(ctx context.Context, ppid, pid int, nohang bool) (ProcessInfo, error) {
+	for {
+		pm.mu.Lock()
+		info, err := pm.findExitedChildLocked(ppid, pid)
+		if err != nil {
+			pm.mu.Unlock()
+			return ProcessInfo{}, err
+		}
+		if info != nil {
+			result := *info.copy()
+			pm.reapLocked(info.PID)
+			pm.mu.Unlock()
+			return result, nil
+		}
+		exited := pm.exited
+		pm.mu.Unlock()
+
+		if nohang {
+			return ProcessInfo{}, ErrNoExitedChild
+		}
+		select {
+		case <-ctx.Done():
+			return ProcessInfo{}, ctx.Err()
+		case <-exited:
+		}
+	}
+}
+
+// findExitedChildLocked returns an exited child matching pid (or any child for
+// WaitAny), nil if matching children are still alive, or ErrNoChild
+func (pm *ProcessManager) findExitedChildLocked(ppid, pid int) (*ProcessInfo, error) {
+	parent, ok := pm.processes[ppid]
+	if !ok {
+		return nil, ErrNoChild
+	}
+	found := false
+	for _, child := range parent.Children {
+		if pid != WaitAny && child != pid {
+			continue
+		}
+		info, ok := pm.processes[child]
+		if !ok || info.PPID != ppid {
+			continue
+		}
+		found = true
+		if info.exited() {
+			return info, nil
+		}
+	}
+	if !found {
+		return nil, ErrNoChild
+	}
+	return nil, nil
+}
+
+// reapLocked removes an exited process from the table, its parent, group and
+// session, and frees its PID for reuse
+func (pm *ProcessManager) reapLocked(pid int) {
+	info, ok := pm.processes[pid]
+	if !ok {
+		return
+	}
+	delete(pm.processes, pid)
+	ReleasePID(pid)
+
+	if parent, ok := pm.processes[info.PPID]; ok {
+		parent.Children = removePID(parent.Children, pid)
+	}
+	if pg, ok := pm.processGroups[info.PGID]; ok {
+		pg.mu.Lock()
+		pg.PIDs = removePID(pg.PIDs, pid)
+		empty := len(pg.PIDs) == 0
+		pg.mu.Unlock()
+		if empty {
+			delete(pm.processGroups, info.PGID)
+		}
+	}
+	if sess, ok := pm.sessions[info.SID]; ok {
+		sess.PIDs = removePID(sess.PIDs, pid)
+		pm.releaseSessionLocked(info.SID)
+	}
+}
+
+// ReapOrphans reaps exited processes nobody will wait for: those adopted by
+// init, top-level ones (PPID 0) and those whose parent is gone. It returns the reaped PIDs.
+func (pm *ProcessManager) ReapOrphans() []int {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var reaped []int
+	for pid, info := range pm.processes {
+		if !info.exited() {
+			continue
+		}
+		if _, parentAlive := pm.processes[info.PPID]; info.PPID == 0 || info.PPID == InitPID || !parentAlive {
+			reaped = append(reaped, pid)
+		}
+	}
+	for _, pid := range reaped {
+		pm.reapLocked(pid)
+	}
+	return reaped
+}
+
+// RunReaper periodically reaps orphaned zombies until the context is cancelled
+func (pm *ProcessManager) RunReaper(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if reaped := pm.ReapOrphans(); len(reaped) > 0 {
+				logger.Debug("Reaped orphaned zombies", zap.Ints("pids", reaped))
+			}
+		}
+	}
+}
+
+// removePID returns pids without pid
+func removePID(pids []int, pid int) []int {
+	for i, p := range pids {
+		if p == pid {
+			return append(pids[:i], pids[i+1:]...)
+		}
+	}
+	return pids
+}
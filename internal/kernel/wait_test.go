@@ -0,0 +1,95 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestOrphanReparenting tests that children of an exited parent move to init
+func TestOrphanReparenting(t *testing.T) {
+	pm := NewProcessManager()
+	pm.CreateProcess(5001, 0)
+	pm.CreateProcess(5002, 5001)
+
+	pm.ExitProcess(5001, 0)
+
+	child, _ := pm.GetProcess(5002)
+	if child.PPID != InitPID {
+		t.Errorf("Expected orphan reparented to init, got PPID %d", child.PPID)
+	}
+	if tree := pm.GetProcessTree(InitPID); len(tree) != 2 {
+		t.Errorf("Expected init to adopt the orphan, got %d processes", len(tree))
+	}
+}
+
+// TestWaitReapsZombie tests collecting a child's exit code
+func TestWaitReapsZombie(t *testing.T) {
+	pm := NewProcessManager()
+	pm.CreateProcess(5001, 0)
+	pm.CreateProcess(5002, 5001)
+	pm.ExitProcess(5002, 3)
+
+	zombie, _ := pm.GetProcess(5002)
+	if zombie.State != "zombie" || zombie.ExitCode != 3 {
+		t.Errorf("Expected zombie with exit code 3, got %s/%d", zombie.State, zombie.ExitCode)
+	}
+
+	info, err := pm.Wait(context.Background(), 5001, 5002, false)
+	if err != nil || info.ExitCode != 3 {
+		t.Fatalf("Expected exit code 3, got %d (%v)", info.ExitCode, err)
+	}
+	if _, ok := pm.GetProcess(5002); ok {
+		t.Error("Expected zombie to be reaped")
+	}
+	if _, err := pm.Wait(context.Background(), 5001, WaitAny, false); !errors.Is(err, ErrNoChild) {
+		t.Errorf("Expected ErrNoChild after reaping, got %v", err)
+	}
+}
+
+// TestWaitBlocks tests that wait returns once a running child exits
+func TestWaitBlocks(t *testing.T) {
+	pm := NewProcessManager()
+	pm.CreateProcess(5001, 0)
+	pm.CreateProcess(5002, 5001)
+
+	if _, err := pm.Wait(context.Background(), 5001, WaitAny, true); !errors.Is(err, ErrNoExitedChild) {
+		t.Errorf("Expected ErrNoExitedChild with nohang, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pm.ExitProcess(5002, 1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	info, err := pm.Wait(ctx, 5001, WaitAny, false)
+	if err != nil || info.PID != 5002 || info.ExitCode != 1 {
+		t.Errorf("Expected 5002 with exit code 1, got %+v (%v)", info, err)
+	}
+}
+
+// TestReapOrphans tests the background reaper
+func TestReapOrphans(t *testing.T) {
+	pm := NewProcessManager()
+	pm.CreateProcess(5001, 0)
+	pm.CreateProcess(5002, 5001)
+	pm.CreateProcess(5003, 5001)
+	pm.ExitProcess(5003, 0) // Parent alive, stays a zombie
+	pm.ExitProcess(5002, 0)
+
+	pm.ReapOrphans()
+	if _, ok := pm.GetProcess(5003); !ok {
+		t.Error("Expected zombie with a live parent to be kept")
+	}
+
+	pm.ExitProcess(5001, 0) // 5001 (top-level) and 5003 (now orphaned) are reaped
+	pm.ReapOrphans()
+	for _, pid := range []int{5001, 5002, 5003} {
+		if _, ok := pm.GetProcess(pid); ok {
+			t.Errorf("Expected %d to be reaped", pid)
+		}
+	}
+}
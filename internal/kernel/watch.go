@@ -0,0 +1,115 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"ckm/internal/common"
+)
+
+// DefaultWatchHistory is how many past events a store keeps for resuming watches
+const DefaultWatchHistory = 1000
+
+// watchBuffer is how many live events a watcher may lag behind before it's dropped
+const watchBuffer = 256
+
+// EventType is the kind of change a WatchEvent describes
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+)
+
+// ErrResourceVersionGone is returned when a watch asks for changes older than the history
+var ErrResourceVersionGone = errors.New("resource version is too old; list again and watch from the new version")
+
+// WatchEvent is one change to a workload, with the workload as it was after the change
+type WatchEvent struct {
+	Type            EventType `json:"type"`
+	Object          *Workload `json:"object"`
+	ResourceVersion uint64    `json:"resourceVersion"`
+}
+
+// watcher is one open watch
+type watcher struct {
+	ch     chan WatchEvent
+	closed bool
+}
+
+// ResourceVersion returns the version of the latest change
+func (s *WorkloadStore) ResourceVersion() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rv
+}
+
+// Watch streams changes newer than fromRV until ctx is done. With fromRV 0
+// it first sends every current workload as ADDED. A watcher that falls too
+// far behind has its channel closed; it can resume from the last version
+// it saw as long as that is still in the history.
+func (s *WorkloadStore) Watch(ctx context.Context, fromRV uint64) (<-chan WatchEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var backlog []WatchEvent
+	if fromRV == 0 {
+		for _, w := range s.workloads {
+			backlog = append(backlog, WatchEvent{Type: EventAdded, Object: w.Clone(), ResourceVersion: w.ResourceVersion})
+		}
+		sort.Slice(backlog, func(i, j int) bool { return backlog[i].ResourceVersion < backlog[j].ResourceVersion })
+	} else if fromRV < s.rv {
+		// The history must reach back to the first change the client missed
+		if len(s.history) == 0 || s.history[0].ResourceVersion > fromRV+1 {
+			return nil, ErrResourceVersionGone
+		}
+		for _, e := range s.history {
+			if e.ResourceVersion > fromRV {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	wt := &watcher{ch: make(chan WatchEvent, len(backlog)+watchBuffer)}
+	for _, e := range backlog {
+		wt.ch <- e
+	}
+	s.watchers[wt] = struct{}{}
+	common.StoreWatchers.Inc()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closeWatcher(wt)
+	}()
+	return wt.ch, nil
+}
+
+// publish appends an event to the history and fans it out; the caller holds s.mu
+func (s *WorkloadStore) publish(e WatchEvent) {
+	s.history = append(s.history, e)
+	if over := len(s.history) - s.historySize; over > 0 {
+		s.history = append(s.history[:0:0], s.history[over:]...)
+	}
+	for wt := range s.watchers {
+		select {
+		case wt.ch <- e:
+		default:
+			s.closeWatcher(wt) // Too slow; it must resume from its last version
+		}
+	}
+}
+
+// closeWatcher ends a watch; the caller holds s.mu
+func (s *WorkloadStore) closeWatcher(wt *watcher) {
+	if wt.closed {
+		return
+	}
+	wt.closed = true
+	close(wt.ch)
+	delete(s.watchers, wt)
+	common.StoreWatchers.Dec()
+}
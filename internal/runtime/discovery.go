@@ -21,6 +21,7 @@ type ContainerStats struct {
 	ImageName     string
 	CPUPercent    float64
 	MemoryUsage   uint64
+	MemoryPeak    uint64 // Highest usage seen: cgroup v1's max_usage, or the largest sample on v2
 	MemoryLimit   uint64
 	MemoryPercent float64
 	NetworkRx     uint64
@@ -124,6 +125,7 @@ func (d *ContainerDiscovery) discoverAndCollect(ctx context.Context) {
 			ImageName:     c.Image,
 			CPUPercent:    stats.cpuPercent,
 			MemoryUsage:   stats.memoryUsage,
+			MemoryPeak:    max(stats.memoryPeak, stats.memoryUsage),
 			MemoryLimit:   stats.memoryLimit,
 			MemoryPercent: stats.memoryPercent,
 			NetworkRx:     stats.networkRx,
@@ -137,6 +139,9 @@ func (d *ContainerDiscovery) discoverAndCollect(ctx context.Context) {
 		}
 
 		d.mu.Lock()
+		if prev, ok := d.containers[c.ID]; ok {
+			containerStats.MemoryPeak = max(containerStats.MemoryPeak, prev.MemoryPeak)
+		}
 		d.containers[c.ID] = containerStats
 		d.mu.Unlock()
 
@@ -161,6 +166,7 @@ func (d *ContainerDiscovery) discoverAndCollect(ctx context.Context) {
 type statsResult struct {
 	cpuPercent    float64
 	memoryUsage   uint64
+	memoryPeak    uint64
 	memoryLimit   uint64
 	memoryPercent float64
 	networkRx     uint64
@@ -217,6 +223,7 @@ func (d *ContainerDiscovery) getContainerStats(ctx context.Context, containerID
 	return &statsResult{
 		cpuPercent:    cpuPercent,
 		memoryUsage:   memoryUsage,
+		memoryPeak:    stats.MemoryStats.MaxUsage,
 		memoryLimit:   memoryLimit,
 		memoryPercent: memoryPercent,
 		networkRx:     networkRx,
@@ -2,11 +2,14 @@ package runtime
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"go.uber.org/zap"
@@ -28,10 +31,38 @@ func NewDockerRuntime(dockerClient *client.Client, logger *zap.Logger) *DockerRu
 	return &DockerRuntime{client: dockerClient, logger: logger}
 }
 
+// ResourceLimits holds the cgroup limits applied to a container
+type ResourceLimits struct {
+	MemoryMB      int
+	CPUShares     int64 // CPU weight (1024 = 1 CPU)
+	CPUMillicores int64 // Hard CPU quota (1000 = 1 CPU), 0 = unlimited
+	PIDsLimit     int64 // Max processes, 0 = unlimited
+	DiskMB        int64 // Writable layer size, 0 = unlimited
+}
+
+// Labels CKM puts on every container it creates, so it can find them after a restart
+const (
+	LabelManaged    = "ckm.managed"
+	LabelWorkloadID = "ckm.workload.id"
+	LabelPID        = "ckm.workload.pid"
+	LabelSpecHash   = "ckm.workload.spec-hash"
+)
+
+// ManagedContainer is a container carrying CKM's labels
+type ManagedContainer struct {
+	ID         string
+	WorkloadID string
+	PID        int
+	SpecHash   string
+	Image      string
+	Running    bool
+	ExitCode   int64 // Valid once the container has stopped
+}
+
 // CreateContainer creates a new container with resource limits (like cgroups)
-func (r *DockerRuntime) CreateContainer(ctx context.Context, imageName string, cmd []string, memoryMB int, cpuShares int64) (string, error) {
+func (r *DockerRuntime) CreateContainer(ctx context.Context, imageName string, cmd []string, limits ResourceLimits, labels map[string]string) (string, error) {
 	// Convert MB to bytes for memory limit
-	memoryBytes := int64(memoryMB) * 1024 * 1024
+	memoryBytes := int64(limits.MemoryMB) * 1024 * 1024
 
 	// Pull image if not present
 	_, _, err := r.client.ImageInspectWithRaw(ctx, imageName)
@@ -48,18 +79,28 @@ func (r *DockerRuntime) CreateContainer(ctx context.Context, imageName string, c
 
 	// Container configuration
 	config := &container.Config{
-		Image: imageName,
-		Cmd:   cmd,
+		Image:  imageName,
+		Cmd:    cmd,
+		Labels: labels,
 	}
 
 	// Resource limits (cgroups-like)
 	hostConfig := &container.HostConfig{
 		Resources: container.Resources{
 			Memory:     memoryBytes,
-			MemorySwap: memoryBytes, // Disable swap
-			CPUShares:  cpuShares,   // CPU weight (1024 = 1 CPU)
+			MemorySwap: memoryBytes,      // Disable swap
+			CPUShares:  limits.CPUShares, // CPU weight (1024 = 1 CPU)
+			NanoCPUs:   limits.CPUMillicores * 1e6,
 		},
 	}
+	if limits.PIDsLimit > 0 {
+		pids := limits.PIDsLimit
+		hostConfig.Resources.PidsLimit = &pids
+	}
+	// Disk quota needs a storage driver that supports it (e.g. overlay2 on xfs with pquota)
+	if limits.DiskMB > 0 {
+		hostConfig.StorageOpt = map[string]string{"size": fmt.Sprintf("%dM", limits.DiskMB)}
+	}
 
 	resp, err := r.client.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
 	if err != nil {
@@ -91,6 +132,33 @@ func (r *DockerRuntime) StopContainer(ctx context.Context, containerID string, t
 	return nil
 }
 
+// KillContainer sends a signal (e.g. "SIGTERM") to a container's main process
+func (r *DockerRuntime) KillContainer(ctx context.Context, containerID, signal string) error {
+	if err := r.client.ContainerKill(ctx, containerID, signal); err != nil {
+		return err
+	}
+	r.logger.Info("Container signalled", zap.String("id", containerID[:12]), zap.String("signal", signal))
+	return nil
+}
+
+// PauseContainer freezes every process in a container (like SIGSTOP)
+func (r *DockerRuntime) PauseContainer(ctx context.Context, containerID string) error {
+	if err := r.client.ContainerPause(ctx, containerID); err != nil {
+		return err
+	}
+	r.logger.Info("Container paused", zap.String("id", containerID[:12]))
+	return nil
+}
+
+// UnpauseContainer resumes a paused container (like SIGCONT)
+func (r *DockerRuntime) UnpauseContainer(ctx context.Context, containerID string) error {
+	if err := r.client.ContainerUnpause(ctx, containerID); err != nil {
+		return err
+	}
+	r.logger.Info("Container unpaused", zap.String("id", containerID[:12]))
+	return nil
+}
+
 // RemoveContainer removes a container
 func (r *DockerRuntime) RemoveContainer(ctx context.Context, containerID string) error {
 	err := r.client.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
@@ -101,12 +169,18 @@ func (r *DockerRuntime) RemoveContainer(ctx context.Context, containerID string)
 	return nil
 }
 
-// GetContainerLogs streams container logs
-func (r *DockerRuntime) GetContainerLogs(ctx context.Context, containerID string) (io.ReadCloser, error) {
+// GetContainerLogs streams a container's logs in Docker's multiplexed
+// format with a timestamp on every line; read it with ReadLogStream
+func (r *DockerRuntime) GetContainerLogs(ctx context.Context, containerID string, opts LogOptions) (io.ReadCloser, error) {
 	options := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
-		Follow:     true,
+		Follow:     opts.Follow,
+		Timestamps: true,
+		Tail:       opts.Tail,
+	}
+	if !opts.Since.IsZero() {
+		options.Since = strconv.FormatInt(opts.Since.Unix(), 10)
 	}
 	return r.client.ContainerLogs(ctx, containerID, options)
 }
@@ -122,6 +196,47 @@ func (r *DockerRuntime) WaitContainer(ctx context.Context, containerID string) (
 	}
 }
 
+// HostInfo returns the memory and CPUs the Docker daemon reports for its host
+func (r *DockerRuntime) HostInfo(ctx context.Context) (int64, int, error) {
+	info, err := r.client.Info(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	return info.MemTotal, info.NCPU, nil
+}
+
+// ListManagedContainers returns every container, running or stopped, that CKM created
+func (r *DockerRuntime) ListManagedContainers(ctx context.Context) ([]ManagedContainer, error) {
+	list, err := r.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelManaged+"=true")),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ManagedContainer, 0, len(list))
+	for _, c := range list {
+		pid, _ := strconv.Atoi(c.Labels[LabelPID])
+		mc := ManagedContainer{
+			ID:         c.ID,
+			WorkloadID: c.Labels[LabelWorkloadID],
+			PID:        pid,
+			SpecHash:   c.Labels[LabelSpecHash],
+			Image:      c.Image,
+			Running:    c.State == "running" || c.State == "paused",
+		}
+		if !mc.Running {
+			info, err := r.client.ContainerInspect(ctx, c.ID)
+			if err == nil && info.ContainerJSONBase != nil && info.State != nil {
+				mc.ExitCode = int64(info.State.ExitCode)
+			}
+		}
+		result = append(result, mc)
+	}
+	return result, nil
+}
+
 // InspectContainer gets container status and details
 func (r *DockerRuntime) InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error) {
 	return r.client.ContainerInspect(ctx, containerID)
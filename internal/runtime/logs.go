@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Log streams, as in Docker's json-file log driver
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LogOptions selects which container logs to read
+type LogOptions struct {
+	Follow bool      // Keep streaming until the container stops
+	Tail   string    // Number of lines from the end, or "all"
+	Since  time.Time // Only lines written after this time; zero for all
+}
+
+// LogEntry is one line of container output
+type LogEntry struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // StreamStdout or StreamStderr
+	Log    string    `json:"log"`    // The line without its trailing newline
+}
+
+// ReadLogStream demultiplexes Docker's log stream for a container without a
+// TTY: every frame has an 8-byte header naming the stream and giving the
+// payload size, and every payload starts with the line's timestamp. It
+// calls fn for each line until the stream ends.
+func ReadLogStream(r io.Reader, fn func(LogEntry) error) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, 8)
+	var payload []byte
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read log frame header: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+		if cap(payload) < int(size) {
+			payload = make([]byte, size)
+		}
+		payload = payload[:size]
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return fmt.Errorf("read log frame: %w", err)
+		}
+
+		var stream string
+		switch header[0] {
+		case 1:
+			stream = StreamStdout
+		case 2:
+			stream = StreamStderr
+		case 3:
+			return fmt.Errorf("docker log error: %s", strings.TrimSpace(string(payload)))
+		default:
+			continue // stdin is never logged
+		}
+
+		for _, line := range strings.SplitAfter(string(payload), "\n") {
+			if line == "" {
+				continue
+			}
+			entry := LogEntry{Stream: stream, Log: strings.TrimSuffix(line, "\n")}
+			if ts, text, ok := strings.Cut(entry.Log, " "); ok {
+				if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					entry.Time, entry.Log = t, text
+				}
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
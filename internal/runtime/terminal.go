@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// TerminalSession is an interactive stream to a process in a container:
+// a command run with Exec, or the container's main process with Attach
+type TerminalSession interface {
+	// Stdin feeds the process's standard input
+	Stdin() io.Writer
+	// CloseStdin sends EOF to the process
+	CloseStdin() error
+	// CopyOutput copies output until the process exits or detaches. With a
+	// TTY everything goes to stdout; without one stderr is kept separate.
+	CopyOutput(stdout, stderr io.Writer) error
+	// Resize changes the TTY size
+	Resize(ctx context.Context, height, width uint) error
+	// ExitCode returns how the process exited, or -1 if it's still running
+	ExitCode(ctx context.Context) (int, error)
+	// TTY reports whether the process has a terminal
+	TTY() bool
+	Close() error
+}
+
+// dockerSession is a TerminalSession over a hijacked Docker API connection
+type dockerSession struct {
+	resp     types.HijackedResponse
+	tty      bool
+	resize   func(ctx context.Context, options container.ResizeOptions) error
+	exitCode func(ctx context.Context) (int, error)
+}
+
+func (s *dockerSession) Stdin() io.Writer  { return s.resp.Conn }
+func (s *dockerSession) CloseStdin() error { return s.resp.CloseWrite() }
+func (s *dockerSession) TTY() bool         { return s.tty }
+
+func (s *dockerSession) CopyOutput(stdout, stderr io.Writer) error {
+	if s.tty {
+		_, err := io.Copy(stdout, s.resp.Reader)
+		return err
+	}
+	_, err := stdcopy.StdCopy(stdout, stderr, s.resp.Reader)
+	return err
+}
+
+func (s *dockerSession) Resize(ctx context.Context, height, width uint) error {
+	return s.resize(ctx, container.ResizeOptions{Height: height, Width: width})
+}
+
+func (s *dockerSession) ExitCode(ctx context.Context) (int, error) {
+	return s.exitCode(ctx)
+}
+
+func (s *dockerSession) Close() error {
+	s.resp.Close()
+	return nil
+}
+
+// Exec starts a command in a running container with stdin, stdout and
+// stderr attached, like docker exec -i (and -t with tty)
+func (r *DockerRuntime) Exec(ctx context.Context, containerID string, cmd []string, tty bool) (TerminalSession, error) {
+	created, err := r.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		Tty:          tty,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{Tty: tty})
+	if err != nil {
+		return nil, err
+	}
+	return &dockerSession{
+		resp: resp,
+		tty:  tty,
+		resize: func(ctx context.Context, options container.ResizeOptions) error {
+			return r.client.ContainerExecResize(ctx, created.ID, options)
+		},
+		exitCode: func(ctx context.Context) (int, error) {
+			inspect, err := r.client.ContainerExecInspect(ctx, created.ID)
+			if err != nil || inspect.Running {
+				return -1, err
+			}
+			return inspect.ExitCode, nil
+		},
+	}, nil
+}
+
+// Attach connects to a container's main process, like docker attach. Input
+// only reaches a process whose container was created with stdin open.
+func (r *DockerRuntime) Attach(ctx context.Context, containerID string) (TerminalSession, error) {
+	info, err := r.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dockerSession{
+		resp: resp,
+		tty:  info.Config != nil && info.Config.Tty,
+		resize: func(ctx context.Context, options container.ResizeOptions) error {
+			return r.client.ContainerResize(ctx, containerID, options)
+		},
+		exitCode: func(ctx context.Context) (int, error) {
+			info, err := r.client.ContainerInspect(ctx, containerID)
+			if err != nil || info.State == nil || info.State.Running {
+				return -1, err
+			}
+			return info.State.ExitCode, nil
+		},
+	}, nil
+}